@@ -0,0 +1,235 @@
+package pubsub
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of the messages published to the dead-letter topic of SubscribeAck.
+const (
+	HeaderDeadLetterTopic = "Pubsub-Dead-Letter-Topic" // the topic of the message
+	HeaderAttempts        = "Pubsub-Attempts"          // the number of deliveries
+)
+
+// AckOption configures SubscribeAck.
+type AckOption func(o *ackOptions)
+
+type ackOptions struct {
+	timeout    time.Duration
+	attempts   int
+	deadLetter string
+	buffer     int
+}
+
+// AckTimeout redelivers a message which isn't acknowledged within d, 30s by default.
+func AckTimeout(d time.Duration) AckOption {
+	return func(o *ackOptions) {
+		o.timeout = d
+	}
+}
+
+// MaxAttempts gives a message up after n deliveries which weren't acknowledged, 5
+// by default.
+func MaxAttempts(n int) AckOption {
+	return func(o *ackOptions) {
+		o.attempts = n
+	}
+}
+
+// DeadLetterTopic publishes the messages given up to name, with the
+// HeaderDeadLetterTopic and HeaderAttempts headers. Without a dead-letter topic,
+// they are reported as dropped.
+func DeadLetterTopic(name string) AckOption {
+	return func(o *ackOptions) {
+		o.deadLetter = name
+	}
+}
+
+// AckBuffer sets the size of the buffer receiving the published messages before
+// they are queued, 64 by default.
+func AckBuffer(n int) AckOption {
+	return func(o *ackOptions) {
+		o.buffer = n
+	}
+}
+
+// Delivery is a message delivered by SubscribeAck, which must be acknowledged with
+// Ack, or rejected with Nak.
+type Delivery struct {
+	Msg     Event
+	Attempt int // the number of the delivery, from 1
+
+	entry *ackEntry
+	sub   *AckSubscription
+}
+
+// Ack acknowledges the message, it's not delivered again.
+func (d *Delivery) Ack() {
+	d.sub.settle(d.entry, true)
+}
+
+// Nak rejects the message, it's delivered again right away unless it reached the
+// maximum attempts.
+func (d *Delivery) Nak() {
+	d.sub.settle(d.entry, false)
+}
+
+type ackEntry struct {
+	event    Event
+	attempts int
+	deadline time.Time
+	done     bool
+}
+
+type settlement struct {
+	entry *ackEntry
+	ack   bool
+}
+
+// AckSubscription is a subscription created by SubscribeAck.
+type AckSubscription struct {
+	p       *Pubsub
+	name    string
+	opts    ackOptions
+	in      chan Event
+	c       chan *Delivery
+	settled chan settlement
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+// SubscribeAck subscribes c to name with at-least-once delivery: c receives every
+// message as a *Delivery, and the messages which aren't acknowledged in time or
+// rejected are delivered again, until the maximum attempts. Messages are queued
+// without limit while c isn't ready, the buffer receiving them only drops messages
+// if it's full, e.g. when publishing a burst larger than the buffer.
+func (p *Pubsub) SubscribeAck(name string, c chan *Delivery, opts ...AckOption) (*AckSubscription, error) {
+	name = p.normalize(name)
+	o := ackOptions{timeout: 30 * time.Second, attempts: 5, buffer: 64}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &AckSubscription{
+		p:       p,
+		name:    name,
+		opts:    o,
+		in:      make(chan Event, o.buffer),
+		c:       c,
+		settled: make(chan settlement),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := p.Subscribe(name, s.in); err != nil {
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// Close unsubscribes and stops delivering messages, the messages not acknowledged
+// yet are discarded.
+func (s *AckSubscription) Close() {
+	s.p.UnsubscribeAll(s.in)
+	s.once.Do(func() { close(s.stop) })
+	<-s.done
+}
+
+func (s *AckSubscription) settle(entry *ackEntry, ack bool) {
+	select {
+	case s.settled <- settlement{entry, ack}:
+	case <-s.done:
+	}
+}
+
+func (s *AckSubscription) run() {
+	defer close(s.done)
+
+	var (
+		ready    []*ackEntry
+		inflight = map[*ackEntry]struct{}{}
+		timer    = s.p.clock.NewTimer(time.Hour)
+	)
+	defer timer.Stop()
+
+	for {
+		// wait for the earliest deadline
+		timer.Stop()
+		var next time.Time
+		for entry := range inflight {
+			if next.IsZero() || entry.deadline.Before(next) {
+				next = entry.deadline
+			}
+		}
+		var expired <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(next.Sub(s.p.clock.Now()))
+			expired = timer.C()
+		}
+		var (
+			out      chan *Delivery
+			delivery *Delivery
+		)
+		for len(ready) > 0 && s.p.expired(ready[0].event) {
+			ready = ready[1:]
+		}
+		if len(ready) > 0 {
+			out = s.c
+			delivery = &Delivery{Msg: ready[0].event, Attempt: ready[0].attempts + 1, entry: ready[0], sub: s}
+		}
+
+		select {
+		case event := <-s.in:
+			ready = append(ready, &ackEntry{event: event})
+		case out <- delivery:
+			entry := ready[0]
+			ready = ready[1:]
+			entry.attempts++
+			entry.deadline = s.p.clock.Now().Add(s.opts.timeout)
+			inflight[entry] = struct{}{}
+		case st := <-s.settled:
+			if _, ok := inflight[st.entry]; !ok || st.entry.done {
+				break
+			}
+			delete(inflight, st.entry)
+			if st.ack {
+				st.entry.done = true
+			} else if s.retry(st.entry) {
+				ready = append(ready, st.entry)
+			}
+		case now := <-expired:
+			for entry := range inflight {
+				if entry.deadline.After(now) {
+					continue
+				}
+				delete(inflight, entry)
+				if s.retry(entry) {
+					ready = append(ready, entry)
+				}
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// retry returns whether entry is delivered again, or gives it up.
+func (s *AckSubscription) retry(entry *ackEntry) bool {
+	if entry.attempts < s.opts.attempts {
+		return true
+	}
+	entry.done = true
+	if s.opts.deadLetter == "" {
+		s.p.drop(s.in, entry.event, "")
+		return false
+	}
+	headers := make(map[string]string, len(entry.event.Headers)+2)
+	for k, v := range entry.event.Headers {
+		headers[k] = v
+	}
+	headers[HeaderDeadLetterTopic] = entry.event.Name
+	headers[HeaderAttempts] = strconv.Itoa(entry.attempts)
+	s.p.PublishMsg(s.opts.deadLetter, entry.event.Message, headers)
+	return false
+}
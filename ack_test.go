@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func receiveDelivery(t *testing.T, c chan *Delivery) *Delivery {
+	t.Helper()
+	select {
+	case d := <-c:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("no delivery")
+	}
+	return nil
+}
+
+func TestSubscribeAck(t *testing.T) {
+	ps := New(-1)
+	dead := make(chan Event, 10)
+	ps.Subscribe("dead", dead)
+
+	c := make(chan *Delivery)
+	s, err := ps.SubscribeAck("tasks", c, AckTimeout(20*time.Millisecond), MaxAttempts(2), DeadLetterTopic("dead"))
+	assert.Equal(t, err, nil)
+	defer s.Close()
+
+	// c isn't ready, the messages are queued
+	for i := 0; i < 3; i++ {
+		ps.Publish("tasks", i)
+	}
+
+	d := receiveDelivery(t, c)
+	assert.Equal(t, d.Msg.Message, 0)
+	d.Ack()
+
+	d = receiveDelivery(t, c)
+	assert.Equal(t, d.Msg.Message, 1)
+	d.Nak()
+
+	// rejected messages are queued again
+	d = receiveDelivery(t, c)
+	assert.Equal(t, d.Msg.Message, 2)
+	d.Ack()
+	d = receiveDelivery(t, c)
+	assert.Equal(t, d.Msg.Message, 1)
+	assert.Equal(t, d.Attempt, 2)
+
+	// not acknowledged in time after the last attempt
+	select {
+	case e := <-dead:
+		assert.Equal(t, e.Message, 1)
+		assert.Equal(t, e.Headers[HeaderDeadLetterTopic], "tasks")
+		assert.Equal(t, e.Headers[HeaderAttempts], "2")
+	case <-time.After(time.Second):
+		t.Fatal("no dead letter")
+	}
+	// acknowledging after giving up is ignored
+	d.Ack()
+}
+
+func TestSubscribeAckRedelivery(t *testing.T) {
+	var dropped []DroppedMessage
+	ps := New(-1, OnDrop(func(m DroppedMessage) { dropped = append(dropped, m) }))
+	c := make(chan *Delivery, 1)
+	s, _ := ps.SubscribeAck("tasks", c, AckTimeout(10*time.Millisecond), MaxAttempts(3))
+
+	ps.Publish("tasks", "a")
+	for i := 1; i <= 3; i++ {
+		d := receiveDelivery(t, c)
+		assert.Equal(t, d.Attempt, i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	s.Close()
+	assert.Equal(t, len(dropped), 1)
+	assert.Equal(t, len(c), 0)
+}
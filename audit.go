@@ -0,0 +1,230 @@
+package pubsub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditAction is an action recorded by an Auditor.
+type AuditAction int
+
+const (
+	// AuditPublish is a message published to a name.
+	AuditPublish AuditAction = iota
+	// AuditSubscribe is a channel subscribed to a name, pattern, filter or queue
+	// group.
+	AuditSubscribe
+	// AuditUnsubscribe is a channel unsubscribed.
+	AuditUnsubscribe
+)
+
+func (a AuditAction) String() string {
+	switch a {
+	case AuditPublish:
+		return "publish"
+	case AuditSubscribe:
+		return "subscribe"
+	case AuditUnsubscribe:
+		return "unsubscribe"
+	}
+	return "unknown"
+}
+
+// AuditRecord is the record of an action. Records are chained: Hash is the SHA-256
+// of the record with the Hash of the previous one, so a removed or modified record
+// breaks the chain, see VerifyAudit. The published message is part of Hash through
+// its Digest.
+type AuditRecord struct {
+	Seq      uint64 // from 1
+	Time     time.Time
+	Action   AuditAction
+	Identity string      // of the caller, see ContextWithIdentity
+	Name     string      // the name, pattern or filter
+	ID       string      // of the published message, WithIDGenerator
+	Message  interface{} `json:",omitempty"` // the published message
+	Digest   string      `json:",omitempty"` // of the published message, see AuditDigest
+	Channel  chan Event  `json:"-"`          // the subscribed channel
+	Hash     string
+}
+
+// Auditor records the actions of a Pubsub, e.g. to a compliance log.
+type Auditor interface {
+	Audit(record AuditRecord)
+}
+
+// AuditorFunc is an adapter to use an ordinary function as an Auditor.
+type AuditorFunc func(record AuditRecord)
+
+// Audit calls fn(record).
+func (fn AuditorFunc) Audit(record AuditRecord) {
+	fn(record)
+}
+
+// AuditWriter is an Auditor writing the records to w as JSON, one object per line.
+// Messages which can't be encoded with encoding/json are written formatted with
+// fmt.Sprint. Write errors are ignored.
+func AuditWriter(w io.Writer) Auditor {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return AuditorFunc(func(record AuditRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, err := json.Marshal(record.Message); err != nil {
+			record.Message = fmt.Sprint(record.Message)
+		}
+		enc.Encode(record)
+	})
+}
+
+// AuditLogger is an Auditor logging the records to l at the info level.
+func AuditLogger(l *slog.Logger) Auditor {
+	return AuditorFunc(func(record AuditRecord) {
+		l.Info("pubsub audit",
+			slog.Uint64("seq", record.Seq),
+			slog.String("action", record.Action.String()),
+			slog.String("identity", record.Identity),
+			slog.String("name", record.Name),
+			slog.String("id", record.ID),
+			slog.String("digest", record.Digest),
+			slog.String("hash", record.Hash),
+		)
+	})
+}
+
+// WithAuditor records every published message, subscription and unsubscription
+// with a, including the ones rejected later, e.g. by the schema of the topic. a is
+// called synchronously, in order, and must not call the Pubsub. The names with the
+// $SYS/ prefix aren't audited.
+func WithAuditor(a Auditor) Option {
+	return func(p *Pubsub) {
+		p.auditor = &auditor{a: a}
+	}
+}
+
+type identityKey struct{}
+
+// ContextWithIdentity returns ctx with the identity of the caller, recorded by the
+// Auditor for the messages published with PublishCtx and PublishWithContext, and
+// for the channels subscribed with SubscribeCtx and PSubscribeCtx.
+func ContextWithIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of ctx set by ContextWithIdentity.
+func IdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(identityKey{}).(string)
+	return identity
+}
+
+type auditor struct {
+	a          Auditor
+	identities sync.Map // chan Event -> identity of its subscriptions
+
+	mu   sync.Mutex
+	seq  uint64
+	hash string
+}
+
+// audit records record, unless the Pubsub has no Auditor.
+func (p *Pubsub) audit(record AuditRecord) {
+	if p.auditor == nil || p.system(record.Name) {
+		return
+	}
+	a := p.auditor
+	if record.Channel != nil && record.Identity == "" {
+		if v, ok := a.identities.Load(record.Channel); ok {
+			record.Identity = v.(string)
+		}
+	}
+
+	if record.Action == AuditPublish {
+		record.Digest = AuditDigest(p.Codec(), record.Message)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	record.Seq = a.seq
+	record.Time = p.clock.Now()
+	record.Hash = auditHash(a.hash, record)
+	a.hash = record.Hash
+	a.a.Audit(record)
+}
+
+// identify sets the identity of the subscriptions of c from ctx.
+func (p *Pubsub) identify(ctx context.Context, c chan Event) {
+	if p.auditor == nil {
+		return
+	}
+	if identity := IdentityFromContext(ctx); identity != "" {
+		p.auditor.identities.Store(c, identity)
+	}
+}
+
+// forgetIdentity deletes the identity of c once it has no subscriptions.
+func (p *Pubsub) forgetIdentity(c chan Event) {
+	if p.auditor != nil {
+		p.auditor.identities.Delete(c)
+	}
+}
+
+// forgetUnsubscribed deletes the identity of c if it has no subscriptions after
+// subscribing failed.
+func (p *Pubsub) forgetUnsubscribed(c chan Event) {
+	if p.auditor != nil && p.Subscription(c) == nil {
+		p.auditor.identities.Delete(c)
+	}
+}
+
+// AuditDigest returns the SHA-256 of message encoded with c, or formatted with
+// fmt.Sprint if c can't encode it. It's the Digest of the AuditRecord of a published
+// message, encoded with the Codec of the Pubsub, so a message kept elsewhere can be
+// checked against the audit records.
+func AuditDigest(c Codec, message interface{}) string {
+	data, err := c.Encode(message)
+	if err != nil {
+		data = []byte(fmt.Sprint(message))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditHash returns the hash of record chained to the hash of the previous record.
+func auditHash(prev string, record AuditRecord) string {
+	h := sha256.New()
+	for _, field := range []string{
+		prev,
+		strconv.FormatUint(record.Seq, 10),
+		record.Time.UTC().Format(time.RFC3339Nano),
+		record.Action.String(),
+		record.Identity,
+		record.Name,
+		record.ID,
+		record.Digest,
+	} {
+		// length prefixed, so fields can't be shifted
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAudit returns the index of the first record which breaks the chain of
+// records, or -1 if the chain is intact. The first record may follow a record
+// with hash prev, or prev is empty if it's the first record of the Pubsub.
+func VerifyAudit(prev string, records []AuditRecord) int {
+	for i, record := range records {
+		if auditHash(prev, record) != record.Hash {
+			return i
+		}
+		prev = record.Hash
+	}
+	return -1
+}
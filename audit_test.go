@@ -0,0 +1,82 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestAuditor(t *testing.T) {
+	var records []AuditRecord
+	ps := New(-1, WithAuditor(AuditorFunc(func(record AuditRecord) {
+		records = append(records, record)
+	})), WithIDGenerator(IDGeneratorFunc(func() string { return "id" })))
+	ctx := ContextWithIdentity(context.Background(), "alice")
+
+	c := make(chan Event, 2)
+	assert.Equal(t, ps.SubscribeCtx(ctx, "orders", c), nil)
+	assert.Equal(t, ps.PublishCtx(ctx, "orders", 1), nil)
+	ps.Publish("orders", 2)
+	ps.Unsubscribe("orders", c)
+	assert.Equal(t, ps.QueueSubscribe("jobs", "workers", c), nil)
+	ps.Notify(Notice{Kind: NoticeSlowSubscriber, Name: "orders"})
+
+	assert.Equal(t, len(records), 5)
+	type action struct {
+		Action   AuditAction
+		Identity string
+		Name     string
+	}
+	var actions []action
+	for i, record := range records {
+		assert.Equal(t, record.Seq, uint64(i+1))
+		actions = append(actions, action{record.Action, record.Identity, record.Name})
+	}
+	assert.Equal(t, actions, []action{
+		{AuditSubscribe, "alice", "orders"},
+		{AuditPublish, "alice", "orders"},
+		{AuditPublish, "", "orders"},
+		{AuditUnsubscribe, "alice", "orders"},
+		// the identity is forgotten with the last subscription of c
+		{AuditSubscribe, "", "jobs"},
+	})
+	assert.Equal(t, records[1].Message, 1)
+	assert.Equal(t, records[1].ID, "id")
+	assert.Equal(t, records[0].Channel, c)
+	assert.Equal(t, VerifyAudit("", records), -1)
+
+	records[1].Identity = "mallory"
+	assert.Equal(t, VerifyAudit("", records), 1)
+	assert.Equal(t, VerifyAudit(records[1].Hash, records[2:]), -1)
+	assert.Equal(t, VerifyAudit("", append(records[:1:1], records[2:]...)), 1)
+}
+
+func TestAuditWriter(t *testing.T) {
+	var buf bytes.Buffer
+	ps := New(-1, WithAuditor(AuditWriter(&buf)))
+	ps.PublishWithContext(ContextWithIdentity(context.Background(), "bob"), "orders", map[string]int{"id": 1})
+	ps.Publish("orders", func() {})
+
+	dec := json.NewDecoder(&buf)
+	var records []AuditRecord
+	for dec.More() {
+		var record AuditRecord
+		assert.Equal(t, dec.Decode(&record), nil)
+		records = append(records, record)
+	}
+	assert.Equal(t, len(records), 2)
+	assert.Equal(t, records[0].Identity, "bob")
+	assert.Equal(t, records[0].Message, map[string]interface{}{"id": 1.0})
+	assert.Equal(t, records[1].Message != nil, true)
+	assert.Equal(t, records[0].Digest, AuditDigest(JSON, map[string]int{"id": 1}))
+	records[0].Message, records[1].Message = nil, nil
+	assert.Equal(t, VerifyAudit("", records), -1)
+
+	// a changed message doesn't match its digest, which is chained
+	assert.Equal(t, records[0].Digest != AuditDigest(JSON, map[string]int{"id": 2}), true)
+	records[0].Digest = AuditDigest(JSON, map[string]int{"id": 2})
+	assert.Equal(t, VerifyAudit("", records), 0)
+}
@@ -0,0 +1,112 @@
+package pubsub
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Batch coalesces the messages received from c into batches of at most size
+// messages, sent to the returned channel when full or delay after their first
+// message, e.g. to write many small messages in one frame. The returned channel is
+// closed after the pending batch when c is closed or ctx is done.
+func Batch(ctx context.Context, c <-chan Event, size int, delay time.Duration) <-chan []Event {
+	if size <= 0 {
+		size = 1
+	}
+	out := make(chan []Event)
+	go func() {
+		defer close(out)
+
+		var (
+			batch []Event
+			timer *time.Timer
+			fire  <-chan time.Time
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, fire = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			select {
+			case out <- batch:
+				batch = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for {
+			select {
+			case event, ok := <-c:
+				if !ok {
+					flush()
+					return
+				}
+				batch = append(batch, event)
+				if len(batch) >= size {
+					if !flush() {
+						return
+					}
+				} else if timer == nil {
+					timer = time.NewTimer(delay)
+					fire = timer.C
+				}
+			case <-fire:
+				if !flush() {
+					return
+				}
+			case <-ctx.Done():
+				flush()
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// PublishBatch publishes msgs to name in order like Publish, looking the
+// subscribers of name up once for the whole batch. Subscriptions changed during the
+// batch apply from the next batch.
+func (p *Pubsub) PublishBatch(name string, msgs []interface{}) PublishResult {
+	name = p.normalize(name)
+	var targets []target
+	p.route(name, func(s *subscriber, pattern string) {
+		targets = append(targets, target{s, pattern})
+	})
+	route := func(n string, fn func(s *subscriber, pattern string)) {
+		if n != name {
+			// renamed by a middleware
+			p.route(n, fn)
+			return
+		}
+		for _, t := range targets {
+			fn(t.s, t.pattern)
+		}
+	}
+
+	var result PublishResult
+	for _, msg := range msgs {
+		result.merge(p.publishRouted(p.newEvent(name, msg), route))
+	}
+	return result
+}
+
+// PublishMulti publishes a message to each name of msgs like Publish, in the order
+// of the names.
+func (p *Pubsub) PublishMulti(msgs map[string]interface{}) PublishResult {
+	names := make([]string, 0, len(msgs))
+	for name := range msgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result PublishResult
+	for _, name := range names {
+		result.merge(p.Publish(name, msgs[name]))
+	}
+	return result
+}
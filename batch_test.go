@@ -0,0 +1,70 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestBatch(t *testing.T) {
+	c := make(chan Event)
+	batches := Batch(context.Background(), c, 3, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		c <- Event{Message: i}
+	}
+	assert.Equal(t, <-batches, []Event{{Message: 0}, {Message: 1}, {Message: 2}})
+	c <- Event{Message: 3}
+
+	start := time.Now()
+	assert.Equal(t, <-batches, []Event{{Message: 3}})
+	assert.Equal(t, time.Since(start) < time.Second, true)
+
+	c <- Event{Message: 4}
+	close(c)
+	assert.Equal(t, <-batches, []Event{{Message: 4}})
+	_, ok := <-batches
+	assert.Equal(t, ok, false)
+}
+
+func TestBatchCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := Batch(ctx, make(chan Event), 3, time.Hour)
+	cancel()
+	_, ok := <-batches
+	assert.Equal(t, ok, false)
+}
+
+func TestPublishBatch(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.PSubscribe("a*", c)
+
+	r := ps.PublishBatch("a", []interface{}{1, 2, 3})
+	assert.Equal(t, r.Delivered, 6)
+	for _, want := range []int{1, 1, 2, 2, 3, 3} {
+		assert.Equal(t, (<-c).Message, want)
+	}
+
+	r = ps.PublishMulti(map[string]interface{}{"ab": 2, "a": 1, "b": 3})
+	assert.Equal(t, r.Delivered, 3)
+	events := receiveN(t, c, 3)
+	assert.Equal(t, events[0].Name, "a")
+	assert.Equal(t, events[2].Name, "ab")
+}
+
+func BenchmarkPublishBatch(b *testing.B) {
+	ps := New(-1)
+	for i := 0; i < 100; i++ {
+		ps.PSubscribe(fmt.Sprintf("other%d.*", i), make(chan Event, 1))
+	}
+	msgs := make([]interface{}, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.PublishBatch("a", msgs)
+	}
+}
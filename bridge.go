@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// HeaderVia is the header of the messages forwarded by Bridge, listing the Pubsubs
+// the message went through. Bridge doesn't forward a message back to a Pubsub it
+// went through, so bridging two Pubsubs both ways doesn't loop.
+const HeaderVia = "Pubsub-Via"
+
+// bridgeBuffer is the size of the buffer of the channel of a Bridge.
+const bridgeBuffer = 256
+
+var pubsubIDs atomic.Uint64
+
+// bridgeID returns the ID of the Pubsub in the HeaderVia header.
+func (p *Pubsub) bridgeID() string {
+	p.bridgeOnce.Do(func() {
+		p.viaID = strconv.FormatUint(pubsubIDs.Add(1), 10)
+	})
+	return p.viaID
+}
+
+// Bridge forwards the messages published to the topics of src matching pattern to
+// dst, with their headers, until closed. transform can remap the topic and the
+// message, or skip the message by returning false, it's not called if nil. Like any
+// subscriber, the bridge drops the messages it isn't ready to receive. It returns
+// the error of PSubscribe if pattern can't be subscribed to, e.g. ErrBadPattern.
+func Bridge(src, dst *Pubsub, pattern string, transform func(topic string, msg interface{}) (string, interface{}, bool)) (io.Closer, error) {
+	b := &bridge{
+		src:  src,
+		c:    make(chan Event, bridgeBuffer),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := src.PSubscribe(pattern, b.c); err != nil {
+		return nil, err
+	}
+	go b.run(dst, transform)
+	return b, nil
+}
+
+type bridge struct {
+	src  *Pubsub
+	c    chan Event
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func (b *bridge) run(dst *Pubsub, transform func(topic string, msg interface{}) (string, interface{}, bool)) {
+	defer close(b.done)
+	srcID, dstID := b.src.bridgeID(), dst.bridgeID()
+	for {
+		var event Event
+		select {
+		case event = <-b.c:
+		case <-b.stop:
+			return
+		}
+		via := event.Headers[HeaderVia]
+		if via == "" {
+			via = srcID
+		}
+		if containsID(via, dstID) {
+			continue
+		}
+		name, message := event.Name, event.Message
+		if transform != nil {
+			var ok bool
+			if name, message, ok = transform(name, message); !ok {
+				continue
+			}
+		}
+		headers := make(map[string]string, len(event.Headers)+1)
+		for k, v := range event.Headers {
+			headers[k] = v
+		}
+		headers[HeaderVia] = via + "," + dstID
+		dst.PublishMsg(name, message, headers)
+	}
+}
+
+func containsID(via, id string) bool {
+	for _, v := range strings.Split(via, ",") {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Close stops forwarding messages.
+func (b *bridge) Close() error {
+	b.once.Do(func() {
+		b.src.UnsubscribeAll(b.c)
+		close(b.stop)
+	})
+	<-b.done
+	return nil
+}
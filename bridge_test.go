@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestBridge(t *testing.T) {
+	a, b := New(-1), New(-1)
+	ab, err := Bridge(a, b, "sensors.*", func(topic string, msg interface{}) (string, interface{}, bool) {
+		if msg == "skip" {
+			return "", nil, false
+		}
+		return strings.Replace(topic, "sensors.", "mirror.", 1), msg, true
+	})
+	assert.Equal(t, err, nil)
+	ba, err := Bridge(b, a, "mirror.*", nil)
+	assert.Equal(t, err, nil)
+
+	ca := make(chan Event, 10)
+	cb := make(chan Event, 10)
+	a.PSubscribe("mirror.*", ca)
+	b.Subscribe("mirror.temp", cb)
+
+	a.PublishMsg("sensors.temp", "skip", nil)
+	a.PublishMsg("sensors.temp", 21, map[string]string{"unit": "C"})
+	e := receiveN(t, cb, 1)[0]
+	assert.Equal(t, e.Message, 21)
+	assert.Equal(t, e.Headers["unit"], "C")
+
+	// not forwarded back to a
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, len(ca), 0)
+
+	b.Publish("mirror.temp", 22)
+	assert.Equal(t, receiveN(t, ca, 1)[0].Message, 22)
+	receiveN(t, cb, 1)
+
+	assert.Equal(t, ab.Close(), nil)
+	assert.Equal(t, ba.Close(), nil)
+	a.Publish("sensors.temp", 23)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, len(cb), 0)
+	assert.Equal(t, a.NumSubscribers("sensors.temp"), 0)
+}
+
+func TestBridgeBadPattern(t *testing.T) {
+	a, b := New(-1), New(-1)
+	closer, err := Bridge(a, b, "sensors[", nil)
+	assert.Equal(t, errors.Is(err, ErrBadPattern), true)
+	assert.Equal(t, closer, nil)
+	assert.Equal(t, a.Patterns(), []string{})
+}
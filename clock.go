@@ -0,0 +1,51 @@
+package pubsub
+
+import "time"
+
+// Clock is the time source of the Pubsub for the scheduled messages of
+// PublishAfter, PublishAt and PublishEvery, the times of the messages
+// WithTimestamps, the expiry of the messages and the topics, the drops, the
+// notices, the deduplication window, the acknowledgement deadlines and the
+// rates, e.g. a fake clock in tests.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a Timer sending the time on its channel after d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, like time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// WithClock uses c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(p *Pubsub) {
+		p.clock = c
+	}
+}
+
+// expired returns whether event has a TTL which is over by the clock.
+func (p *Pubsub) expired(event Event) bool {
+	return event.expiredAt(p.clock.Now())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+// manualClock is a Clock which only moves when advanced, without timers.
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(time.Hour)}
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestClock(t *testing.T) {
+	clock := &manualClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var drops []time.Time
+	ps := New(-1, WithClock(clock), WithHistory(2), WithTopicExpiry(time.Minute), OnDrop(func(d DroppedMessage) {
+		drops = append(drops, d.Time)
+	}))
+	defer ps.Close()
+
+	// the TTL of the messages
+	ps.PublishTTL("a", 1, time.Second)
+	assert.Equal(t, len(ps.History("a")), 1)
+	clock.advance(time.Second)
+	assert.Equal(t, len(ps.History("a")), 0)
+
+	ps.Subscribe("b", make(chan Event))
+	ps.Publish("b", 1)
+	assert.Equal(t, drops, []time.Time{clock.Now()})
+
+	// the expiry of the topics
+	ps.Publish("c", 1)
+	assert.Equal(t, ps.CompactTopics(), 0)
+	clock.advance(time.Minute + time.Nanosecond)
+	assert.Equal(t, ps.CompactTopics(), 2)
+}
+
+func TestClockDedupNotices(t *testing.T) {
+	clock := &manualClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ps := New(-1, WithClock(clock), WithDedup(time.Minute, func(e Event) string {
+		id, _ := e.Message.(string)
+		return id
+	}))
+	defer ps.Close()
+
+	// the deduplication window
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 1)
+	clock.advance(time.Minute - time.Nanosecond)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 0)
+	clock.advance(time.Nanosecond)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 1)
+
+	// the time of the notices
+	events, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+	ps.Notify(Notice{Kind: NoticeBridgeError})
+	assert.Equal(t, receiveNotice(t, events).Time, clock.Now())
+}
@@ -0,0 +1,84 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec encodes the messages sent to other processes by the network facing
+// packages, e.g. the bridges, and decodes them. Subscribers in the process keep
+// receiving the published values.
+type Codec interface {
+	ContentType() string
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+var (
+	// JSON encodes with encoding/json, it's the default Codec.
+	JSON Codec = jsonCodec{}
+	// Gob encodes with encoding/gob. Values sent as interface values must be
+	// registered with gob.Register.
+	Gob Codec = gobCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) ContentType() string {
+	return "application/x-gob"
+}
+
+func (gobCodec) Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Decode(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func init() {
+	RegisterDecoder(Gob.ContentType(), Gob.Decode)
+}
+
+// RegisterCodec registers the decoder of c for its content type, so Into decodes
+// the messages c encoded. The decoders are global to the process, they aren't
+// registered by the options taking a Codec.
+func RegisterCodec(c Codec) {
+	RegisterDecoder(c.ContentType(), c.Decode)
+}
+
+// WithCodec encodes the messages sent to other processes with c instead of JSON,
+// for the network facing packages without a codec of their own. Register c with
+// RegisterCodec for Into to decode the messages it encoded.
+func WithCodec(c Codec) Option {
+	return func(p *Pubsub) {
+		p.codec = c
+	}
+}
+
+// Codec returns the Codec of the Pubsub.
+func (p *Pubsub) Codec() Codec {
+	if p.codec == nil {
+		return JSON
+	}
+	return p.codec
+}
@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestCodec(t *testing.T) {
+	type order struct {
+		ID    int
+		Items []string
+	}
+	for _, c := range []Codec{JSON, Gob} {
+		data, err := c.Encode(order{1, []string{"a"}})
+		assert.Equal(t, err, nil)
+
+		var got order
+		e := Event{Message: data, Headers: map[string]string{HeaderContentType: c.ContentType()}}
+		assert.Equal(t, e.Into(&got), nil)
+		assert.Equal(t, got, order{1, []string{"a"}})
+	}
+}
+
+type testCodec struct{ jsonCodec }
+
+func (testCodec) ContentType() string {
+	return "application/x-test"
+}
+
+func TestWithCodec(t *testing.T) {
+	assert.Equal(t, New(-1).Codec(), JSON)
+	assert.Equal(t, New(-1, WithCodec(Gob)).Codec(), Gob)
+
+	// not registered by WithCodec
+	ps := New(-1, WithCodec(testCodec{}))
+	data, _ := ps.Codec().Encode(1)
+	e := Event{Message: data, Headers: map[string]string{HeaderContentType: "application/x-test"}}
+	var n int
+	assert.Equal(t, e.Into(&n) != nil, true)
+	RegisterCodec(testCodec{})
+	t.Cleanup(func() {
+		decoders.Lock()
+		defer decoders.Unlock()
+		delete(decoders.m, "application/x-test")
+	})
+	assert.Equal(t, e.Into(&n), nil)
+	assert.Equal(t, n, 1)
+}
@@ -0,0 +1,191 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithTopicExpiry compacts the topics idle for d every d, until the Pubsub is
+// closed, like CompactTopics. A topic is idle without subscribers and without
+// messages published to it, e.g. the topics of a session ID once the session ended.
+func WithTopicExpiry(d time.Duration) Option {
+	return func(p *Pubsub) {
+		if d > 0 {
+			p.expiry = d
+		}
+	}
+}
+
+// CompactTopics forgets the state kept for the names which have no subscribers, by
+// name or matching their patterns and filters, and returns the number of such
+// names: their expired retained message, history, rates, sequence numbers, counters
+// of WithTopicStats and the caches of their options and routes. The sequence
+// numbers and the counters of a compacted name start again, the totals of Stats
+// still count its messages. The names of durable topics with consumers and with a
+// retained message which didn't expire are kept, like the policy of the names
+// rejecting late subscribers after their first message. If the Pubsub is created
+// WithTopicExpiry, only the names idle for its duration are compacted.
+func (p *Pubsub) CompactTopics() int {
+	return p.compact(p.clock.Now().Add(-p.expiry))
+}
+
+// touch records the activity of name, if the topics expire.
+func (p *Pubsub) touch(name string) {
+	if p.expiry <= 0 {
+		return
+	}
+	now := p.clock.Now().UnixNano()
+	if v, ok := p.activity.Load(name); ok {
+		v.(*atomic.Int64).Store(now)
+		return
+	}
+	last := new(atomic.Int64)
+	last.Store(now)
+	if v, loaded := p.activity.LoadOrStore(name, last); loaded {
+		v.(*atomic.Int64).Store(now)
+	}
+}
+
+// idle returns whether name has no subscribers, no retained message which can't
+// expire and no activity after cutoff. It must be called with the write lock held.
+func (p *Pubsub) idle(name string, cutoff time.Time) bool {
+	if len(p.shard(name).channels[name]) > 0 || len(p.queues[name]) > 0 || p.rings[name] != nil {
+		return false
+	}
+	if event, ok := p.retained[name]; ok && !p.expired(event) {
+		return false
+	}
+	if p.durable != nil && p.consumed(name) {
+		return false
+	}
+	matched := false
+	p.routeGroups(name, func(_ kind, _ string, subs []*subscriber) {
+		matched = matched || len(subs) > 0
+	})
+	if matched {
+		return false
+	}
+	if v, ok := p.activity.Load(name); ok && v.(*atomic.Int64).Load() > cutoff.UnixNano() {
+		return false
+	}
+	return true
+}
+
+func (p *Pubsub) compact(cutoff time.Time) int {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	names := map[string]bool{}
+	keys := func(m *sync.Map) {
+		m.Range(func(k, _ interface{}) bool {
+			names[k.(string)] = true
+			return true
+		})
+	}
+	for name := range p.retained {
+		names[name] = true
+	}
+	if p.history != nil {
+		p.history.mu.Lock()
+		for name := range p.history.topics {
+			names[name] = true
+		}
+		p.history.mu.Unlock()
+	}
+	if p.window != nil {
+		p.window.mu.Lock()
+		for name := range p.window.topics {
+			names[name] = true
+		}
+		p.window.mu.Unlock()
+	}
+	caches := p.caches()
+	for _, m := range caches {
+		keys(m)
+	}
+	if p.late != nil {
+		keys(&p.late.cache)
+	}
+	if p.durable != nil {
+		keys(&p.durable.cache)
+	}
+	keys(&p.activity)
+
+	compacted := map[string]bool{}
+	for name := range names {
+		if !p.idle(name, cutoff) {
+			continue
+		}
+		compacted[name] = true
+		delete(p.retained, name)
+		if p.history != nil {
+			p.history.mu.Lock()
+			delete(p.history.topics, name)
+			p.history.mu.Unlock()
+		}
+		if p.window != nil {
+			p.window.mu.Lock()
+			delete(p.window.topics, name)
+			p.window.mu.Unlock()
+		}
+		if p.sequences != nil {
+			if v, ok := p.sequences.topics.Load(name); ok {
+				// not while a message of name is delivered
+				seq := v.(*topicSequence)
+				if seq.mu.TryLock() {
+					p.sequences.topics.Delete(name)
+					seq.mu.Unlock()
+				}
+			}
+		}
+		for _, m := range caches {
+			m.Delete(name)
+		}
+		if p.late != nil {
+			p.forgetLate(name)
+		}
+		if p.durable != nil {
+			p.forgetDurable(name)
+		}
+		p.activity.Delete(name)
+	}
+	p.routeCache.forget(compacted)
+	return len(compacted)
+}
+
+// caches returns the maps keeping state per name which can always be dropped,
+// unlike the sequence numbers, the late policies and the durable topics.
+func (p *Pubsub) caches() []*sync.Map {
+	caches := []*sync.Map{&p.maxes.cache, &p.schemas.cache}
+	if p.stats != nil {
+		caches = append(caches, &p.stats.topics)
+	}
+	if p.concurrency != nil {
+		caches = append(caches, &p.concurrency.cache)
+	}
+	if p.topicDrops != nil {
+		caches = append(caches, &p.topicDrops.cache)
+	}
+	if p.shed != nil {
+		caches = append(caches, &p.shed.cache)
+	}
+	if p.limits != nil {
+		caches = append(caches, &p.limits.cache)
+	}
+	return caches
+}
+
+func (p *Pubsub) expireTopics(d time.Duration) {
+	timer := p.clock.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-timer.C():
+			p.compact(now.Add(-d))
+			timer.Reset(d)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestCompactTopics(t *testing.T) {
+	ps := New(-1, WithHistory(2), WithTopicStats(), WithSequence())
+	c := make(chan Event, 1)
+	ps.Subscribe("kept", c)
+	ps.PublishRetain("kept", 1)
+	ps.PublishRetain("session/1", 1)
+	ps.retained["session/1"] = Event{Name: "session/1", Message: 1, Expires: time.Now()}
+	ps.Publish("session/2", 2)
+
+	assert.Equal(t, ps.CompactTopics(), 2)
+	_, ok := ps.Retained("session/1")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(ps.History("session/2")), 0)
+	// the counters of the topic start again, not the totals
+	_, ok = ps.Stats().Topics["session/2"]
+	assert.Equal(t, ok, false)
+	assert.Equal(t, ps.Stats().Published, uint64(3))
+	v, _ := ps.Retained("kept")
+	assert.Equal(t, v, 1)
+	assert.Equal(t, len(ps.History("kept")), 1)
+	assert.Equal(t, ps.CompactTopics(), 0)
+
+	// the retained messages which can't expire are kept
+	ps.Unsubscribe("kept", c)
+	assert.Equal(t, ps.CompactTopics(), 0)
+	ps.PublishRetain("kept", nil)
+	assert.Equal(t, ps.CompactTopics(), 1)
+	ps.Publish("kept", 2)
+	assert.Equal(t, ps.History("kept")[0].Seq, uint64(1))
+}
+
+func TestCompactTopicsMatched(t *testing.T) {
+	ps := New(-1, WithHistory(1), WithLatePolicy("late", RejectLate))
+	c := make(chan Event, 2)
+	ps.PSubscribe("orders/*", c)
+	ps.HSubscribe("users/#", c)
+	ps.Publish("orders/1", 1)
+	ps.Publish("users/1", 1)
+	ps.Publish("late", 1)
+
+	assert.Equal(t, ps.CompactTopics(), 1)
+	assert.Equal(t, len(ps.History("orders/1")), 1)
+	assert.Equal(t, len(ps.History("users/1")), 1)
+	// the late policy still rejects late subscribers
+	assert.Equal(t, ps.Subscribe("late", make(chan Event)), ErrLateSubscriber)
+}
+
+func TestCompactTopicsChurn(t *testing.T) {
+	store := NewMemoryStore()
+	ps := New(-1, WithTopicStats(), WithTopicConcurrency("*", 1),
+		WithLatePolicy("late/*", RejectLate), WithDurable("durable/*", store))
+	ps.PSubscribe("other/*", make(chan Event))
+	for i := 0; i < 100; i++ {
+		for _, name := range []string{fmt.Sprint("session/", i), fmt.Sprint("late/", i), fmt.Sprint("durable/", i)} {
+			c := make(chan Event, 1)
+			ps.Subscribe(name, c)
+			ps.Publish(name, i)
+			ps.Unsubscribe(name, c)
+		}
+	}
+	d, err := ps.SubscribeDurable("durable/0", "consumer", make(chan Event, 1))
+	assert.Equal(t, err, nil)
+	defer d.Close()
+
+	assert.Equal(t, ps.CompactTopics(), 299)
+	size := func(m *sync.Map) int {
+		n := 0
+		m.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	// the durable topic with a consumer is kept
+	assert.Equal(t, size(&ps.durable.cache), 1)
+	assert.Equal(t, size(&ps.stats.topics), 1)
+	assert.Equal(t, size(&ps.concurrency.cache), 0)
+	// the started topics still reject late subscribers, besides the durable topic
+	assert.Equal(t, size(&ps.late.cache), 100+1)
+	assert.Equal(t, ps.Subscribe("late/1", make(chan Event)), ErrLateSubscriber)
+	routes := 0
+	for i := range ps.routeCache.shards {
+		if snap := ps.routeCache.shards[i].snapshot.Load(); snap != nil {
+			routes += len(snap.entries)
+		}
+	}
+	assert.Equal(t, routes, 0)
+
+	// a compacted durable topic is looked up again
+	ps.Publish("durable/1", "again")
+	c := make(chan Event, 2)
+	d1, err := ps.SubscribeDurable("durable/1", "consumer", c)
+	assert.Equal(t, err, nil)
+	defer d1.Close()
+	assert.Equal(t, (<-c).Message, 1)
+	assert.Equal(t, (<-c).Message, "again")
+}
+
+func TestTopicExpiry(t *testing.T) {
+	ps := New(-1, WithTopicExpiry(time.Hour), WithHistory(1))
+	defer ps.Close()
+	ps.Publish("a", 1)
+	assert.Equal(t, ps.CompactTopics(), 0)
+
+	// idle for the expiry
+	ps.compact(time.Now())
+	assert.Equal(t, len(ps.History("a")), 0)
+
+	ps.Publish("b", 1)
+	c := make(chan Event)
+	ps.Subscribe("b", c)
+	assert.Equal(t, ps.compact(time.Now()), 0)
+	ps.Unsubscribe("b", c)
+	assert.Equal(t, ps.CompactTopics(), 0)
+}
+
+func TestTopicExpiryTicker(t *testing.T) {
+	ps := New(-1, WithTopicExpiry(10*time.Millisecond), WithHistory(1))
+	defer ps.Close()
+	ps.Publish("a", 1)
+	deadline := time.Now().Add(time.Second)
+	for len(ps.History("a")) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("topic didn't expire")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
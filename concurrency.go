@@ -0,0 +1,51 @@
+package pubsub
+
+import "sync"
+
+// WithTopicConcurrency bounds to n the number of messages of the topics matching
+// pattern, matched by the Matcher of the Pubsub, which are handled concurrently
+// by all callback subscriptions. Handlers wait for a free slot before handling a
+// message. The first matching pattern wins.
+func WithTopicConcurrency(pattern string, n int) Option {
+	return func(p *Pubsub) {
+		if n <= 0 {
+			return
+		}
+		if p.concurrency == nil {
+			p.concurrency = &concurrency{}
+		}
+		p.concurrency.rules = append(p.concurrency.rules, concurrencyRule{
+			pattern: pattern,
+			slots:   make(chan struct{}, n),
+		})
+	}
+}
+
+type concurrencyRule struct {
+	pattern string
+	slots   chan struct{}
+}
+
+type concurrency struct {
+	rules []concurrencyRule
+	cache sync.Map // name -> chan struct{}, nil if unbounded
+}
+
+// slots returns the slots bounding the concurrency of name, or nil if unbounded.
+func (p *Pubsub) slots(name string) chan struct{} {
+	if p.concurrency == nil {
+		return nil
+	}
+	if v, ok := p.concurrency.cache.Load(name); ok {
+		return v.(chan struct{})
+	}
+	var slots chan struct{}
+	for _, rule := range p.concurrency.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			slots = rule.slots
+			break
+		}
+	}
+	p.concurrency.cache.Store(name, slots)
+	return slots
+}
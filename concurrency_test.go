@@ -0,0 +1,40 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestTopicConcurrency(t *testing.T) {
+	ps := New(-1, WithTopicConcurrency("db.*", 1))
+	assert.Equal(t, ps.slots("other") == nil, true)
+
+	var (
+		running, max atomic.Int32
+		wg           sync.WaitGroup
+	)
+	handler := func(context.Context, Event) {
+		defer wg.Done()
+		n := running.Add(1)
+		if n > max.Load() {
+			max.Store(n)
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+	}
+	for i := 0; i < 4; i++ {
+		_, err := ps.SubscribeFunc(context.Background(), "db.write", handler)
+		assert.Equal(t, err, nil)
+	}
+	for i := 0; i < 3; i++ {
+		wg.Add(4)
+		ps.Publish("db.write", i)
+	}
+	wg.Wait()
+	assert.Equal(t, max.Load(), int32(1))
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNotReloadable is returned by UpdateConfig for a setting which can't change on
+// a running Pubsub.
+var ErrNotReloadable = errors.New("setting can't change on a running pubsub")
+
+// Config is the configuration of a Pubsub created by NewWithConfig, which can be
+// updated with UpdateConfig. The zero Config is the configuration of New(0).
+type Config struct {
+	MaxSubscribers   int                 // of a name or pattern, the max of New
+	MaxSubscriptions int                 // like WithMaxSubscriptions
+	MaxTopics        int                 // like WithMaxTopics
+	History          int                 // like WithHistory
+	Overflow         Overflow            // of the subscriptions of SubscribeWith without WithOverflow
+	Matcher          Matcher             // like WithMatcher, GlobMatcher if nil
+	Normalizer       func(string) string // like WithTopicNormalizer
+}
+
+// NewWithConfig returns a new Pubsub configured by cfg and opts, which are applied
+// after cfg.
+func NewWithConfig(cfg Config, opts ...Option) *Pubsub {
+	return New(cfg.MaxSubscribers, append([]Option{cfg.apply}, opts...)...)
+}
+
+func (cfg Config) apply(p *Pubsub) {
+	p.maxSubs = cfg.MaxSubscriptions
+	p.maxTopics = cfg.MaxTopics
+	p.overflow = cfg.Overflow
+	if cfg.History > 0 {
+		WithHistory(cfg.History)(p)
+	}
+	if cfg.Matcher != nil {
+		p.matcher = cfg.Matcher
+	}
+	p.normalizer = cfg.Normalizer
+}
+
+// Config returns the current configuration of the Pubsub.
+func (p *Pubsub) Config() Config {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	cfg := Config{
+		MaxSubscribers:   p.max,
+		MaxSubscriptions: p.maxSubs,
+		MaxTopics:        p.maxTopics,
+		Overflow:         p.overflow,
+		Matcher:          p.matcher,
+		Normalizer:       p.normalizer,
+	}
+	if p.history != nil {
+		cfg.History = p.history.len()
+	}
+	return cfg
+}
+
+// UpdateConfig applies cfg to the running Pubsub at once. The limits apply to the
+// subscriptions from then on, the existing ones are kept. The history of every
+// topic is resized, but can't be enabled or disabled. The Matcher and the
+// Normalizer can't change, the patterns and names are already matched and
+// normalized by them. If cfg changes a setting which can't change, UpdateConfig
+// returns ErrNotReloadable and doesn't apply anything.
+func (p *Pubsub) UpdateConfig(cfg Config) error {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if (cfg.History > 0) != (p.history != nil) {
+		return fmt.Errorf("pubsub: history: %w", ErrNotReloadable)
+	}
+	if cfg.Matcher == nil {
+		cfg.Matcher = GlobMatcher{}
+	}
+	if !sameValue(cfg.Matcher, p.matcher) {
+		return fmt.Errorf("pubsub: matcher: %w", ErrNotReloadable)
+	}
+	if !sameValue(cfg.Normalizer, p.normalizer) {
+		return fmt.Errorf("pubsub: normalizer: %w", ErrNotReloadable)
+	}
+
+	p.max = cfg.MaxSubscribers
+	p.maxSubs = cfg.MaxSubscriptions
+	p.maxTopics = cfg.MaxTopics
+	p.overflow = cfg.Overflow
+	if p.history != nil {
+		p.history.resize(cfg.History)
+	}
+	// the cached max of the names without a rule is the max of the Pubsub
+	p.maxes.mu.Lock()
+	clearMap(&p.maxes.cache)
+	p.maxes.mu.Unlock()
+	return nil
+}
+
+// sameValue returns whether a and b are the same value, or the same func.
+func sameValue(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() {
+		return va.IsValid() == vb.IsValid()
+	}
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Func:
+		return va.IsNil() == vb.IsNil() && va.Pointer() == vb.Pointer()
+	case reflect.Pointer:
+		return va.Pointer() == vb.Pointer()
+	}
+	return va.Type().Comparable() && a == b
+}
@@ -0,0 +1,76 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestNewWithConfig(t *testing.T) {
+	ps := NewWithConfig(Config{
+		MaxSubscribers: 1,
+		History:        2,
+		Overflow:       OverflowDropOldest,
+		Normalizer:     CaseInsensitive,
+	})
+	sub, err := ps.SubscribeWith("A", WithBuffer(1))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ps.Subscribe("a", make(chan Event)), ErrMaxSubscribe)
+
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-sub.Channel()).Message, 2)
+	assert.Equal(t, len(ps.History("a")), 2)
+
+	cfg := ps.Config()
+	assert.Equal(t, cfg.MaxSubscribers, 1)
+	assert.Equal(t, cfg.History, 2)
+	assert.Equal(t, cfg.Overflow, OverflowDropOldest)
+	assert.Equal(t, cfg.Matcher, Matcher(GlobMatcher{}))
+}
+
+func TestUpdateConfig(t *testing.T) {
+	ps := NewWithConfig(Config{MaxSubscribers: 1, History: 3})
+	assert.Equal(t, ps.Subscribe("a", make(chan Event)), nil)
+	assert.Equal(t, ps.Subscribe("a", make(chan Event)), ErrMaxSubscribe)
+	for i := 0; i < 3; i++ {
+		ps.Publish("a", i)
+	}
+
+	cfg := ps.Config()
+	cfg.MaxSubscribers = 2
+	cfg.MaxTopics = 1
+	cfg.History = 2
+	cfg.Overflow = OverflowLatest
+	assert.Equal(t, ps.UpdateConfig(cfg), nil)
+	assert.Equal(t, ps.Config(), cfg)
+
+	assert.Equal(t, ps.Subscribe("a", make(chan Event)), nil)
+	assert.Equal(t, ps.Subscribe("b", make(chan Event)), ErrMaxTopics)
+	history := ps.History("a")
+	assert.Equal(t, len(history), 2)
+	assert.Equal(t, history[0].Message, 1)
+	ps.Publish("a", 3)
+	assert.Equal(t, ps.History("a")[1].Message, 3)
+
+	sub, err := ps.PSubscribeWith("*", WithBuffer(1))
+	assert.Equal(t, err, nil)
+	ps.Publish("a", 4)
+	ps.Publish("a", 5)
+	assert.Equal(t, (<-sub.Channel()).Message, 4)
+	assert.Equal(t, (<-sub.Channel()).Message, 5)
+}
+
+func TestUpdateConfigNotReloadable(t *testing.T) {
+	ps := NewWithConfig(Config{MaxSubscribers: 1})
+	for _, cfg := range []Config{
+		{MaxSubscribers: 2, History: 1},
+		{MaxSubscribers: 2, Matcher: MQTTMatcher{}},
+		{MaxSubscribers: 2, Normalizer: CaseInsensitive},
+	} {
+		assert.Equal(t, errors.Is(ps.UpdateConfig(cfg), ErrNotReloadable), true)
+	}
+	assert.Equal(t, ps.Config().MaxSubscribers, 1)
+	assert.Equal(t, ps.UpdateConfig(Config{MaxSubscribers: 2}), nil)
+}
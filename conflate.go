@@ -0,0 +1,136 @@
+package pubsub
+
+import "sync"
+
+// SubscribeConflated subscribes channel c to name like Subscribe, keeping only the
+// latest message when c isn't ready to receive instead of dropping it, e.g. for
+// state updates like prices or progress. The kept message is sent as soon as c is
+// ready, unless a newer one replaces it. Messages already buffered in c aren't
+// replaced, so c should have a small buffer.
+func (p *Pubsub) SubscribeConflated(name string, c chan Event) error {
+	name = p.normalize(name)
+	return p.subscribeConflated(byName, name, c)
+}
+
+// PSubscribeConflated is like SubscribeConflated for the topics matching pattern,
+// keeping the latest message of each topic.
+func (p *Pubsub) PSubscribeConflated(pattern string, c chan Event) error {
+	pattern = p.normalize(pattern)
+	return p.subscribeConflated(byPattern, pattern, c)
+}
+
+func (p *Pubsub) subscribeConflated(k kind, name string, c chan Event) error {
+	if c == nil {
+		return nil
+	}
+	if k == byPattern {
+		if err := p.validatePattern(name); err != nil {
+			return err
+		}
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	_, err := p.addWith(k, name, c, func(s *subscriber) { s.conflate = &conflater{s: s} })
+	return err
+}
+
+// conflater keeps the latest message of each topic a conflated subscriber wasn't
+// ready to receive, sent by a goroutine running while there are messages kept.
+type conflater struct {
+	s *subscriber
+
+	mu      sync.Mutex
+	pending map[string]Event
+	order   []string // topics of pending, oldest first
+	sending string   // topic of the message being sent
+	running bool
+	stopped bool          // the subscriber was removed
+	quit    chan struct{} // closed when stopped
+}
+
+// replace keeps event if a message of its topic is kept or being sent, so the
+// messages of a topic are sent in order. It returns false otherwise.
+func (f *conflater) replace(event Event) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return false
+	}
+	if _, ok := f.pending[event.Name]; !ok && (!f.running || f.sending != event.Name) {
+		return false
+	}
+	f.keepLocked(event)
+	return true
+}
+
+// keep keeps event until the channel is ready.
+func (f *conflater) keep(event Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keepLocked(event)
+}
+
+func (f *conflater) keepLocked(event Event) {
+	if f.stopped {
+		return
+	}
+	if f.pending == nil {
+		f.pending = map[string]Event{}
+		f.quit = make(chan struct{})
+	}
+	if _, ok := f.pending[event.Name]; !ok {
+		f.order = append(f.order, event.Name)
+	}
+	f.pending[event.Name] = event
+	if !f.running {
+		f.running = true
+		go f.flush()
+	}
+}
+
+// stop discards the kept messages once the subscriber is removed, while the
+// subscription of its channel may go on.
+func (f *conflater) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return
+	}
+	f.stopped = true
+	f.order, f.pending = nil, nil
+	if f.quit != nil {
+		close(f.quit)
+	}
+}
+
+// flush sends the kept messages until there is none, or the subscriber is
+// removed.
+func (f *conflater) flush() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	quit := f.quit
+	for len(f.order) > 0 {
+		name := f.order[0]
+		event := f.pending[name]
+		f.order = f.order[1:]
+		delete(f.pending, name)
+		f.sending = name
+		f.mu.Unlock()
+
+		select {
+		case f.s.c <- event:
+		case <-f.s.sub.done:
+			f.mu.Lock()
+			f.order, f.pending = nil, nil
+			continue
+		case <-quit:
+			f.mu.Lock()
+			continue
+		}
+		f.mu.Lock()
+	}
+	f.sending = ""
+	f.running = false
+}
@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSubscribeConflated(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	assert.Equal(t, ps.PSubscribeConflated("price.*", c), nil)
+
+	for i := 0; i < 5; i++ {
+		r := ps.Publish("price.a", i)
+		assert.Equal(t, r.Delivered, 1)
+		ps.Publish("price.b", i*10)
+	}
+	ps.Publish("price.a", 5)
+
+	// the latest messages are kept, and the messages of a topic are in order
+	latest := map[string]interface{}{}
+	n := 0
+	for done := false; !done; {
+		select {
+		case e := <-c:
+			assert.Equal(t, e.Pattern, "price.*")
+			if prev, ok := latest[e.Name]; ok && prev.(int) >= e.Message.(int) {
+				t.Fatalf("%s: %v after %v", e.Name, e.Message, prev)
+			}
+			latest[e.Name] = e.Message
+			n++
+		case <-time.After(20 * time.Millisecond):
+			done = true
+		}
+	}
+	assert.Equal(t, latest["price.a"], 5)
+	assert.Equal(t, latest["price.b"], 40)
+	assert.Equal(t, n < 11, true)
+
+	// unsubscribing stops sending the kept messages
+	ps.Publish("price.a", 6)
+	ps.UnsubscribeAll(c)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case e := <-c:
+		t.Fatalf("unexpected %v", e)
+	default:
+	}
+}
+
+func TestSubscribeConflatedUnsubscribe(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	assert.Equal(t, ps.SubscribeConflated("a", c), nil)
+	assert.Equal(t, ps.SubscribeConflated("b", c), nil)
+
+	// the first message of a is being sent while the second one is kept
+	ps.Publish("a", 0)
+	time.Sleep(10 * time.Millisecond)
+	ps.Publish("a", 1)
+
+	// unsubscribing from a stops sending its messages while c stays subscribed to b
+	ps.Unsubscribe("a", c)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case e := <-c:
+		t.Fatalf("unexpected %v", e)
+	default:
+	}
+
+	ps.Publish("b", 2)
+	assert.Equal(t, (<-c).Message, 2)
+}
@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SubscribeCtx subscribes channel c to name like Subscribe, and unsubscribes it when
+// ctx is done. The Subscription of c is evicted if it was its last subscription.
+// It returns ctx.Err() without subscribing if ctx is already done.
+func (p *Pubsub) SubscribeCtx(ctx context.Context, name string, c chan Event) error {
+	name = p.normalize(name)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.identify(ctx, c)
+	if err := p.Subscribe(name, c); err != nil {
+		p.forgetUnsubscribed(c)
+		return err
+	}
+	p.watchCtx(ctx, byName, name, c)
+	return nil
+}
+
+// PSubscribeCtx subscribes channel c to pattern like PSubscribe, and unsubscribes it
+// when ctx is done. The Subscription of c is evicted if it was its last
+// subscription. It returns ctx.Err() without subscribing if ctx is already done.
+func (p *Pubsub) PSubscribeCtx(ctx context.Context, pattern string, c chan Event) error {
+	pattern = p.normalize(pattern)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	p.identify(ctx, c)
+	if err := p.PSubscribe(pattern, c); err != nil {
+		p.forgetUnsubscribed(c)
+		return err
+	}
+	p.watchCtx(ctx, byPattern, pattern, c)
+	return nil
+}
+
+// ctxWatch unsubscribes a subscriber once the context of SubscribeCtx is done.
+type ctxWatch struct {
+	stop func() bool
+}
+
+// watchCtx unsubscribes the subscriber of c to name once ctx is done, unless it
+// was removed or replaced before. It stops watching the context of a previous
+// SubscribeCtx of c to name.
+func (p *Pubsub) watchCtx(ctx context.Context, k kind, name string, c chan Event) {
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	collection, unlock := p.collection(k, name)
+	defer unlock()
+
+	i := p.findChan(collection[name], c)
+	if i < 0 {
+		// unsubscribed meanwhile
+		return
+	}
+	s := collection[name][i]
+	s.unwatchCtx()
+	w := &ctxWatch{}
+	w.stop = context.AfterFunc(ctx, func() {
+		p.locker.Lock()
+		defer p.locker.Unlock()
+		p.evict(c, func() {
+			collection, unlock := p.collection(k, name)
+			defer unlock()
+			for i, s := range collection[name] {
+				if s.ctx == w {
+					p.removeAt(k, collection, name, i)
+					p.changed(k, name)
+					return
+				}
+			}
+		})
+	})
+	s.ctx = w
+}
+
+// unwatchCtx stops watching the context of SubscribeCtx for s.
+func (s *subscriber) unwatchCtx() {
+	if s.ctx != nil {
+		s.ctx.stop()
+		s.ctx = nil
+	}
+}
+
+// HeaderDeadline is the header of the deadline of the context of a message
+// published with PublishWithContext, formatted with time.RFC3339Nano.
+const HeaderDeadline = "Pubsub-Deadline"
+
+type contextKey struct {
+	header string
+	key    interface{}
+}
+
+// WithContextKey carries the value of key of the context of PublishWithContext in
+// header, formatted with fmt.Sprint. Context restores it as a string value of key.
+func WithContextKey(header string, key interface{}) Option {
+	return func(p *Pubsub) {
+		p.contextKeys = append(p.contextKeys, contextKey{header, key})
+	}
+}
+
+// PublishWithContext publishes a message like Publish, carrying the deadline of ctx,
+// the values of the keys set WithContextKey and, if the Tracer is a TraceCarrier,
+// the trace of ctx in its headers, so a subscriber can continue with the Context of
+// the message, e.g. after a bridge or a durable log. It doesn't publish if ctx is
+// already done, and returns ctx.Err() in the result.
+func (p *Pubsub) PublishWithContext(ctx context.Context, name string, message interface{}) PublishResult {
+	if err := ctx.Err(); err != nil {
+		return PublishResult{Err: err}
+	}
+	event := p.newEventAs(IdentityFromContext(ctx), name, message)
+	headers := make(map[string]string, len(p.contextKeys)+1)
+	if deadline, ok := ctx.Deadline(); ok {
+		headers[HeaderDeadline] = deadline.Format(time.RFC3339Nano)
+	}
+	for _, k := range p.contextKeys {
+		if v := ctx.Value(k.key); v != nil {
+			headers[k.header] = fmt.Sprint(v)
+		}
+	}
+	if c, ok := p.tracer.(TraceCarrier); ok {
+		c.Inject(ctx, headers)
+	}
+	if len(headers) > 0 {
+		event.Headers = headers
+	}
+	return p.publishVia(event)
+}
+
+// Context returns a context derived from parent with what event carries from the
+// context of PublishWithContext: the deadline, the values of the keys set
+// WithContextKey and the trace. The caller must call cancel once event is handled.
+func (p *Pubsub) Context(parent context.Context, event Event) (ctx context.Context, cancel context.CancelFunc) {
+	ctx = parent
+	for _, k := range p.contextKeys {
+		if v, ok := event.Headers[k.header]; ok {
+			ctx = context.WithValue(ctx, k.key, v)
+		}
+	}
+	if c, ok := p.tracer.(TraceCarrier); ok && len(event.Headers) > 0 {
+		ctx = c.Extract(ctx, event)
+	}
+	if v, ok := event.Headers[HeaderDeadline]; ok {
+		if deadline, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return context.WithDeadline(ctx, deadline)
+		}
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeCtx(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.Equal(t, ps.SubscribeCtx(ctx, "topic", c), nil)
+	assert.Equal(t, ps.PSubscribeCtx(ctx, "top*", c), nil)
+	assert.Equal(t, ps.Health().Subscriptions, 2)
+
+	cancel()
+	waitFor(t, func() bool { return ps.Health().Subscriptions == 0 })
+
+	assert.Equal(t, ps.SubscribeCtx(ctx, "topic", c), context.Canceled)
+	assert.Equal(t, ps.PSubscribeCtx(ctx, "top*", c), context.Canceled)
+	assert.Equal(t, ps.Health().Subscriptions, 0)
+}
+
+func TestSubscribeCtxResubscribed(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.Equal(t, ps.SubscribeCtx(ctx, "topic", c), nil)
+	assert.Equal(t, ps.PSubscribeCtx(ctx, "top*", c), nil)
+	ps.Unsubscribe("topic", c)
+	ps.PUnsubscribe("top*", c)
+	assert.Equal(t, ps.Subscribe("topic", c), nil)
+	assert.Equal(t, ps.PSubscribe("top*", c), nil)
+
+	// the context of the removed subscriptions no longer unsubscribes c
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, ps.Health().Subscriptions, 2)
+}
+
+type requestIDKey struct{}
+
+// carrierTracer carries the trace of a context in the "trace" header.
+type carrierTracer struct {
+	parents []string
+}
+
+type traceKey struct{}
+
+func (t *carrierTracer) StartPublish(ctx context.Context, event *Event) func(PublishResult) {
+	parent, _ := ctx.Value(traceKey{}).(string)
+	t.parents = append(t.parents, parent)
+	return nil
+}
+
+func (t *carrierTracer) Inject(ctx context.Context, headers map[string]string) {
+	if trace, ok := ctx.Value(traceKey{}).(string); ok {
+		headers["trace"] = trace
+	}
+}
+
+func (t *carrierTracer) Extract(ctx context.Context, event Event) context.Context {
+	if trace, ok := event.Headers["trace"]; ok {
+		return context.WithValue(ctx, traceKey{}, trace)
+	}
+	return ctx
+}
+
+func TestPublishWithContext(t *testing.T) {
+	tracer := &carrierTracer{}
+	ps := New(-1, WithContextKey("Request-ID", requestIDKey{}), WithTracer(tracer))
+	c := make(chan Event, 2)
+	assert.Equal(t, ps.Subscribe("topic", c), nil)
+
+	deadline := time.Now().Add(time.Hour).Truncate(0)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	ctx = context.WithValue(ctx, requestIDKey{}, 42)
+	ctx = context.WithValue(ctx, traceKey{}, "t1")
+	result := ps.PublishWithContext(ctx, "topic", 1)
+	assert.Equal(t, result.Delivered, 1)
+	assert.Equal(t, tracer.parents, []string{"t1"})
+
+	event := <-c
+	assert.Equal(t, event.Headers["Request-ID"], "42")
+	received, cancel := ps.Context(context.Background(), event)
+	defer cancel()
+	got, ok := received.Deadline()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, got.Equal(deadline), true)
+	assert.Equal(t, received.Value(requestIDKey{}), "42")
+	assert.Equal(t, received.Value(traceKey{}), "t1")
+
+	// without anything to carry
+	ps.PublishWithContext(context.Background(), "topic", 2)
+	event = <-c
+	assert.Equal(t, event.Headers == nil, true)
+	received, cancel = ps.Context(context.Background(), event)
+	_, ok = received.Deadline()
+	assert.Equal(t, ok, false)
+	cancel()
+	assert.Equal(t, received.Err(), context.Canceled)
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = ps.PublishWithContext(done, "topic", 3)
+	assert.Equal(t, result.Err, context.Canceled)
+	assert.Equal(t, len(c), 0)
+}
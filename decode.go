@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Error of decoding a message which isn't assignable and isn't encoded.
+var ErrNotDecodable = errors.New("message can't be decoded")
+
+// Error of decoding into a value which isn't a non-nil pointer.
+var ErrInvalidTarget = errors.New("decode target must be a non-nil pointer")
+
+// DefaultContentType is used to decode messages when no content type is given.
+const DefaultContentType = "application/json"
+
+// Decoder decodes data into the value pointed to by v.
+type Decoder func(data []byte, v interface{}) error
+
+var decoders = struct {
+	sync.RWMutex
+	m map[string]Decoder
+}{
+	m: map[string]Decoder{
+		"application/json":      json.Unmarshal,
+		"application/json+gzip": Gzip(json.Unmarshal),
+	},
+}
+
+// RegisterDecoder registers the decoder d for contentType, replacing any decoder
+// registered before.
+func RegisterDecoder(contentType string, d Decoder) {
+	decoders.Lock()
+	defer decoders.Unlock()
+	decoders.m[contentType] = d
+}
+
+// Gzip returns a Decoder which decompresses the data with gzip before decoding it with d.
+func Gzip(d Decoder) Decoder {
+	return func(data []byte, v interface{}) error {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		data, err = io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return d(data, v)
+	}
+}
+
+// HeaderContentType is the header of the content type of an encoded message.
+const HeaderContentType = "Content-Type"
+
+// Into stores the message of the event in the value pointed to by v. If the message
+// is assignable to v it's stored directly, otherwise a []byte, string or
+// json.RawMessage message is decoded with the decoder of the HeaderContentType
+// header, or of DefaultContentType without the header.
+func (e Event) Into(v interface{}) error {
+	if contentType, ok := e.Headers[HeaderContentType]; ok {
+		return e.IntoAs(contentType, v)
+	}
+	return e.IntoAs(DefaultContentType, v)
+}
+
+// IntoAs is like Into, but decodes encoded messages with the decoder registered for
+// contentType.
+func (e Event) IntoAs(contentType string, v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return ErrInvalidTarget
+	}
+	target = target.Elem()
+
+	if e.Message != nil {
+		msg := reflect.ValueOf(e.Message)
+		if msg.Type().AssignableTo(target.Type()) {
+			target.Set(msg)
+			return nil
+		}
+		if msg.Kind() == reflect.Ptr && !msg.IsNil() && msg.Elem().Type().AssignableTo(target.Type()) {
+			target.Set(msg.Elem())
+			return nil
+		}
+	}
+
+	var data []byte
+	switch m := e.Message.(type) {
+	case []byte:
+		data = m
+	case json.RawMessage:
+		data = m
+	case string:
+		data = []byte(m)
+	default:
+		return ErrNotDecodable
+	}
+
+	decoders.RLock()
+	d, ok := decoders.m[contentType]
+	decoders.RUnlock()
+	if !ok {
+		return fmt.Errorf("pubsub: no decoder for content type %q", contentType)
+	}
+	return d(data, v)
+}
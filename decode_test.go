@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestInto(t *testing.T) {
+	var p point
+	assert.Equal(t, Event{Message: point{1, 2}}.Into(&p), nil)
+	assert.Equal(t, p, point{1, 2})
+	assert.Equal(t, Event{Message: &point{3, 4}}.Into(&p), nil)
+	assert.Equal(t, p, point{3, 4})
+	assert.Equal(t, Event{Message: []byte(`{"X":5,"Y":6}`)}.Into(&p), nil)
+	assert.Equal(t, p, point{5, 6})
+	assert.Equal(t, Event{Message: json.RawMessage(`{"X":7}`)}.Into(&p), nil)
+	assert.Equal(t, p, point{7, 6})
+
+	var s string
+	assert.Equal(t, Event{Message: "raw"}.Into(&s), nil)
+	assert.Equal(t, s, "raw")
+
+	assert.Equal(t, Event{Message: 1}.Into(&p), ErrNotDecodable)
+	assert.Equal(t, Event{Message: 1}.Into(p), ErrInvalidTarget)
+	assert.Equal(t, Event{Message: "{}"}.IntoAs("text/unknown", &p) != nil, true)
+}
+
+func TestIntoGzip(t *testing.T) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(`{"X":1,"Y":2}`))
+	w.Close()
+
+	var p point
+	assert.Equal(t, Event{Message: buf.Bytes()}.IntoAs("application/json+gzip", &p), nil)
+	assert.Equal(t, p, point{1, 2})
+
+	headers := map[string]string{HeaderContentType: "application/json+gzip"}
+	p = point{}
+	assert.Equal(t, Event{Message: buf.Bytes(), Headers: headers}.Into(&p), nil)
+	assert.Equal(t, p, point{1, 2})
+}
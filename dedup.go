@@ -0,0 +1,94 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// HeaderMessageID is the header of the ID of a message given by its publisher, e.g.
+// kept across the brokers a message is bridged through.
+const HeaderMessageID = "Pubsub-Message-ID"
+
+// IDFunc returns the ID of a message to deduplicate it, or "" to never drop it as a
+// duplicate.
+type IDFunc func(event Event) string
+
+// MessageID is the default IDFunc, returning the HeaderMessageID header of a
+// message, or its ID.
+func MessageID(event Event) string {
+	if id, ok := event.Headers[HeaderMessageID]; ok {
+		return id
+	}
+	return event.ID
+}
+
+// WithDedup sends a message with an ID, returned by fn or MessageID if nil, only
+// once to each channel within window, e.g. the duplicates produced by bridges and
+// replays. A dropped message isn't seen, its duplicate can still be sent.
+func WithDedup(window time.Duration, fn IDFunc) Option {
+	return func(p *Pubsub) {
+		if fn == nil {
+			fn = MessageID
+		}
+		p.dedup = &dedup{window: window, id: fn}
+	}
+}
+
+type dedup struct {
+	window time.Duration
+	id     IDFunc
+}
+
+// seenIDs are the IDs of the messages sent to a channel within the window.
+type seenIDs struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+	order []string // oldest first
+}
+
+// add adds id seen at now, and returns false if it was seen within window.
+func (s *seenIDs) add(id string, window time.Duration, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.order) > 0 {
+		oldest := s.order[0]
+		t, ok := s.times[oldest]
+		if ok && now.Sub(t) < window {
+			break
+		}
+		s.order = s.order[1:]
+		if ok {
+			delete(s.times, oldest)
+		}
+	}
+	if _, ok := s.times[id]; ok {
+		return false
+	}
+	if s.times == nil {
+		s.times = map[string]time.Time{}
+	}
+	s.times[id] = now
+	s.order = append(s.order, id)
+	return true
+}
+
+// remove forgets id, the message wasn't sent.
+func (s *seenIDs) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.times, id)
+}
+
+// duplicate returns the ID of event, and whether it was sent to sub within the
+// window. The ID must be removed if the message isn't sent.
+func (p *Pubsub) duplicate(sub *Subscription, event Event) (string, bool) {
+	if p.dedup == nil {
+		return "", false
+	}
+	id := p.dedup.id(event)
+	if id == "" {
+		return "", false
+	}
+	return id, !sub.seen.add(id, p.dedup.window, p.clock.Now())
+}
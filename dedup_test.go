@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestDedup(t *testing.T) {
+	ps := New(-1, WithDedup(time.Minute, nil))
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.PSubscribe("*", c)
+	other := make(chan Event, 10)
+	ps.Subscribe("a", other)
+
+	headers := map[string]string{HeaderMessageID: "1"}
+	result := ps.PublishMsg("a", "x", headers)
+	assert.Equal(t, result.Delivered, 2)
+	result = ps.PublishMsg("a", "x", headers)
+	assert.Equal(t, result.Delivered, 0)
+	assert.Equal(t, ps.PublishCtx(context.Background(), "a", "y"), nil)
+	ps.PublishMsg("a", "z", map[string]string{HeaderMessageID: "2"})
+	// the message without ID is sent by name and by pattern
+	assert.Equal(t, len(c), 4)
+	assert.Equal(t, len(other), 3)
+}
+
+func TestDedupWindow(t *testing.T) {
+	ps := New(-1, WithDedup(10*time.Millisecond, func(e Event) string { return e.Message.(string) }))
+	c := make(chan Event)
+	ps.Subscribe("a", c)
+
+	// a dropped message isn't seen
+	assert.Equal(t, ps.Publish("a", "x").Dropped, 1)
+	c2 := make(chan Event, 10)
+	ps.Unsubscribe("a", c)
+	ps.Subscribe("a", c2)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 1)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 0)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, ps.Publish("a", "x").Delivered, 1)
+	assert.Equal(t, ps.Publish("a", "").Delivered, 1)
+	assert.Equal(t, ps.Publish("a", "").Delivered, 1)
+}
+
+func TestDedupReplay(t *testing.T) {
+	ps := New(-1, WithDedup(time.Minute, nil), WithHistory(10), WithIDGenerator(UUIDv7()))
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+	ps.Replay("a", c, 2)
+	assert.Equal(t, len(c), 2)
+}
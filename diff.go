@@ -0,0 +1,94 @@
+package pubsub
+
+// Differ computes the difference between the old and the new retained message of
+// a topic, for channels subscribed with SubscribeDiff. It returns false if the new
+// message should be sent in full instead.
+type Differ interface {
+	Diff(name string, old, new interface{}) (interface{}, bool)
+}
+
+// DifferFunc is an adapter to use an ordinary function as a Differ.
+type DifferFunc func(name string, old, new interface{}) (interface{}, bool)
+
+// Diff calls fn(name, old, new).
+func (fn DifferFunc) Diff(name string, old, new interface{}) (interface{}, bool) {
+	return fn(name, old, new)
+}
+
+// WithDiffer computes the diffs of retained messages with d.
+func WithDiffer(d Differ) Option {
+	return func(p *Pubsub) {
+		p.differ = d
+	}
+}
+
+// Diff is the message sent to a channel subscribed with SubscribeDiff when the
+// retained message of the topic changes.
+type Diff struct {
+	Patch interface{} // computed by the Differ of the Pubsub
+}
+
+// SubscribeDiff subscribes channel c like SubscribeRetained, but PublishRetain sends
+// a Diff from the previous retained message to c instead of the full message. c
+// receives the full message again if it missed the previous one, or if the Differ
+// can't compute a diff. Without a Differ, SubscribeDiff is SubscribeRetained.
+func (p *Pubsub) SubscribeDiff(name string, c chan Event) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	p.diffs.Lock()
+	defer p.diffs.Unlock()
+	s, err := p.add(byName, name, c)
+	if err != nil {
+		p.locker.Unlock()
+		return err
+	}
+	s.diff = true
+	s.stale = true
+	event, ok := p.retained[name]
+	// sent without the lock, for the drop hooks
+	p.locker.Unlock()
+	if ok && !p.expired(event) {
+		s.stale = p.send(s, event, "") != delivered
+	}
+	return nil
+}
+
+// publishDiff publishes the new retained event, sending a diff from old to the diff
+// subscribers which received old. It must be called with p.diffs held.
+func (p *Pubsub) publishDiff(event Event, old Event, hasOld bool) PublishResult {
+	var (
+		result  PublishResult
+		diff    Event
+		hasDiff bool
+		diffed  bool
+	)
+	defer p.sequence(&event)()
+	p.record(event)
+	p.route(event.Name, func(s *subscriber, pattern string) {
+		if !s.diff || pattern != "" {
+			result.add(p.send(s, event, pattern))
+			return
+		}
+		if !diffed && hasOld {
+			diffed = true
+			if patch, ok := p.differ.Diff(event.Name, old.Message, event.Message); ok {
+				diff = event
+				diff.Message = Diff{Patch: patch}
+				hasDiff = true
+			}
+		}
+		e := event
+		if hasDiff && !s.stale {
+			e = diff
+		}
+		o := p.send(s, e, pattern)
+		s.stale = o != delivered
+		result.add(o)
+	})
+	p.sendQueues(event, &result)
+	return result
+}
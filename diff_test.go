@@ -0,0 +1,52 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSubscribeDiff(t *testing.T) {
+	ps := New(-1, WithDiffer(DifferFunc(func(name string, old, new interface{}) (interface{}, bool) {
+		if new.(int) < old.(int) {
+			return nil, false
+		}
+		return new.(int) - old.(int), true
+	})))
+	ps.PublishRetain("counter", 1)
+
+	full := make(chan Event, 1)
+	ps.Subscribe("counter", full)
+	diff := make(chan Event, 1)
+	assert.Equal(t, ps.SubscribeDiff("counter", diff), nil)
+	assert.Equal(t, (<-diff).Message, 1)
+
+	assert.Equal(t, ps.PublishRetain("counter", 3), PublishResult{Delivered: 2})
+	assert.Equal(t, (<-full).Message, 3)
+	assert.Equal(t, (<-diff).Message, Diff{Patch: 2})
+
+	// no diff, full message
+	ps.PublishRetain("counter", 0)
+	<-full
+	assert.Equal(t, (<-diff).Message, 0)
+
+	// missed a message, full message
+	ps.PublishRetain("counter", 4)
+	assert.Equal(t, ps.PublishRetain("counter", 5), PublishResult{Dropped: 2})
+	<-full
+	<-diff
+	ps.PublishRetain("counter", 6)
+	assert.Equal(t, (<-diff).Message, 6)
+	ps.PublishRetain("counter", 7)
+	assert.Equal(t, (<-diff).Message, Diff{Patch: 1})
+}
+
+func TestSubscribeDiffWithoutDiffer(t *testing.T) {
+	ps := New(-1)
+	ps.PublishRetain("state", "a")
+	c := make(chan Event, 1)
+	ps.SubscribeDiff("state", c)
+	assert.Equal(t, (<-c).Message, "a")
+	ps.PublishRetain("state", "b")
+	assert.Equal(t, (<-c).Message, "b")
+}
@@ -0,0 +1,150 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultDispatchQueue is the size of the queue of each dispatch worker without
+// WithDispatchQueue.
+const defaultDispatchQueue = 1024
+
+// WithDispatchWorkers delivers the published messages from n worker goroutines, so
+// Publish only queues the message and returns an empty PublishResult, whatever the
+// number of subscribers or the cost of matching. The messages of a topic are
+// delivered by the same worker, in order. Publish blocks while the queue of the
+// worker is full. PublishCtx still delivers from the publisher. Use Flush to wait
+// for the queued messages to be delivered, Close delivers the queued messages and
+// stops the workers, Publish delivers from the publisher after.
+func WithDispatchWorkers(n int) Option {
+	return func(p *Pubsub) {
+		if n <= 0 {
+			return
+		}
+		size := defaultDispatchQueue
+		if p.dispatch != nil {
+			size = p.dispatch.size
+		}
+		p.dispatch = &dispatcher{workers: make([]chan dispatched, n), size: size}
+	}
+}
+
+// WithDispatchQueue sets the size of the queue of each worker of
+// WithDispatchWorkers, 1024 by default.
+func WithDispatchQueue(size int) Option {
+	return func(p *Pubsub) {
+		if p.dispatch == nil {
+			p.dispatch = &dispatcher{}
+		}
+		p.dispatch.size = size
+	}
+}
+
+type dispatched struct {
+	event Event
+	route router
+}
+
+type dispatcher struct {
+	workers []chan dispatched
+	size    int
+
+	sending sync.RWMutex // held while queuing, excludes stopping
+	stopped bool         // guarded by sending
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	pending int
+	idle    []chan struct{} // closed when pending is 0
+}
+
+// startDispatch starts the workers of WithDispatchWorkers.
+func (p *Pubsub) startDispatch() {
+	d := p.dispatch
+	if d == nil {
+		return
+	}
+	if len(d.workers) == 0 {
+		// WithDispatchQueue without WithDispatchWorkers
+		p.dispatch = nil
+		return
+	}
+	for i := range d.workers {
+		d.workers[i] = make(chan dispatched, d.size)
+		d.wg.Add(1)
+		go p.dispatchWorker(d.workers[i])
+	}
+}
+
+func (p *Pubsub) dispatchWorker(queue chan dispatched) {
+	d := p.dispatch
+	defer d.wg.Done()
+	for m := range queue {
+		func() {
+			defer p.isolate(m.event)
+			p.publishNow(m.event, m.route)
+		}()
+		d.mu.Lock()
+		d.pending--
+		if d.pending == 0 {
+			for _, c := range d.idle {
+				close(c)
+			}
+			d.idle = nil
+		}
+		d.mu.Unlock()
+	}
+}
+
+// enqueue queues event for the worker of its topic. It returns false if the workers
+// are stopped.
+func (d *dispatcher) enqueue(event Event, route router) bool {
+	d.sending.RLock()
+	defer d.sending.RUnlock()
+	if d.stopped {
+		return false
+	}
+	d.mu.Lock()
+	d.pending++
+	d.mu.Unlock()
+
+	d.workers[fnv32(event.Name)%uint32(len(d.workers))] <- dispatched{event, route}
+	return true
+}
+
+// stop delivers the queued messages and stops the workers.
+func (d *dispatcher) stop() {
+	d.sending.Lock()
+	if !d.stopped {
+		d.stopped = true
+		for _, queue := range d.workers {
+			close(queue)
+		}
+	}
+	d.sending.Unlock()
+	d.wg.Wait()
+}
+
+// Flush waits until the messages queued WithDispatchWorkers are delivered, or ctx is
+// done. It returns immediately without dispatch workers.
+func (p *Pubsub) Flush(ctx context.Context) error {
+	d := p.dispatch
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if d.pending == 0 {
+		d.mu.Unlock()
+		return nil
+	}
+	idle := make(chan struct{})
+	d.idle = append(d.idle, idle)
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,37 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestDispatchWorkers(t *testing.T) {
+	ps := New(-1, WithDispatchWorkers(4), WithDispatchQueue(8), WithSequence())
+	c := make(chan Event, 1000)
+	ps.PSubscribe("*", c)
+
+	for i := 0; i < 100; i++ {
+		r := ps.Publish("a", i)
+		assert.Equal(t, r, PublishResult{})
+		ps.Publish("b", i)
+	}
+	assert.Equal(t, ps.Flush(context.Background()), nil)
+	assert.Equal(t, len(c), 200)
+
+	// in order per topic
+	next := map[string]int{}
+	for len(c) > 0 {
+		e := <-c
+		assert.Equal(t, e.Message, next[e.Name])
+		next[e.Name]++
+	}
+
+	// Close delivers the queued messages, then Publish delivers directly
+	ps.Publish("a", 100)
+	ps.Close()
+	assert.Equal(t, len(c), 1)
+	assert.Equal(t, ps.Publish("a", 101).Delivered, 1)
+	assert.Equal(t, ps.Flush(context.Background()), nil)
+}
@@ -0,0 +1,117 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// DroppedMessage describes a message which Publish failed to deliver because the
+// subscribed channel wasn't ready.
+type DroppedMessage struct {
+	Name    string
+	Pattern string // the matched pattern, empty if subscribed by name
+	Message interface{}
+	Channel chan Event
+	Time    time.Time
+}
+
+// WithDeadLetter sends every dropped message to c. Sending to c never blocks, if c
+// isn't ready the dropped message is discarded.
+func WithDeadLetter(c chan DroppedMessage) Option {
+	return func(p *Pubsub) {
+		p.deadLetter = c
+	}
+}
+
+// OnDrop calls fn with every dropped message. fn is called synchronously by Publish,
+// so it shouldn't block or subscribe/unsubscribe on the same Pubsub.
+func OnDrop(fn func(DroppedMessage)) Option {
+	return func(p *Pubsub) {
+		p.onDrop = fn
+	}
+}
+
+// OnTopicDrop calls fn with the dropped messages of the topics matching pattern,
+// matched by the Matcher of the Pubsub. The handlers of all matching patterns are
+// called, after the handler of OnDrop. Like OnDrop, fn is called synchronously by
+// Publish.
+func OnTopicDrop(pattern string, fn func(DroppedMessage)) Option {
+	return func(p *Pubsub) {
+		if p.topicDrops == nil {
+			p.topicDrops = &topicDrops{}
+		}
+		p.topicDrops.rules = append(p.topicDrops.rules, topicDropRule{pattern, fn})
+	}
+}
+
+type topicDropRule struct {
+	pattern string
+	fn      func(DroppedMessage)
+}
+
+type topicDrops struct {
+	rules []topicDropRule
+	cache sync.Map // name -> []func(DroppedMessage)
+}
+
+// dropHandlers returns the OnTopicDrop handlers of name.
+func (p *Pubsub) dropHandlers(name string) []func(DroppedMessage) {
+	if p.topicDrops == nil {
+		return nil
+	}
+	if v, ok := p.topicDrops.cache.Load(name); ok {
+		return v.([]func(DroppedMessage))
+	}
+	var fns []func(DroppedMessage)
+	for _, rule := range p.topicDrops.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			fns = append(fns, rule.fn)
+		}
+	}
+	p.topicDrops.cache.Store(name, fns)
+	return fns
+}
+
+// drop reports event dropped for c, once its topic is released WithSequence.
+func (p *Pubsub) drop(c chan Event, event Event, pattern string) {
+	if p.deferDrop(c, event, pattern) {
+		return
+	}
+	p.reportDrop(c, event, pattern)
+}
+
+func (p *Pubsub) reportDrop(c chan Event, event Event, pattern string) {
+	p.dropped.Add(1)
+	p.notifyKeyspace(KeyspaceDrop, event.Name, c, event.ID)
+	p.logDrop(c, event, pattern)
+	if p.stats != nil {
+		p.stats.topic(event.Name).dropped.Add(1)
+	}
+	now := p.clock.Now()
+	if p.window != nil {
+		p.window.dropped(event.Name, now)
+	}
+	handlers := p.dropHandlers(event.Name)
+	if p.deadLetter == nil && p.onDrop == nil && len(handlers) == 0 {
+		return
+	}
+	d := DroppedMessage{
+		Name:    event.Name,
+		Pattern: pattern,
+		Message: event.Message,
+		Channel: c,
+		Time:    now,
+	}
+	if p.onDrop != nil {
+		p.onDrop(d)
+	}
+	for _, fn := range handlers {
+		fn(d)
+	}
+	if p.deadLetter != nil {
+		select {
+		case p.deadLetter <- d:
+		default:
+		}
+	}
+}
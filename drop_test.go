@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestDeadLetter(t *testing.T) {
+	dead := make(chan DroppedMessage, 2)
+	ps := New(-1, WithDeadLetter(dead))
+
+	c := make(chan Event)
+	ps.Subscribe("topic", c)
+	ps.PSubscribe("top*", c)
+	ps.Publish("topic", "msg")
+
+	d := <-dead
+	assert.Equal(t, d.Name, "topic")
+	assert.Equal(t, d.Message, "msg")
+	assert.Equal(t, d.Channel, c)
+	assert.Equal(t, d.Time.IsZero(), false)
+	d = <-dead
+	assert.Equal(t, d.Pattern, "top*")
+}
+
+func TestOnDrop(t *testing.T) {
+	var drops []DroppedMessage
+	ps := New(-1, OnDrop(func(d DroppedMessage) {
+		drops = append(drops, d)
+	}))
+
+	full := make(chan Event)
+	ready := make(chan Event, 1)
+	ps.Subscribe("topic", full)
+	ps.Subscribe("topic", ready)
+	ps.Publish("topic", "msg")
+
+	assert.Equal(t, len(drops), 1)
+	assert.Equal(t, drops[0].Channel, full)
+	assert.Equal(t, (<-ready).Message, "msg")
+}
+
+func TestOnTopicDrop(t *testing.T) {
+	var orders, all []string
+	ps := New(-1,
+		OnTopicDrop("orders.*", func(d DroppedMessage) {
+			orders = append(orders, d.Name)
+		}),
+		OnTopicDrop("*", func(d DroppedMessage) {
+			all = append(all, d.Name)
+		}),
+	)
+
+	full := make(chan Event)
+	ps.Subscribe("orders.1", full)
+	ps.Subscribe("clicks", full)
+	ps.Publish("orders.1", 1)
+	ps.Publish("clicks", 2)
+
+	assert.Equal(t, orders, []string{"orders.1"})
+	assert.Equal(t, all, []string{"orders.1", "clicks"})
+}
@@ -0,0 +1,371 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Error of subscribing durably to a topic which isn't durable.
+var ErrNotDurable = errors.New("topic isn't durable")
+
+// Store is the log of the messages of durable topics, and of the offsets the
+// consumers acknowledged. A Store must be safe for concurrent use.
+type Store interface {
+	// Append appends event to the log of its topic, and returns its offset. The
+	// offsets of a topic start at 1.
+	Append(event Event) (uint64, error)
+	// Read calls fn with the events of the log of name from offset on, with their
+	// Offset set, until fn returns false.
+	Read(name string, offset uint64, fn func(event Event) bool) error
+	// Commit keeps offset as the offset acknowledged by consumer of name, unless
+	// it already keeps a higher one.
+	Commit(name, consumer string, offset uint64) error
+	// Committed returns the offset acknowledged by consumer of name, or 0.
+	Committed(name, consumer string) (uint64, error)
+	// Sync flushes the store to its storage.
+	Sync() error
+}
+
+// OffsetLister is implemented by a Store which can list the offsets acknowledged by
+// the consumers of all topics, to take them in a Snapshot.
+type OffsetLister interface {
+	// Offsets returns the acknowledged offsets, by name and consumer.
+	Offsets() (map[string]map[string]uint64, error)
+}
+
+// WithDurable appends every message published to the topics matching pattern,
+// matched by the Matcher of the Pubsub, to store, which is synced when the Pubsub
+// is shut down. Channels subscribed with SubscribeDurable receive the messages
+// from the log. The first matching pattern wins.
+func WithDurable(pattern string, store Store) Option {
+	return func(p *Pubsub) {
+		if p.durable == nil {
+			p.durable = &durables{onError: func(string, error) {}}
+		}
+		p.durable.rules = append(p.durable.rules, durableRule{pattern, store})
+		p.OnShutdownPhase(ShutdownStores, func(ctx context.Context) error {
+			return store.Sync()
+		})
+	}
+}
+
+// OnStoreError calls fn with the errors of the Store of a durable topic, which are
+// ignored by default. fn is called synchronously by Publish when appending fails.
+func OnStoreError(fn func(name string, err error)) Option {
+	return func(p *Pubsub) {
+		if p.durable == nil {
+			p.durable = &durables{}
+		}
+		p.durable.onError = fn
+	}
+}
+
+type durableRule struct {
+	pattern string
+	store   Store
+}
+
+type durables struct {
+	rules   []durableRule
+	cache   sync.Map // name -> *durableTopic, nil if not durable
+	onError func(name string, err error)
+}
+
+// durableTopic is the log of a durable topic and its consumers.
+type durableTopic struct {
+	store Store
+
+	mu        sync.Mutex
+	cursors   map[*Durable]struct{}
+	forgotten bool // dropped from the cache by CompactTopics
+}
+
+// durableTopic returns the log of name, or nil if name isn't durable.
+func (p *Pubsub) durableTopic(name string) *durableTopic {
+	if p.durable == nil {
+		return nil
+	}
+	if v, ok := p.durable.cache.Load(name); ok {
+		return v.(*durableTopic)
+	}
+	var topic *durableTopic
+	for _, rule := range p.durable.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			topic = &durableTopic{store: rule.store, cursors: map[*Durable]struct{}{}}
+			break
+		}
+	}
+	v, _ := p.durable.cache.LoadOrStore(name, topic)
+	return v.(*durableTopic)
+}
+
+// consumed returns whether name is a durable topic with consumers.
+func (p *Pubsub) consumed(name string) bool {
+	v, ok := p.durable.cache.Load(name)
+	if !ok {
+		return false
+	}
+	topic := v.(*durableTopic)
+	if topic == nil {
+		return false
+	}
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	return len(topic.cursors) > 0
+}
+
+// forgetDurable drops the cached log of name, unless it has consumers. Its
+// messages stay in its Store.
+func (p *Pubsub) forgetDurable(name string) {
+	v, ok := p.durable.cache.Load(name)
+	if !ok {
+		return
+	}
+	if topic := v.(*durableTopic); topic != nil {
+		topic.mu.Lock()
+		defer topic.mu.Unlock()
+		if len(topic.cursors) > 0 {
+			return
+		}
+		topic.forgotten = true
+	}
+	p.durable.cache.Delete(name)
+}
+
+// appendDurable appends event to the log of its topic if it's durable, and wakes
+// its consumers up.
+func (p *Pubsub) appendDurable(event Event) {
+	topic := p.durableTopic(event.Name)
+	if topic == nil {
+		return
+	}
+	if _, err := topic.store.Append(event); err != nil {
+		p.durable.onError(event.Name, err)
+		return
+	}
+	topic.mu.Lock()
+	defer topic.mu.Unlock()
+	for d := range topic.cursors {
+		select {
+		case d.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Durable is a durable subscription of a channel to a topic, created by
+// SubscribeDurable.
+type Durable struct {
+	p        *Pubsub
+	topic    *durableTopic
+	name     string
+	consumer string
+	c        chan Event
+	notify   chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// SubscribeDurable sends the messages of the durable topic name to channel c,
+// from the message after the last one consumerID acknowledged with Ack, e.g.
+// before a restart. The messages are read from the Store, so c receives every
+// message in order and never misses one, a slow c delays only itself. It returns
+// ErrNotDurable if name isn't durable.
+func (p *Pubsub) SubscribeDurable(name, consumerID string, c chan Event) (*Durable, error) {
+	name = p.normalize(name)
+	topic := p.durableTopic(name)
+	if topic == nil {
+		return nil, ErrNotDurable
+	}
+	offset, err := topic.store.Committed(name, consumerID)
+	if err != nil {
+		return nil, err
+	}
+	d := &Durable{
+		p:        p,
+		name:     name,
+		consumer: consumerID,
+		c:        c,
+		notify:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	topic.mu.Lock()
+	for topic.forgotten {
+		// compacted meanwhile, the topic is looked up again
+		topic.mu.Unlock()
+		topic = p.durableTopic(name)
+		topic.mu.Lock()
+	}
+	d.topic = topic
+	topic.cursors[d] = struct{}{}
+	topic.mu.Unlock()
+	go d.run(offset + 1)
+	return d, nil
+}
+
+// Reading the log of a Durable again after the Store failed waits for
+// durableBackoff, doubled by each failure without progress. The message which
+// can't be read after durableRetries is skipped.
+const (
+	durableBackoff = 10 * time.Millisecond
+	durableRetries = 5
+)
+
+// run sends the messages of the log from offset next on until the subscription is
+// closed.
+func (d *Durable) run(next uint64) {
+	defer close(d.done)
+	failures := 0
+	for {
+		from := next
+		stopped := false
+		err := d.topic.store.Read(d.name, next, func(event Event) bool {
+			if d.p.expired(event) {
+				next = event.Offset + 1
+				return true
+			}
+			select {
+			case d.c <- event:
+				next = event.Offset + 1
+				return true
+			case <-d.stop:
+				stopped = true
+				return false
+			}
+		})
+		if stopped {
+			return
+		}
+		if err == nil {
+			failures = 0
+			select {
+			case <-d.notify:
+			case <-d.stop:
+				return
+			}
+			continue
+		}
+
+		d.p.durable.onError(d.name, err)
+		if next != from {
+			failures = 0
+		}
+		if failures == durableRetries {
+			// the message at next can't be read, it's skipped
+			next++
+			failures = 0
+			continue
+		}
+		timer := d.p.clock.NewTimer(durableBackoff << failures)
+		failures++
+		select {
+		case <-timer.C():
+		case <-d.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// Ack acknowledges event and the messages before it, the subscriptions of the
+// same consumer start after it.
+func (d *Durable) Ack(event Event) error {
+	return d.topic.store.Commit(d.name, d.consumer, event.Offset)
+}
+
+// Close stops sending messages to the channel. It waits until a message being
+// sent is sent or given up.
+func (d *Durable) Close() {
+	d.once.Do(func() {
+		close(d.stop)
+		d.topic.mu.Lock()
+		delete(d.topic.cursors, d)
+		d.topic.mu.Unlock()
+	})
+	<-d.done
+}
+
+// MemoryStore is a Store keeping the logs in memory, e.g. for tests.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	logs    map[string][]Event
+	offsets map[string]map[string]uint64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		logs:    map[string][]Event{},
+		offsets: map[string]map[string]uint64{},
+	}
+}
+
+func (s *MemoryStore) Append(event Event) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	event.Offset = uint64(len(s.logs[event.Name])) + 1
+	s.logs[event.Name] = append(s.logs[event.Name], event)
+	return event.Offset, nil
+}
+
+func (s *MemoryStore) Read(name string, offset uint64, fn func(event Event) bool) error {
+	s.mu.RLock()
+	log := s.logs[name]
+	s.mu.RUnlock()
+	if offset == 0 {
+		offset = 1
+	}
+	for i := offset - 1; i < uint64(len(log)); i++ {
+		if !fn(log[i]) {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Commit(name, consumer string, offset uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets, ok := s.offsets[name]
+	if !ok {
+		offsets = map[string]uint64{}
+		s.offsets[name] = offsets
+	}
+	if offset > offsets[consumer] {
+		offsets[consumer] = offset
+	}
+	return nil
+}
+
+func (s *MemoryStore) Committed(name, consumer string) (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.offsets[name][consumer], nil
+}
+
+// Offsets returns the committed offsets of all topics.
+func (s *MemoryStore) Offsets() (map[string]map[string]uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := make(map[string]map[string]uint64, len(s.offsets))
+	for name, offsets := range s.offsets {
+		ret[name] = copyOffsets(offsets)
+	}
+	return ret, nil
+}
+
+func copyOffsets(offsets map[string]uint64) map[string]uint64 {
+	ret := make(map[string]uint64, len(offsets))
+	for consumer, offset := range offsets {
+		ret[consumer] = offset
+	}
+	return ret
+}
+
+func (s *MemoryStore) Sync() error {
+	return nil
+}
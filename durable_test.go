@@ -0,0 +1,110 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func receiveN(t *testing.T, c chan Event, n int) []Event {
+	t.Helper()
+	var ret []Event
+	for len(ret) < n {
+		select {
+		case e := <-c:
+			ret = append(ret, e)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d messages, want %d", len(ret), n)
+		}
+	}
+	return ret
+}
+
+func TestDurable(t *testing.T) {
+	store := NewMemoryStore()
+	ps := New(-1, WithDurable("orders.*", store))
+
+	c := make(chan Event)
+	_, err := ps.SubscribeDurable("other", "a", c)
+	assert.Equal(t, err, ErrNotDurable)
+
+	ps.Publish("orders.new", 1)
+	ps.Publish("orders.new", 2)
+
+	d, err := ps.SubscribeDurable("orders.new", "a", c)
+	assert.Equal(t, err, nil)
+	events := receiveN(t, c, 2)
+	assert.Equal(t, events[0].Message, 1)
+	assert.Equal(t, events[1].Offset, uint64(2))
+
+	ps.Publish("orders.new", 3)
+	events = receiveN(t, c, 1)
+	assert.Equal(t, events[0].Message, 3)
+
+	assert.Equal(t, d.Ack(events[0]), nil)
+	d.Close()
+
+	// resumes after the acknowledged message
+	ps.Publish("orders.new", 4)
+	d, err = ps.SubscribeDurable("orders.new", "a", c)
+	assert.Equal(t, err, nil)
+	defer d.Close()
+	events = receiveN(t, c, 1)
+	assert.Equal(t, events[0].Message, 4)
+
+	// another consumer starts from the beginning
+	c2 := make(chan Event, 10)
+	d2, _ := ps.SubscribeDurable("orders.new", "b", c2)
+	defer d2.Close()
+	assert.Equal(t, len(receiveN(t, c2, 4)), 4)
+}
+
+// failingStore fails to read the messages of its offsets.
+type failingStore struct {
+	*MemoryStore
+	mu    sync.Mutex
+	fails map[uint64]int // offset -> failures left, -1 for ever
+}
+
+func (s *failingStore) Read(name string, offset uint64, fn func(event Event) bool) error {
+	var err error
+	s.MemoryStore.Read(name, offset, func(event Event) bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if n := s.fails[event.Offset]; n != 0 {
+			s.fails[event.Offset] = n - 1
+			err = errors.New("corrupt record")
+			return false
+		}
+		return fn(event)
+	})
+	return err
+}
+
+func TestDurableReadError(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore(), fails: map[uint64]int{2: 1, 4: -1}}
+	errs := make(chan error, 16)
+	ps := New(-1, WithDurable("orders", store), OnStoreError(func(name string, err error) {
+		errs <- err
+	}))
+	for i := 1; i <= 5; i++ {
+		ps.Publish("orders", i)
+	}
+
+	c := make(chan Event, 10)
+	d, err := ps.SubscribeDurable("orders", "a", c)
+	assert.Equal(t, err, nil)
+	defer d.Close()
+
+	// the failed read is retried without another message, and the message which
+	// can't be read is skipped
+	var got []interface{}
+	for _, event := range receiveN(t, c, 4) {
+		got = append(got, event.Message)
+	}
+	assert.Equal(t, got, []interface{}{1, 2, 3, 5})
+	assert.Equal(t, len(errs), 1+durableRetries+1)
+}
@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownKey is returned by a KeyProvider for an ID it doesn't have.
+var ErrUnknownKey = errors.New("pubsub: unknown encryption key")
+
+// KeyProvider provides the keys encrypting the persisted data, e.g. from a KMS.
+// Data is encrypted with AES-GCM and the current key, and records the ID of its
+// key, so keys can be rotated while the data encrypted with older keys stays
+// readable.
+type KeyProvider interface {
+	// CurrentKey returns the ID and the key encrypting new data, of 16, 24 or 32
+	// bytes for AES-128, AES-192 or AES-256.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key of id, or ErrUnknownKey.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKey returns a KeyProvider of a single key with id.
+func StaticKey(id string, key []byte) KeyProvider {
+	return staticKey{id, key}
+}
+
+type staticKey struct {
+	id  string
+	key []byte
+}
+
+func (k staticKey) CurrentKey() (string, []byte, error) {
+	return k.id, k.key, nil
+}
+
+func (k staticKey) Key(id string) ([]byte, error) {
+	if id != k.id {
+		return nil, ErrUnknownKey
+	}
+	return k.key, nil
+}
+
+// WithEncryption encrypts the retained messages of the BrokerStates taken by
+// Snapshot with keys, and decrypts them in Restore. Names and headers aren't
+// encrypted. See OpenEncryptedFileStore to encrypt durable topics.
+func WithEncryption(keys KeyProvider) Option {
+	return func(p *Pubsub) {
+		p.keys = keys
+	}
+}
+
+// seal encrypts data with the current key of keys, authenticating aad, and returns
+// the ID of the key and the nonce followed by the ciphertext.
+func seal(keys KeyProvider, data, aad []byte) (string, []byte, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("pubsub: key %q: %w", id, err)
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return id, aead.Seal(nonce, nonce, data, aad), nil
+}
+
+// unseal decrypts sealed by seal with the key id of keys.
+func unseal(keys KeyProvider, id string, sealed, aad []byte) ([]byte, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("pubsub: key %q: %w", id, ErrUnknownKey)
+	}
+	key, err := keys.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: key %q: %w", id, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: key %q: %w", id, err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("pubsub: key %q: sealed data too short", id)
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	data, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: key %q: %w", id, err)
+	}
+	return data, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+// rotatingKeys is a KeyProvider whose current key can be changed.
+type rotatingKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+func (k *rotatingKeys) CurrentKey() (string, []byte, error) {
+	return k.current, k.keys[k.current], nil
+}
+
+func (k *rotatingKeys) Key(id string) ([]byte, error) {
+	if key, ok := k.keys[id]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func TestEncryptedFileStore(t *testing.T) {
+	dir := t.TempDir()
+	keys := &rotatingKeys{current: "k1", keys: map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 16),
+	}}
+	store, err := OpenEncryptedFileStore(dir, nil, keys)
+	assert.Equal(t, err, nil)
+	store.Append(Event{Name: "a/b", Message: "secret-1"})
+	keys.current = "k2"
+	store.Append(Event{Name: "a/b", Message: "secret-2"})
+	assert.Equal(t, store.Close(), nil)
+
+	data, err := os.ReadFile(filepath.Join(dir, "a%2Fb.log"))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, bytes.Contains(data, []byte("secret")), false)
+
+	store, err = OpenEncryptedFileStore(dir, nil, keys)
+	assert.Equal(t, err, nil)
+	defer store.Close()
+	var messages []string
+	assert.Equal(t, store.Read("a/b", 1, func(event Event) bool {
+		var msg string
+		assert.Equal(t, event.Into(&msg), nil)
+		messages = append(messages, msg)
+		return true
+	}), nil)
+	assert.Equal(t, messages, []string{"secret-1", "secret-2"})
+
+	// without the key
+	plain, err := OpenFileStore(dir, nil)
+	assert.Equal(t, err, nil)
+	defer plain.Close()
+	err = plain.Read("a/b", 1, func(Event) bool { return true })
+	assert.Equal(t, errors.Is(err, ErrUnknownKey), true)
+}
+
+func TestSnapshotEncrypted(t *testing.T) {
+	keys := StaticKey("k1", bytes.Repeat([]byte{1}, 32))
+	ps := New(-1, WithEncryption(keys))
+	ps.PublishRetain("config", "secret")
+
+	state, err := ps.Snapshot()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, state.Retained[0].Key, "k1")
+	assert.Equal(t, bytes.Contains(state.Retained[0].Data, []byte("secret")), false)
+
+	restored := New(-1, WithEncryption(keys))
+	assert.Equal(t, restored.Restore(state), nil)
+	msg, _ := restored.Retained("config")
+	assert.Equal(t, string(msg.([]byte)), `"secret"`)
+
+	// a state moved to another name doesn't decrypt
+	state.Retained[0].Name = "other"
+	assert.Equal(t, New(-1, WithEncryption(keys)).Restore(state) != nil, true)
+	assert.Equal(t, errors.Is(New(-1).Restore(state), ErrUnknownKey), true)
+}
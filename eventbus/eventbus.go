@@ -0,0 +1,168 @@
+// Package eventbus adapts a Pubsub to the Emit/On API of the popular event bus
+// libraries, to migrate code written against them.
+//
+//	bus := eventbus.New(pubsub.New(-1))
+//	bus.On("user:created", func(name string, admin bool) { ... })
+//	bus.Emit("user:created", "ann", false)
+//
+// Unlike with those libraries, handlers are called asynchronously, one at a time
+// per handler from a goroutine of its own.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+var (
+	// Error of registering a handler which isn't a func.
+	ErrNotFunc = errors.New("handler must be a func")
+	// Error of removing a handler which isn't registered.
+	ErrNoHandler = errors.New("handler isn't registered")
+)
+
+// Bus is an event bus backed by a Pubsub.
+type Bus struct {
+	ps       *pubsub.Pubsub
+	mu       sync.Mutex
+	handlers map[string][]handler
+}
+
+type handler struct {
+	fn  uintptr // the code pointer of the func, to find it again in Off
+	sub *pubsub.Subscription
+}
+
+// New creates a Bus emitting events to ps.
+func New(ps *pubsub.Pubsub) *Bus {
+	return &Bus{
+		ps:       ps,
+		handlers: map[string][]handler{},
+	}
+}
+
+// On calls fn with the arguments of every event emitted after. fn can be any func,
+// nil and missing arguments are passed as zero values and extra ones are left out.
+// Like with the event bus libraries, calling fn panics if the arguments have the
+// wrong types. Messages published to event with the Pubsub are passed as the only
+// argument.
+func (b *Bus) On(event string, fn interface{}) error {
+	return b.on(event, fn, b.ps.SubscribeFunc)
+}
+
+// Once is like On, but fn is only called with the next event.
+func (b *Bus) Once(event string, fn interface{}) error {
+	return b.on(event, fn, b.ps.SubscribeOnceFunc)
+}
+
+func (b *Bus) on(event string, fn interface{}, subscribe func(ctx context.Context, name string, fn pubsub.Handler) (*pubsub.Subscription, error)) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return ErrNotFunc
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub, err := subscribe(context.Background(), event, func(ctx context.Context, e pubsub.Event) {
+		call(v, e.Message)
+	})
+	if err != nil {
+		return err
+	}
+	b.handlers[event] = append(b.handlers[event], handler{v.Pointer(), sub})
+	return nil
+}
+
+// Off removes fn registered for event. Events already emitted may still call fn.
+func (b *Bus) Off(event string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return ErrNotFunc
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	handlers := b.live(event)
+	for i, h := range handlers {
+		if h.fn == v.Pointer() {
+			h.sub.Unsubscribe()
+			b.set(event, append(handlers[:i:i], handlers[i+1:]...))
+			return nil
+		}
+	}
+	return ErrNoHandler
+}
+
+// Emit calls the handlers of event with args. It blocks until every handler
+// received the event, but doesn't wait for the handlers to return.
+func (b *Bus) Emit(event string, args ...interface{}) {
+	b.ps.PublishCtx(context.Background(), event, args)
+}
+
+// HasHandler returns whether event has handlers.
+func (b *Bus) HasHandler(event string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.live(event)) > 0
+}
+
+// live returns the handlers of event, without the ones called Once.
+func (b *Bus) live(event string) []handler {
+	handlers := b.handlers[event]
+	var ret []handler
+	for _, h := range handlers {
+		if h.sub.State() != pubsub.Closed {
+			ret = append(ret, h)
+		}
+	}
+	if len(ret) != len(handlers) {
+		b.set(event, ret)
+	}
+	return ret
+}
+
+func (b *Bus) set(event string, handlers []handler) {
+	if len(handlers) == 0 {
+		delete(b.handlers, event)
+	} else {
+		b.handlers[event] = handlers
+	}
+}
+
+// call calls fn with the arguments of message.
+func call(fn reflect.Value, message interface{}) {
+	args, ok := message.([]interface{})
+	if !ok {
+		args = []interface{}{message}
+	}
+	t := fn.Type()
+	n := t.NumIn()
+	if !t.IsVariadic() && len(args) > n {
+		args = args[:n]
+	}
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		typ := paramType(t, i)
+		if arg == nil {
+			in = append(in, reflect.Zero(typ))
+			continue
+		}
+		in = append(in, reflect.ValueOf(arg))
+	}
+	for i := len(in); i < n && !(t.IsVariadic() && i == n-1); i++ {
+		in = append(in, reflect.Zero(t.In(i)))
+	}
+	fn.Call(in)
+}
+
+// paramType returns the type of the i-th argument of a call of a func of type t.
+func paramType(t reflect.Type, i int) reflect.Type {
+	if t.IsVariadic() && i >= t.NumIn()-1 {
+		return t.In(t.NumIn() - 1).Elem()
+	}
+	return t.In(i)
+}
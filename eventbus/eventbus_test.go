@@ -0,0 +1,64 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestBus(t *testing.T) {
+	ps := pubsub.New(-1)
+	bus := New(ps)
+	type call struct {
+		name  string
+		admin bool
+	}
+	calls := make(chan call, 10)
+	handler := func(name string, admin bool) {
+		calls <- call{name, admin}
+	}
+	assert.Equal(t, bus.On("created", handler), nil)
+	assert.Equal(t, bus.HasHandler("created"), true)
+
+	bus.Emit("created", "ann", true)
+	assert.Equal(t, <-calls, call{"ann", true})
+	bus.Emit("created", "bob")
+	assert.Equal(t, <-calls, call{"bob", false})
+	bus.Emit("created", nil, true, "extra")
+	assert.Equal(t, <-calls, call{"", true})
+	ps.Publish("created", "carl")
+	assert.Equal(t, <-calls, call{"carl", false})
+
+	assert.Equal(t, bus.Off("created", handler), nil)
+	assert.Equal(t, bus.HasHandler("created"), false)
+	assert.Equal(t, bus.Off("created", handler), ErrNoHandler)
+	assert.Equal(t, bus.On("created", 1), ErrNotFunc)
+}
+
+func TestBusVariadic(t *testing.T) {
+	bus := New(pubsub.New(-1))
+	sums := make(chan int, 1)
+	bus.On("sum", func(prefix string, n ...int) {
+		sum := 0
+		for _, n := range n {
+			sum += n
+		}
+		sums <- sum
+	})
+	bus.Emit("sum", "total", 1, 2, 3)
+	assert.Equal(t, <-sums, 6)
+}
+
+func TestBusOnce(t *testing.T) {
+	bus := New(pubsub.New(-1))
+	calls := make(chan int, 10)
+	bus.Once("tick", func(n int) { calls <- n })
+	bus.Emit("tick", 1)
+	assert.Equal(t, <-calls, 1)
+	bus.Emit("tick", 2)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, len(calls), 0)
+	assert.Equal(t, bus.HasHandler("tick"), false)
+}
@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Feature flags toggling experimental engine behaviors at runtime.
+const (
+	// FlagParallelFanout sends a message to many subscribers from multiple
+	// goroutines, so OnDrop may be called concurrently. Off by default.
+	FlagParallelFanout = "parallel_fanout"
+	// FlagPatternIndex only matches the patterns whose literal prefix matches the
+	// topic. On by default.
+	FlagPatternIndex = "pattern_index"
+)
+
+// Error of setting a flag which doesn't exist.
+var ErrUnknownFlag = errors.New("unknown flag")
+
+// parallelFanoutMin is the minimum number of subscribers to fan out in parallel.
+const parallelFanoutMin = 64
+
+type flags struct {
+	parallelFanout atomic.Bool
+	patternIndex   atomic.Bool
+}
+
+func newFlags() *flags {
+	f := &flags{}
+	f.patternIndex.Store(true)
+	return f
+}
+
+func (f *flags) lookup(name string) *atomic.Bool {
+	switch name {
+	case FlagParallelFanout:
+		return &f.parallelFanout
+	case FlagPatternIndex:
+		return &f.patternIndex
+	}
+	return nil
+}
+
+// WithFlag sets the feature flag name when the Pubsub is created. Unknown flags are
+// ignored.
+func WithFlag(name string, on bool) Option {
+	return func(p *Pubsub) {
+		p.SetFlag(name, on)
+	}
+}
+
+// SetFlag turns the feature flag name on or off. It takes effect for the next
+// published messages.
+func (p *Pubsub) SetFlag(name string, on bool) error {
+	flag := p.flags.lookup(name)
+	if flag == nil {
+		return ErrUnknownFlag
+	}
+	flag.Store(on)
+	return nil
+}
+
+// Flag returns whether the feature flag name is on.
+func (p *Pubsub) Flag(name string) bool {
+	flag := p.flags.lookup(name)
+	return flag != nil && flag.Load()
+}
+
+// Flags returns the sorted names of the feature flags.
+func Flags() []string {
+	ret := []string{FlagParallelFanout, FlagPatternIndex}
+	sort.Strings(ret)
+	return ret
+}
+
+// fanout sends event to the targets from multiple goroutines.
+func (p *Pubsub) fanout(targets []target, event Event) PublishResult {
+	var (
+		wg     sync.WaitGroup
+		counts [dropped + 1]atomic.Int64
+	)
+	chunk := parallelFanoutMin / 2
+	for i := 0; i < len(targets); i += chunk {
+		end := i + chunk
+		if end > len(targets) {
+			end = len(targets)
+		}
+		wg.Add(1)
+		go func(targets []target) {
+			defer wg.Done()
+			for _, t := range targets {
+				counts[p.send(t.s, event, t.pattern)].Add(1)
+			}
+		}(targets[i:end])
+	}
+	wg.Wait()
+	return PublishResult{
+		Delivered: int(counts[delivered].Load()),
+		Dropped:   int(counts[dropped].Load()),
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestFlags(t *testing.T) {
+	ps := New(-1, WithFlag(FlagParallelFanout, true))
+	assert.Equal(t, ps.Flag(FlagParallelFanout), true)
+	assert.Equal(t, ps.Flag(FlagPatternIndex), true)
+	assert.Equal(t, ps.Flag("unknown"), false)
+	assert.Equal(t, ps.SetFlag("unknown", true), ErrUnknownFlag)
+	assert.Equal(t, Flags(), []string{FlagParallelFanout, FlagPatternIndex})
+
+	var chans []chan Event
+	for i := 0; i < 100; i++ {
+		c := make(chan Event, 1)
+		chans = append(chans, c)
+		ps.Subscribe("topic", c)
+	}
+	assert.Equal(t, ps.Publish("topic", 1), PublishResult{Delivered: 100})
+	assert.Equal(t, ps.Publish("topic", 2), PublishResult{Dropped: 100})
+	for _, c := range chans {
+		assert.Equal(t, (<-c).Message, 1)
+	}
+
+	assert.Equal(t, ps.SetFlag(FlagParallelFanout, false), nil)
+	assert.Equal(t, ps.Publish("topic", 3), PublishResult{Delivered: 100})
+
+	c := make(chan Event, 1)
+	ps.PSubscribe("top*", c)
+	ps.SetFlag(FlagPatternIndex, false)
+	assert.Equal(t, ps.Publish("top", 4), PublishResult{Delivered: 1})
+}
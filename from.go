@@ -0,0 +1,35 @@
+package pubsub
+
+import "sync"
+
+// PublishFrom publishes every message received from src to topic, from a goroutine
+// of the Pubsub, until src is closed, stop is called or the Pubsub is closed. stop
+// waits until the goroutine returned and can be called more than once.
+func (p *Pubsub) PublishFrom(topic string, src <-chan interface{}) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case message, ok := <-src:
+				if !ok {
+					return
+				}
+				p.Publish(topic, message)
+			case <-quit:
+				return
+			case <-p.done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+		})
+		<-done
+	}
+}
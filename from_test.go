@@ -0,0 +1,38 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishFrom(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 2)
+	ps.Subscribe("ticks", c)
+
+	src := make(chan interface{})
+	stop := ps.PublishFrom("ticks", src)
+	src <- 1
+	src <- 2
+	assert.Equal(t, (<-c).Message, 1)
+	assert.Equal(t, (<-c).Message, 2)
+	stop()
+	stop()
+
+	select {
+	case src <- 3:
+		t.Fatal("still consuming after stop")
+	default:
+	}
+
+	src = make(chan interface{})
+	stop = ps.PublishFrom("ticks", src)
+	ps.Close()
+	stop()
+
+	src = make(chan interface{})
+	stop = ps.PublishFrom("ticks", src)
+	close(src)
+	stop()
+}
@@ -0,0 +1,90 @@
+package pubsub
+
+import "context"
+
+// handlerBuffer is the buffer size of the channel of a callback subscription.
+const handlerBuffer = 64
+
+// Handler handles a message received by a callback subscription.
+type Handler func(ctx context.Context, event Event)
+
+// SubscribeFunc subscribes fn to name. fn is called with every message published to
+// name, one at a time, from a goroutine of the subscription, and ctx derived with
+// Context so it also carries the deadline and values of the message. Messages
+// are dropped while fn is busy and its buffer is full. The subscription is evicted
+// when ctx is done, or closed by Unsubscribe of the returned Subscription, fn is
+// still called with the messages buffered before. A panic of fn is recovered and
+// reported as a NoticePanic.
+func (p *Pubsub) SubscribeFunc(ctx context.Context, name string, fn Handler) (*Subscription, error) {
+	name = p.normalize(name)
+	return p.subscribeFunc(ctx, fn, func(c chan Event) error {
+		return p.Subscribe(name, c)
+	})
+}
+
+// PSubscribeFunc subscribes fn to pattern like SubscribeFunc.
+func (p *Pubsub) PSubscribeFunc(ctx context.Context, pattern string, fn Handler) (*Subscription, error) {
+	pattern = p.normalize(pattern)
+	return p.subscribeFunc(ctx, fn, func(c chan Event) error {
+		return p.PSubscribe(pattern, c)
+	})
+}
+
+func (p *Pubsub) subscribeFunc(ctx context.Context, fn Handler, subscribe func(c chan Event) error) (*Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c := make(chan Event, handlerBuffer)
+	if err := subscribe(c); err != nil {
+		return nil, err
+	}
+	s := p.Subscription(c)
+	stop := context.AfterFunc(ctx, func() {
+		p.locker.Lock()
+		defer p.locker.Unlock()
+		p.evict(c, func() {
+			p.removeAll(c)
+		})
+	})
+
+	go func() {
+		defer stop()
+		for {
+			select {
+			case event := <-c:
+				p.handle(ctx, fn, event)
+			case <-s.done:
+				// handle the messages sent before the subscription was closed
+				for len(c) > 0 {
+					p.handle(ctx, fn, <-c)
+				}
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// handle calls fn with event and ctx derived with the Context of event, waiting for
+// a slot if the concurrency of the topic is bounded. A panic of fn is reported as a
+// NoticePanic.
+func (p *Pubsub) handle(ctx context.Context, fn Handler, event Event) {
+	defer p.isolate(event)
+	if len(event.Headers) > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = p.Context(ctx, event)
+		defer cancel()
+	}
+	slots := p.slots(event.Name)
+	if slots == nil {
+		fn(ctx, event)
+		return
+	}
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	defer func() { <-slots }()
+	fn(ctx, event)
+}
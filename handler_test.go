@@ -0,0 +1,92 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+type tenantKey struct{}
+
+func TestSubscribeFunc(t *testing.T) {
+	ps := New(-1)
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), tenantKey{}, "acme"))
+
+	got := make(chan string, 1)
+	s, err := ps.SubscribeFunc(ctx, "a", func(ctx context.Context, e Event) {
+		got <- ctx.Value(tenantKey{}).(string) + ":" + e.Message.(string)
+	})
+	assert.Equal(t, err, nil)
+	ps.Publish("a", "hello")
+	assert.Equal(t, <-got, "acme:hello")
+
+	cancel()
+	waitFor(t, func() bool { return s.State() == Evicted })
+	assert.Equal(t, ps.Topics(), []string{})
+
+	_, err = ps.SubscribeFunc(ctx, "a", func(context.Context, Event) {})
+	assert.Equal(t, err, context.Canceled)
+}
+
+func TestSubscribeFuncStateChanges(t *testing.T) {
+	ps := New(-1)
+	s, err := ps.SubscribeFunc(context.Background(), "a", func(context.Context, Event) {})
+	assert.Equal(t, err, nil)
+	changes := s.StateChanges()
+
+	s.Pause()
+	s.Resume()
+	s.Unsubscribe()
+	var got []State
+	for st := range changes {
+		got = append(got, st)
+	}
+	assert.Equal(t, got, []State{Paused, Active, Closed})
+}
+
+func TestSubscribeFuncMessageContext(t *testing.T) {
+	ps := New(-1, WithContextKey("Tenant", tenantKey{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan context.Context, 1)
+	_, err := ps.SubscribeFunc(ctx, "a", func(ctx context.Context, e Event) {
+		got <- ctx
+	})
+	assert.Equal(t, err, nil)
+	pubCtx, pubCancel := context.WithTimeout(context.WithValue(context.Background(), tenantKey{}, "acme"), time.Hour)
+	defer pubCancel()
+	ps.PublishWithContext(pubCtx, "a", 1)
+
+	handled := <-got
+	assert.Equal(t, handled.Value(tenantKey{}), "acme")
+	deadline, ok := handled.Deadline()
+	want, _ := pubCtx.Deadline()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, deadline.Equal(want), true)
+	// canceled with the handler context too
+	cancel()
+	<-handled.Done()
+}
+
+func TestPSubscribeFunc(t *testing.T) {
+	ps := New(-1)
+	got := make(chan string, 2)
+	s, err := ps.PSubscribeFunc(context.Background(), "a*", func(_ context.Context, e Event) {
+		got <- e.Name
+	})
+	assert.Equal(t, err, nil)
+	ps.Publish("ab", 1)
+	assert.Equal(t, <-got, "ab")
+
+	s.Unsubscribe()
+	assert.Equal(t, s.State(), Closed)
+	ps.Publish("ab", 2)
+	select {
+	case name := <-got:
+		t.Fatalf("got %s after unsubscribe", name)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,72 @@
+package pubsub
+
+import "time"
+
+// HealthTopic is the topic which the Pubsub publishes its Health to when created
+// with WithHealthReport.
+const HealthTopic = "$SYS/health"
+
+// Health is a snapshot of the state of a Pubsub.
+type Health struct {
+	Time          time.Time
+	Topics        int
+	Patterns      int
+	Subscriptions int
+	Published     uint64 // messages published since the Pubsub was created
+	Dropped       uint64 // messages dropped since the Pubsub was created
+	Rates         map[string]Rate
+}
+
+// WithHealthReport publishes the Health of the Pubsub to HealthTopic every interval,
+// until the Pubsub is closed.
+func WithHealthReport(interval time.Duration) Option {
+	return func(p *Pubsub) {
+		p.healthInterval = interval
+	}
+}
+
+// Health returns a snapshot of the state of the Pubsub.
+func (p *Pubsub) Health() Health {
+	p.locker.RLock()
+	h := Health{
+		Time:     time.Now(),
+		Patterns: len(p.patterns),
+	}
+	for _, sh := range p.shards {
+		sh.mu.Lock()
+		h.Topics += len(sh.channels)
+		for _, subs := range sh.channels {
+			h.Subscriptions += len(subs)
+		}
+		sh.mu.Unlock()
+	}
+	for _, groups := range p.queues {
+		for _, g := range groups {
+			h.Subscriptions += len(g.members)
+		}
+	}
+	for _, collection := range []map[string][]*subscriber{p.patterns, p.filters} {
+		for _, subs := range collection {
+			h.Subscriptions += len(subs)
+		}
+	}
+	p.locker.RUnlock()
+
+	h.Published = p.published.Load()
+	h.Dropped = p.dropped.Load()
+	h.Rates = p.Rates()
+	return h
+}
+
+func (p *Pubsub) reportHealth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.Publish(HealthTopic, p.Health())
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestHealth(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	ps.Subscribe("a", c)
+	ps.Subscribe("b", c)
+	ps.PSubscribe("*", c)
+	ps.Publish("a", 1)
+
+	h := ps.Health()
+	assert.Equal(t, h.Topics, 2)
+	assert.Equal(t, h.Patterns, 1)
+	assert.Equal(t, h.Subscriptions, 3)
+	assert.Equal(t, h.Published, uint64(1))
+	assert.Equal(t, h.Dropped, uint64(2))
+}
+
+func TestHealthReport(t *testing.T) {
+	ps := New(-1, WithHealthReport(time.Millisecond))
+	defer ps.Close()
+
+	c := make(chan Event, 1)
+	ps.Subscribe(HealthTopic, c)
+	select {
+	case e := <-c:
+		_, ok := e.Message.(Health)
+		assert.Equal(t, ok, true)
+	case <-time.After(time.Second):
+		t.Fatal("no health report")
+	}
+}
@@ -0,0 +1,196 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// WithHistory keeps the last n messages of every topic, which can be replayed to a
+// new subscriber with Replay, ReplaySince and ReplayAfter.
+func WithHistory(n int) Option {
+	return func(p *Pubsub) {
+		if n <= 0 {
+			return
+		}
+		p.history = &history{
+			size:   n,
+			topics: make(map[string]*historyRing),
+		}
+	}
+}
+
+// History returns the kept messages of name, from oldest to newest.
+func (p *Pubsub) History(name string) []Event {
+	name = p.normalize(name)
+	if p.history == nil {
+		return nil
+	}
+	entries := p.history.since(name, 0, time.Time{}, p.clock.Now())
+	ret := make([]Event, len(entries))
+	for i, entry := range entries {
+		ret[i] = entry.event
+	}
+	return ret
+}
+
+// Replay sends the last n kept messages of name to channel c and subscribes c to
+// name, so c doesn't miss any message published between the replay and the
+// subscription, but may receive a message published concurrently twice. Replayed
+// messages are dropped if c isn't ready to receive.
+func (p *Pubsub) Replay(name string, c chan Event, n int) error {
+	name = p.normalize(name)
+	return p.replay(name, c, n, time.Time{})
+}
+
+// ReplaySince is like Replay, but sends the kept messages published after since.
+func (p *Pubsub) ReplaySince(name string, c chan Event, since time.Time) error {
+	name = p.normalize(name)
+	return p.replay(name, c, 0, since)
+}
+
+// ReplayAfter is like Replay, but sends the kept messages published after the
+// message with id, or all kept messages if none has id, e.g. to resume a stream
+// from its last received message. Messages have IDs WithIDGenerator.
+func (p *Pubsub) ReplayAfter(name string, c chan Event, id string) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	s, err := p.add(byName, name, c)
+	var entries []historyEntry
+	if err == nil && p.history != nil {
+		entries = p.history.since(name, 0, time.Time{}, p.clock.Now())
+		for i, entry := range entries {
+			if id != "" && entry.event.ID == id {
+				entries = entries[i+1:]
+				break
+			}
+		}
+	}
+	p.locker.Unlock()
+	p.sendHistory(s, entries)
+	return err
+}
+
+func (p *Pubsub) replay(name string, c chan Event, n int, since time.Time) error {
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	backfill, err := p.replayLocked(name, c, n, since)
+	p.locker.Unlock()
+	backfill()
+	return err
+}
+
+// replayLocked subscribes c to name with the write lock held, and returns the
+// function sending the kept messages to c, to call once the lock is released so
+// the drop hooks can use the Pubsub.
+func (p *Pubsub) replayLocked(name string, c chan Event, n int, since time.Time) (func(), error) {
+	// subscribe before reading the history, Publish adds to the history before
+	// routing, so a concurrent message is either in the history or delivered.
+	s, err := p.add(byName, name, c)
+	if err != nil || p.history == nil {
+		return noop, err
+	}
+	entries := p.history.since(name, n, since, p.clock.Now())
+	return func() {
+		p.sendHistory(s, entries)
+	}, nil
+}
+
+// sendHistory sends the kept messages of entries to s.
+func (p *Pubsub) sendHistory(s *subscriber, entries []historyEntry) {
+	for _, entry := range entries {
+		p.send(s, entry.event, "")
+	}
+}
+
+type historyEntry struct {
+	event Event
+	time  time.Time
+}
+
+type historyRing struct {
+	entries []historyEntry
+	next    int
+}
+
+type history struct {
+	mu     sync.Mutex
+	size   int
+	topics map[string]*historyRing
+}
+
+func (h *history) add(event Event, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.topics[event.Name]
+	if !ok {
+		ring = &historyRing{}
+		h.topics[event.Name] = ring
+	}
+	entry := historyEntry{event: event, time: now}
+	if len(ring.entries) < h.size {
+		ring.entries = append(ring.entries, entry)
+		return
+	}
+	ring.entries[ring.next] = entry
+	ring.next = (ring.next + 1) % h.size
+}
+
+// len returns the number of messages kept per topic.
+func (h *history) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.size
+}
+
+// resize keeps the last n messages of every topic from now on.
+func (h *history) resize(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ring := range h.topics {
+		entries := append(ring.entries[ring.next:len(ring.entries):len(ring.entries)], ring.entries[:ring.next]...)
+		if len(entries) > n {
+			entries = entries[len(entries)-n:]
+		}
+		ring.entries = append([]historyEntry(nil), entries...)
+		ring.next = 0
+	}
+	h.size = n
+}
+
+// since returns the last n entries of name published after t, from oldest to
+// newest, without the ones expired at now. No limit if n <= 0.
+func (h *history) since(name string, n int, t, now time.Time) []historyEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ring, ok := h.topics[name]
+	if !ok {
+		return nil
+	}
+	ret := make([]historyEntry, 0, len(ring.entries))
+	for _, entries := range [][]historyEntry{ring.entries[ring.next:], ring.entries[:ring.next]} {
+		for _, entry := range entries {
+			if !entry.event.expiredAt(now) {
+				ret = append(ret, entry)
+			}
+		}
+	}
+	if n > 0 && len(ret) > n {
+		ret = ret[len(ret)-n:]
+	}
+	for i, entry := range ret {
+		if entry.time.After(t) {
+			return ret[i:]
+		}
+	}
+	return nil
+}
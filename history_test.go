@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestReplay(t *testing.T) {
+	ps := New(-1, WithHistory(3))
+	for i := 0; i < 5; i++ {
+		ps.Publish("topic", i)
+	}
+	assert.Equal(t, ps.History("topic"), []Event{
+		{Name: "topic", Message: 2},
+		{Name: "topic", Message: 3},
+		{Name: "topic", Message: 4},
+	})
+
+	c := make(chan Event, 10)
+	assert.Equal(t, ps.Replay("topic", c, 2), nil)
+	ps.Publish("topic", 5)
+	assert.Equal(t, (<-c).Message, 3)
+	assert.Equal(t, (<-c).Message, 4)
+	assert.Equal(t, (<-c).Message, 5)
+
+	all := make(chan Event, 10)
+	assert.Equal(t, ps.Replay("topic", all, 0), nil)
+	assert.Equal(t, len(all), 3)
+}
+
+func TestReplaySince(t *testing.T) {
+	ps := New(-1, WithHistory(10))
+	ps.Publish("topic", "old")
+	since := time.Now()
+	time.Sleep(time.Millisecond)
+	ps.Publish("topic", "new")
+
+	c := make(chan Event, 10)
+	assert.Equal(t, ps.ReplaySince("topic", c, since), nil)
+	assert.Equal(t, len(c), 1)
+	assert.Equal(t, (<-c).Message, "new")
+
+	none := make(chan Event, 10)
+	assert.Equal(t, New(-1).Replay("topic", none, 1), nil)
+	assert.Equal(t, len(none), 0)
+}
+
+func TestReplayAfter(t *testing.T) {
+	n := 0
+	ps := New(-1, WithHistory(3), WithIDGenerator(IDGeneratorFunc(func() string {
+		n++
+		return strconv.Itoa(n)
+	})))
+	for i := 0; i < 5; i++ {
+		ps.Publish("topic", i)
+	}
+
+	messages := func(c chan Event) []interface{} {
+		var ret []interface{}
+		for len(c) > 0 {
+			ret = append(ret, (<-c).Message)
+		}
+		return ret
+	}
+	c := make(chan Event, 10)
+	assert.Equal(t, ps.ReplayAfter("topic", c, "3"), nil)
+	assert.Equal(t, messages(c), []interface{}{3, 4})
+	ps.Publish("topic", 5)
+	assert.Equal(t, messages(c), []interface{}{5})
+
+	// older than the history
+	d := make(chan Event, 10)
+	ps.ReplayAfter("topic", d, "1")
+	assert.Equal(t, messages(d), []interface{}{3, 4, 5})
+	e := make(chan Event, 10)
+	ps.ReplayAfter("topic", e, "")
+	assert.Equal(t, messages(e), []interface{}{3, 4, 5})
+}
+
+func TestReplayDropHandler(t *testing.T) {
+	var ps *Pubsub
+	ps = New(-1, WithHistory(2), OnDrop(func(DroppedMessage) {
+		ps.Topics()
+	}))
+	ps.Publish("a", 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ps.Replay("a", make(chan Event), 1)
+		ps.ReplayAfter("a", make(chan Event), "")
+		ps.SubscribeLate("a", make(chan Event), BackfillLast(1))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+	assert.Equal(t, ps.Stats().Dropped, uint64(3))
+}
@@ -0,0 +1,205 @@
+// Package httpsse streams the messages of a Pubsub as Server-Sent Events, e.g. to
+// live dashboards in the browser:
+//
+//	http.Handle("/events", httpsse.New(ps))
+//
+//	new EventSource("/events?topic=orders&pattern=users/*").onmessage = (e) => {
+//		const {topic, pattern, data} = JSON.parse(e.data)
+//	}
+//
+// Every message is sent as a message event with the JSON of an Event, and the ID
+// of the message if it has one. A client reconnecting with the Last-Event-ID
+// header receives the messages of its topics it missed, as far as the Pubsub
+// keeps them WithHistory. WithBatch flushes the events of several messages at
+// once. Requests over the Quota of their principal WithQuotas are answered with
+// 429 Too Many Requests.
+package httpsse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// Event is the data of a message event.
+type Event struct {
+	Topic   string          `json:"topic"`
+	Pattern string          `json:"pattern,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Handler is an http.Handler streaming the messages of the topics of the topic
+// query parameters and the patterns of the pattern query parameters.
+type Handler struct {
+	ps        *pubsub.Pubsub
+	heartbeat time.Duration
+	buffer    int
+	quotas    *pubsub.Quotas
+	batch     int
+	delay     time.Duration
+}
+
+// Option configures a Handler.
+type Option func(h *Handler)
+
+// WithHeartbeat sends a comment every d to keep idle connections open, 15s by
+// default. No heartbeats if d <= 0.
+func WithHeartbeat(d time.Duration) Option {
+	return func(h *Handler) {
+		h.heartbeat = d
+	}
+}
+
+// WithBuffer buffers up to n messages per connection, 64 by default. Messages are
+// dropped when a client is slower.
+func WithBuffer(n int) Option {
+	return func(h *Handler) {
+		h.buffer = n
+	}
+}
+
+// WithQuotas enforces the quotas of q on the connections, for the principal of the
+// identity of the request context, see pubsub.ContextWithIdentity.
+func WithQuotas(q *pubsub.Quotas) Option {
+	return func(h *Handler) {
+		h.quotas = q
+	}
+}
+
+// WithBatch writes the messages of a connection in batches of up to maxSize
+// messages, flushed when full or maxDelay after their first message, e.g. to send
+// many small messages in fewer writes.
+func WithBatch(maxSize int, maxDelay time.Duration) Option {
+	return func(h *Handler) {
+		h.batch = maxSize
+		h.delay = maxDelay
+	}
+}
+
+// New creates a Handler of ps.
+func New(ps *pubsub.Pubsub, opts ...Option) *Handler {
+	h := &Handler{
+		ps:        ps,
+		heartbeat: 15 * time.Second,
+		buffer:    64,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	topics, patterns := query["topic"], query["pattern"]
+	if len(topics) == 0 && len(patterns) == 0 {
+		http.Error(w, "missing topic or pattern", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	c := make(chan pubsub.Event, h.buffer)
+	quota := h.quotas.Session(pubsub.IdentityFromContext(r.Context()))
+	defer quota.Close()
+	defer h.ps.UnsubscribeAll(c)
+	lastID := r.Header.Get("Last-Event-ID")
+	for _, topic := range topics {
+		err := quota.Subscribe(topic, false)
+		if err == nil && lastID != "" {
+			err = h.ps.ReplayAfter(topic, c, lastID)
+		} else if err == nil {
+			err = h.ps.Subscribe(topic, c)
+		}
+		if err != nil {
+			subscribeError(w, err)
+			return
+		}
+	}
+	for _, pattern := range patterns {
+		err := quota.Subscribe(pattern, true)
+		if err == nil {
+			err = h.ps.PSubscribe(pattern, c)
+		}
+		if err != nil {
+			subscribeError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	size, delay := h.batch, h.delay
+	if size <= 0 {
+		size, delay = 1, 0
+	}
+	batches := pubsub.Batch(r.Context(), c, size, delay)
+	var heartbeat <-chan time.Time
+	if h.heartbeat > 0 {
+		ticker := time.NewTicker(h.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case events, ok := <-batches:
+			if !ok {
+				return
+			}
+			for _, event := range events {
+				if err = write(w, event); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// subscribeError replies to a request whose subscription failed with err.
+func subscribeError(w http.ResponseWriter, err error) {
+	code := http.StatusServiceUnavailable
+	if errors.Is(err, pubsub.ErrQuotaExceeded) {
+		code = http.StatusTooManyRequests
+	}
+	http.Error(w, err.Error(), code)
+}
+
+// write writes the message event of event.
+func write(w http.ResponseWriter, event pubsub.Event) error {
+	data, err := json.Marshal(event.Message)
+	if err != nil {
+		// skip the messages which can't be encoded
+		return nil
+	}
+	data, err = json.Marshal(Event{Topic: event.Name, Pattern: event.Pattern, Data: data})
+	if err != nil {
+		return nil
+	}
+	if event.ID != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
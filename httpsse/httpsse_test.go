@@ -0,0 +1,140 @@
+package httpsse
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// stream connects to url, and returns the lines of the response.
+func stream(t *testing.T, url, lastID string) (*http.Response, func() string) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	return resp, func() string {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSuffix(line, "\n")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	n := 0
+	ps := pubsub.New(-1, pubsub.WithHistory(10), pubsub.WithIDGenerator(pubsub.IDGeneratorFunc(func() string {
+		n++
+		return strconv.Itoa(n)
+	})))
+	server := httptest.NewServer(New(ps))
+	defer server.Close()
+
+	resp, read := stream(t, server.URL+"?topic=orders&pattern=users/*", "")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	for ps.NumSubscribers("orders") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("orders", map[string]int{"id": 1})
+	assert.Equal(t, read(), "id: 1")
+	assert.Equal(t, read(), `data: {"topic":"orders","data":{"id":1}}`)
+	assert.Equal(t, read(), "")
+	ps.Publish("users/1", "ann")
+	assert.Equal(t, read(), "id: 2")
+	assert.Equal(t, read(), `data: {"topic":"users/1","pattern":"users/*","data":"ann"}`)
+	resp.Body.Close()
+
+	ps.Publish("orders", 2)
+	ps.Publish("orders", 3)
+	resp, read = stream(t, server.URL+"?topic=orders", "3")
+	defer resp.Body.Close()
+	assert.Equal(t, read(), "id: 4")
+	assert.Equal(t, read(), `data: {"topic":"orders","data":3}`)
+}
+
+func TestHandlerBatch(t *testing.T) {
+	ps := pubsub.New(-1)
+	server := httptest.NewServer(New(ps, WithBatch(2, 50*time.Millisecond)))
+	defer server.Close()
+	resp, read := stream(t, server.URL+"?topic=orders", "")
+	defer resp.Body.Close()
+	for ps.NumSubscribers("orders") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("orders", 1)
+	ps.Publish("orders", 2)
+	assert.Equal(t, read(), `data: {"topic":"orders","data":1}`)
+	assert.Equal(t, read(), "")
+	assert.Equal(t, read(), `data: {"topic":"orders","data":2}`)
+	assert.Equal(t, read(), "")
+
+	// flushed after the delay
+	start := time.Now()
+	ps.Publish("orders", 3)
+	assert.Equal(t, read(), `data: {"topic":"orders","data":3}`)
+	assert.Equal(t, time.Since(start) >= 50*time.Millisecond, true)
+}
+
+func TestHandlerHeartbeat(t *testing.T) {
+	server := httptest.NewServer(New(pubsub.New(-1), WithHeartbeat(time.Millisecond)))
+	defer server.Close()
+	resp, read := stream(t, server.URL+"?topic=orders", "")
+	defer resp.Body.Close()
+	assert.Equal(t, read(), ": heartbeat")
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	server := httptest.NewServer(New(pubsub.New(-1)))
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+}
+
+func TestHandlerQuotas(t *testing.T) {
+	quotas := pubsub.NewQuotas(func(principal string) pubsub.Quota {
+		return pubsub.Quota{MaxPatternSubscriptions: 1}
+	})
+	handler := New(pubsub.New(-1), WithQuotas(quotas))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(pubsub.ContextWithIdentity(r.Context(), "alice")))
+	}))
+	defer server.Close()
+
+	resp, _ := stream(t, server.URL+"?pattern=users/*", "")
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	over, err := http.Get(server.URL + "?pattern=orders/*")
+	assert.Equal(t, err, nil)
+	over.Body.Close()
+	assert.Equal(t, over.StatusCode, http.StatusTooManyRequests)
+
+	// closing the stream releases the quota
+	resp.Body.Close()
+	for i := 0; ; i++ {
+		resp, _ = stream(t, server.URL+"?pattern=orders/*", "")
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+		resp.Body.Close()
+		if i == 100 {
+			t.Fatal("quota not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp.Body.Close()
+}
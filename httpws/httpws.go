@@ -0,0 +1,241 @@
+// Package httpws exposes a Pubsub over WebSocket. Clients send JSON frames to
+// subscribe and publish, and receive the messages of their subscriptions:
+//
+//	{"op": "subscribe", "topic": "orders"}
+//	{"op": "psubscribe", "topic": "orders/*"}
+//	{"op": "publish", "topic": "orders", "data": {"id": 1}}
+//
+// Messages are sent as {"op": "message", "topic": ..., "pattern": ..., "data": ...}
+// frames, or WithBatch as {"op": "batch", "frames": [...]} frames. Invalid frames
+// and rejected messages are answered with {"op": "error", "error": ...}, with
+// "code": "quota_exceeded" for the frames over the Quota of the principal
+// WithQuotas. Messages are published with the identity of the request context, see
+// pubsub.ContextWithIdentity. The subscriptions of a connection are removed when
+// it's closed.
+package httpws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// Operations of the frames.
+const (
+	OpSubscribe    = "subscribe"
+	OpUnsubscribe  = "unsubscribe"
+	OpPSubscribe   = "psubscribe"
+	OpPUnsubscribe = "punsubscribe"
+	OpPublish      = "publish"
+	OpMessage      = "message"
+	OpBatch        = "batch"
+	OpError        = "error"
+)
+
+// CodeQuotaExceeded is the Code of the error frames of the frames over the Quota of
+// the principal.
+const CodeQuotaExceeded = "quota_exceeded"
+
+// Frame is a frame sent or received over a connection.
+type Frame struct {
+	Op      string          `json:"op"`
+	Topic   string          `json:"topic,omitempty"`
+	Pattern string          `json:"pattern,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Code    string          `json:"code,omitempty"`
+	Frames  []Frame         `json:"frames,omitempty"`
+}
+
+// Server is an http.Handler serving WebSocket connections to a Pubsub.
+type Server struct {
+	ps       *pubsub.Pubsub
+	upgrader websocket.Upgrader
+	buffer   int
+	quotas   *pubsub.Quotas
+	batch    int
+	delay    time.Duration
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithUpgrader upgrades the HTTP connections with u, e.g. to check the origin.
+func WithUpgrader(u websocket.Upgrader) Option {
+	return func(s *Server) {
+		s.upgrader = u
+	}
+}
+
+// WithBuffer buffers up to n messages per connection, 64 by default. Messages are
+// dropped when a client is slower.
+func WithBuffer(n int) Option {
+	return func(s *Server) {
+		s.buffer = n
+	}
+}
+
+// WithQuotas enforces the quotas of q on the connections, for the principal of the
+// identity of the request context, see pubsub.ContextWithIdentity.
+func WithQuotas(q *pubsub.Quotas) Option {
+	return func(s *Server) {
+		s.quotas = q
+	}
+}
+
+// WithBatch sends the messages of a connection in batch frames of up to maxSize
+// messages, sent when full or maxDelay after their first message, e.g. to write
+// many small messages in fewer frames.
+func WithBatch(maxSize int, maxDelay time.Duration) Option {
+	return func(s *Server) {
+		s.batch = maxSize
+		s.delay = maxDelay
+	}
+}
+
+// New creates a Server of ps.
+func New(ps *pubsub.Pubsub, opts ...Option) *Server {
+	s := &Server{
+		ps:     ps,
+		buffer: 64,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection, and serves it until
+// it's closed.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader replied with the error
+		return
+	}
+	conn := &conn{
+		ctx:   r.Context(),
+		ws:    ws,
+		c:     make(chan pubsub.Event, s.buffer),
+		quota: s.quotas.Session(pubsub.IdentityFromContext(r.Context())),
+	}
+	defer ws.Close()
+	defer conn.quota.Close()
+	defer s.ps.UnsubscribeAll(conn.c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	size, delay := s.batch, s.delay
+	if size <= 0 {
+		size, delay = 1, 0
+	}
+	go conn.forward(pubsub.Batch(ctx, conn.c, size, delay), s.batch > 0)
+	for {
+		var f Frame
+		if err := ws.ReadJSON(&f); err != nil {
+			return
+		}
+		if err := s.handle(conn, f); err != nil {
+			reply := Frame{Op: OpError, Topic: f.Topic, Error: err.Error()}
+			if errors.Is(err, pubsub.ErrQuotaExceeded) {
+				reply.Code = CodeQuotaExceeded
+			}
+			if conn.write(reply) != nil {
+				return
+			}
+		}
+	}
+}
+
+// frameError is the error of an invalid frame.
+type frameError string
+
+func (e frameError) Error() string {
+	return string(e)
+}
+
+func (s *Server) handle(conn *conn, f Frame) error {
+	if f.Topic == "" {
+		return frameError("missing topic")
+	}
+	switch f.Op {
+	case OpSubscribe:
+		return conn.subscribe(f.Topic, false, s.ps.Subscribe)
+	case OpUnsubscribe:
+		s.ps.Unsubscribe(f.Topic, conn.c)
+		conn.quota.Unsubscribe(f.Topic, false)
+	case OpPSubscribe:
+		return conn.subscribe(f.Topic, true, s.ps.PSubscribe)
+	case OpPUnsubscribe:
+		s.ps.PUnsubscribe(f.Topic, conn.c)
+		conn.quota.Unsubscribe(f.Topic, true)
+	case OpPublish:
+		if err := conn.quota.Publish(); err != nil {
+			return err
+		}
+		if r := s.ps.PublishWithContext(conn.ctx, f.Topic, f.Data); r.Err != nil {
+			return r.Err
+		}
+	default:
+		return frameError("unknown op " + f.Op)
+	}
+	return nil
+}
+
+// conn is a WebSocket connection.
+type conn struct {
+	ctx   context.Context // of the request, with the identity of the client
+	ws    *websocket.Conn
+	mu    sync.Mutex // serializes the writes
+	c     chan pubsub.Event
+	quota *pubsub.QuotaSession
+}
+
+// subscribe subscribes the connection to topic with fn, within its quota.
+func (c *conn) subscribe(topic string, pattern bool, fn func(string, chan pubsub.Event) error) error {
+	if err := c.quota.Subscribe(topic, pattern); err != nil {
+		return err
+	}
+	if err := fn(topic, c.c); err != nil {
+		c.quota.Unsubscribe(topic, pattern)
+		return err
+	}
+	return nil
+}
+
+func (c *conn) write(f Frame) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteJSON(f)
+}
+
+// forward writes the batches of messages of the subscriptions until the connection
+// is done, in batch frames if batched.
+func (c *conn) forward(batches <-chan []pubsub.Event, batched bool) {
+	for events := range batches {
+		frames := make([]Frame, 0, len(events))
+		for _, event := range events {
+			data, err := json.Marshal(event.Message)
+			if err != nil {
+				frames = append(frames, Frame{Op: OpError, Topic: event.Name, Error: err.Error()})
+				continue
+			}
+			frames = append(frames, Frame{Op: OpMessage, Topic: event.Name, Pattern: event.Pattern, Data: data})
+		}
+		if batched {
+			frames = []Frame{{Op: OpBatch, Frames: frames}}
+		}
+		for _, f := range frames {
+			if c.write(f) != nil {
+				// the reader sees the error and closes the connection
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package httpws
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	"github.com/gorilla/websocket"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestServer(t *testing.T) {
+	ps := pubsub.New(-1)
+	server := httptest.NewServer(New(ps))
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	read := func() Frame {
+		var f Frame
+		if err := ws.ReadJSON(&f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	ws.WriteJSON(Frame{Op: OpSubscribe, Topic: "orders"})
+	ws.WriteJSON(Frame{Op: OpPSubscribe, Topic: "users/*"})
+	ws.WriteJSON(Frame{Op: "listen", Topic: "orders"})
+	assert.Equal(t, read(), Frame{Op: OpError, Topic: "orders", Error: "unknown op listen"})
+
+	ps.Publish("orders", map[string]int{"id": 1})
+	assert.Equal(t, read(), Frame{Op: OpMessage, Topic: "orders", Data: json.RawMessage(`{"id":1}`)})
+	ps.Publish("users/1", "ann")
+	assert.Equal(t, read(), Frame{Op: OpMessage, Topic: "users/1", Pattern: "users/*", Data: json.RawMessage(`"ann"`)})
+
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("carts", c)
+	ws.WriteJSON(Frame{Op: OpPublish, Topic: "carts", Data: json.RawMessage(`[1,2]`)})
+	var items []int
+	assert.Equal(t, (<-c).Into(&items), nil)
+	assert.Equal(t, items, []int{1, 2})
+
+	ws.Close()
+	for i := 0; i < 100 && ps.NumSubscribers("orders") > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, ps.NumSubscribers("orders"), 0)
+	assert.Equal(t, ps.Topics(), []string{"carts"})
+}
+
+func TestServerPublish(t *testing.T) {
+	identities := make(chan string, 1)
+	ps := pubsub.New(-1, pubsub.WithAuditor(pubsub.AuditorFunc(func(record pubsub.AuditRecord) {
+		if record.Action == pubsub.AuditPublish {
+			identities <- record.Identity
+		}
+	})))
+	ps.RegisterTopicValidator("orders", func(event pubsub.Event) error {
+		if string(event.Message.(json.RawMessage)) == "null" {
+			return errors.New("empty order")
+		}
+		return nil
+	})
+	handler := New(ps)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(pubsub.ContextWithIdentity(r.Context(), "alice")))
+	}))
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	// the messages are published with the identity of the request
+	ws.WriteJSON(Frame{Op: OpPublish, Topic: "orders", Data: json.RawMessage(`1`)})
+	assert.Equal(t, <-identities, "alice")
+
+	// the rejected messages are answered with an error
+	ws.WriteJSON(Frame{Op: OpPublish, Topic: "orders", Data: json.RawMessage(`null`)})
+	var f Frame
+	if err := ws.ReadJSON(&f); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, f.Op, OpError)
+	assert.Equal(t, f.Topic, "orders")
+	assert.Equal(t, strings.Contains(f.Error, "empty order"), true)
+}
+
+func TestServerBatch(t *testing.T) {
+	ps := pubsub.New(-1)
+	server := httptest.NewServer(New(ps, WithBatch(2, 50*time.Millisecond)))
+	defer server.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	read := func() Frame {
+		var f Frame
+		if err := ws.ReadJSON(&f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	ws.WriteJSON(Frame{Op: OpSubscribe, Topic: "orders"})
+	ws.WriteJSON(Frame{Op: "listen", Topic: "orders"})
+	assert.Equal(t, read().Op, OpError)
+
+	for i := 1; i <= 3; i++ {
+		ps.Publish("orders", i)
+	}
+	// full
+	assert.Equal(t, read(), Frame{Op: OpBatch, Frames: []Frame{
+		{Op: OpMessage, Topic: "orders", Data: json.RawMessage(`1`)},
+		{Op: OpMessage, Topic: "orders", Data: json.RawMessage(`2`)},
+	}})
+	// after the delay
+	assert.Equal(t, read(), Frame{Op: OpBatch, Frames: []Frame{
+		{Op: OpMessage, Topic: "orders", Data: json.RawMessage(`3`)},
+	}})
+}
+
+func TestServerQuotas(t *testing.T) {
+	ps := pubsub.New(-1)
+	quotas := pubsub.NewQuotas(func(principal string) pubsub.Quota {
+		return pubsub.Quota{MaxSubscriptions: 1, PublishRate: 0.001, PublishBurst: 1}
+	})
+	handler := New(ps, WithQuotas(quotas))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(pubsub.ContextWithIdentity(r.Context(), "alice")))
+	}))
+	defer server.Close()
+
+	dial := func() *websocket.Conn {
+		ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { ws.Close() })
+		return ws
+	}
+	read := func(ws *websocket.Conn) Frame {
+		var f Frame
+		if err := ws.ReadJSON(&f); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	first, second := dial(), dial()
+	first.WriteJSON(Frame{Op: OpSubscribe, Topic: "orders"})
+	first.WriteJSON(Frame{Op: OpPublish, Topic: "orders", Data: json.RawMessage(`1`)})
+	assert.Equal(t, read(first).Op, OpMessage)
+
+	// the quota is shared by the connections of the principal
+	second.WriteJSON(Frame{Op: OpSubscribe, Topic: "users"})
+	f := read(second)
+	assert.Equal(t, f.Op, OpError)
+	assert.Equal(t, f.Code, CodeQuotaExceeded)
+	second.WriteJSON(Frame{Op: OpPublish, Topic: "orders", Data: json.RawMessage(`2`)})
+	assert.Equal(t, read(second).Code, CodeQuotaExceeded)
+
+	// unsubscribing releases the quota
+	first.WriteJSON(Frame{Op: OpUnsubscribe, Topic: "orders"})
+	for i := 0; ; i++ {
+		second.WriteJSON(Frame{Op: OpSubscribe, Topic: "users"})
+		second.WriteJSON(Frame{Op: "listen"})
+		if f := read(second); f.Code == "" {
+			break
+		}
+		read(second)
+		if i == 100 {
+			t.Fatal("quota not released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ps.Publish("users", "ann")
+	assert.Equal(t, read(second).Op, OpMessage)
+}
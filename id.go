@@ -0,0 +1,169 @@
+package pubsub
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IDGenerator generates the IDs of published messages.
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc is an adapter to allow the use of ordinary functions as IDGenerator.
+type IDGeneratorFunc func() string
+
+// NewID calls f().
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// WithIDGenerator sets the ID of every published message with g.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(p *Pubsub) {
+		p.ids = g
+	}
+}
+
+// UUIDv7 returns an IDGenerator of RFC 9562 version 7 UUIDs. IDs generated by the
+// same generator sort in generation order.
+func UUIDv7() IDGenerator {
+	return &uuidv7{}
+}
+
+type uuidv7 struct {
+	mu   sync.Mutex
+	ms   int64
+	seq  uint16
+	rand [8]byte
+}
+
+func (g *uuidv7) NewID() string {
+	g.mu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= g.ms {
+		// keep monotonic within the same millisecond with the 12 bits counter
+		g.seq++
+		if g.seq > 0xfff {
+			g.seq = 0
+			g.ms++
+		}
+		ms = g.ms
+	} else {
+		g.ms = ms
+		g.seq = 0
+	}
+	seq := g.seq
+	rand.Read(g.rand[:])
+	var b [16]byte
+	copy(b[8:], g.rand[:])
+	g.mu.Unlock()
+
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = 0x70 | byte(seq>>8)
+	b[7] = byte(seq)
+	b[8] = 0x80 | b[8]&0x3f
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns an IDGenerator of ULIDs. IDs generated by the same generator sort in
+// generation order.
+func ULID() IDGenerator {
+	return &ulid{}
+}
+
+type ulid struct {
+	mu   sync.Mutex
+	ms   int64
+	rand [10]byte
+}
+
+func (g *ulid) NewID() string {
+	g.mu.Lock()
+	ms := time.Now().UnixMilli()
+	if ms <= g.ms {
+		// increment the random part to keep monotonic within the same millisecond
+		ms = g.ms
+		for i := len(g.rand) - 1; i >= 0; i-- {
+			g.rand[i]++
+			if g.rand[i] != 0 {
+				break
+			}
+		}
+	} else {
+		g.ms = ms
+		rand.Read(g.rand[:])
+	}
+	var b [16]byte
+	binary.BigEndian.PutUint16(b[0:], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:], uint32(ms))
+	copy(b[6:], g.rand[:])
+	g.mu.Unlock()
+
+	hi := binary.BigEndian.Uint64(b[0:8])
+	lo := binary.BigEndian.Uint64(b[8:16])
+	var buf [26]byte
+	for i := 25; i >= 0; i-- {
+		buf[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(buf[:])
+}
+
+// SnowflakeEpoch is the epoch of the timestamp of snowflake IDs, in milliseconds.
+const SnowflakeEpoch = 1288834974657
+
+// Snowflake returns an IDGenerator of 64 bits snowflake IDs formatted in decimal,
+// made of a millisecond timestamp, the 10 bits node and a 12 bits sequence. IDs
+// generated by the same generator sort in generation order when compared as numbers.
+func Snowflake(node int64) IDGenerator {
+	return &snowflake{node: node & 0x3ff}
+}
+
+type snowflake struct {
+	mu   sync.Mutex
+	node int64
+	ms   int64
+	seq  int64
+}
+
+func (g *snowflake) NewID() string {
+	g.mu.Lock()
+	ms := time.Now().UnixMilli() - SnowflakeEpoch
+	if ms <= g.ms {
+		g.seq = (g.seq + 1) & 0xfff
+		if g.seq == 0 {
+			g.ms++
+		}
+		ms = g.ms
+	} else {
+		g.ms = ms
+		g.seq = 0
+	}
+	id := ms<<22 | g.node<<12 | g.seq
+	g.mu.Unlock()
+	return strconv.FormatInt(id, 10)
+}
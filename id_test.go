@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func generate(g IDGenerator, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = g.NewID()
+	}
+	return ids
+}
+
+func TestUUIDv7(t *testing.T) {
+	ids := generate(UUIDv7(), 5000)
+	assert.Equal(t, sort.StringsAreSorted(ids), true)
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, id := range ids {
+		assert.Equal(t, re.MatchString(id), true, id)
+	}
+}
+
+func TestULID(t *testing.T) {
+	ids := generate(ULID(), 5000)
+	assert.Equal(t, sort.StringsAreSorted(ids), true)
+	re := regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+	for _, id := range ids {
+		assert.Equal(t, re.MatchString(id), true, id)
+	}
+}
+
+func TestSnowflake(t *testing.T) {
+	ids := generate(Snowflake(3), 5000)
+	last := int64(0)
+	for _, id := range ids {
+		n, err := strconv.ParseInt(id, 10, 64)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, n > last, true)
+		assert.Equal(t, n>>12&0x3ff, int64(3))
+		last = n
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	ps := New(-1, WithIDGenerator(IDGeneratorFunc(func() string { return "id" })))
+	c := make(chan Event, 2)
+	ps.Subscribe("a", c)
+	ps.PublishRetain("a", 1)
+	assert.Equal(t, (<-c).ID, "id")
+
+	late := make(chan Event, 1)
+	ps.SubscribeRetained("a", late)
+	assert.Equal(t, (<-late).ID, "id")
+}
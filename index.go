@@ -0,0 +1,99 @@
+package pubsub
+
+import (
+	"sort"
+	"strings"
+)
+
+// Prefixer is implemented by Matchers which know the literal prefix of every topic
+// a pattern matches. Patterns of such Matchers are indexed by their prefix when
+// subscribed, so Publish only matches the patterns which can match the topic.
+type Prefixer interface {
+	LiteralPrefix(pattern string) string
+}
+
+func (GlobMatcher) LiteralPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+func (PrefixMatcher) LiteralPrefix(pattern string) string {
+	return pattern
+}
+
+func (MQTTMatcher) LiteralPrefix(pattern string) string {
+	i := strings.IndexAny(pattern, "+#")
+	if i < 0 {
+		return pattern
+	}
+	if pattern[i] == '#' && i > 0 {
+		// a/# matches a
+		i--
+	}
+	return pattern[:i]
+}
+
+func (m *RegexpMatcher) LiteralPrefix(pattern string) string {
+	re := m.compile(pattern)
+	if re == nil {
+		return pattern
+	}
+	prefix, _ := re.LiteralPrefix()
+	return prefix
+}
+
+// patternRoutes is the routing snapshot of pattern subscriptions.
+type patternRoutes struct {
+	subs  map[string][]*subscriber
+	index *patternIndex // nil if the Matcher isn't a Prefixer
+}
+
+func newPatternRoutes(patterns map[string][]*subscriber, m Matcher) *patternRoutes {
+	r := &patternRoutes{
+		subs: make(map[string][]*subscriber, len(patterns)),
+	}
+	for pattern, subs := range patterns {
+		r.subs[pattern] = subs
+	}
+	if prefixer, ok := m.(Prefixer); ok {
+		r.index = newPatternIndex(prefixer, patterns)
+	}
+	return r
+}
+
+// patternIndex indexes patterns by their literal prefix.
+type patternIndex struct {
+	prefixes map[string][]string // literal prefix -> patterns
+	lens     []int               // sorted lengths of the prefixes
+}
+
+func newPatternIndex(prefixer Prefixer, patterns map[string][]*subscriber) *patternIndex {
+	idx := &patternIndex{
+		prefixes: make(map[string][]string),
+	}
+	seen := make(map[int]bool)
+	for pattern := range patterns {
+		prefix := prefixer.LiteralPrefix(pattern)
+		idx.prefixes[prefix] = append(idx.prefixes[prefix], pattern)
+		if !seen[len(prefix)] {
+			seen[len(prefix)] = true
+			idx.lens = append(idx.lens, len(prefix))
+		}
+	}
+	sort.Ints(idx.lens)
+	return idx
+}
+
+// candidates calls fn with the patterns whose prefix is a prefix of topic.
+func (idx *patternIndex) candidates(topic string, fn func(pattern string)) {
+	for _, n := range idx.lens {
+		if n > len(topic) {
+			return
+		}
+		for _, pattern := range idx.prefixes[topic[:n]] {
+			fn(pattern)
+		}
+	}
+}
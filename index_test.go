@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		prefixer Prefixer
+		pattern  string
+		want     string
+	}{
+		{GlobMatcher{}, "user.*.created", "user."},
+		{GlobMatcher{}, `a\*`, "a"},
+		{GlobMatcher{}, "exact", "exact"},
+		{PrefixMatcher{}, "user.", "user."},
+		{MQTTMatcher{}, "a/+/c", "a/"},
+		{MQTTMatcher{}, "a/#", "a"},
+		{MQTTMatcher{}, "#", ""},
+		{NewRegexpMatcher(), `user\.\d+`, "user."},
+		{NewRegexpMatcher(), `(a|b)`, ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.prefixer.LiteralPrefix(test.pattern), test.want, test)
+	}
+}
+
+func TestPatternIndex(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 10)
+	ps.PSubscribe("user.*", c)
+	ps.PSubscribe("user.?", c)
+	ps.PSubscribe("*", c)
+	ps.PSubscribe("order.*", c)
+	assert.Equal(t, ps.patternRoutes.Load().index.lens, []int{0, 5, 6})
+
+	assert.Equal(t, ps.Publish("user.1", 1).Delivered, 3)
+	assert.Equal(t, ps.Publish("order.1", 1).Delivered, 2)
+	assert.Equal(t, ps.Publish("x", 1).Delivered, 1)
+
+	ps.PUnsubscribe("order.*", c)
+	assert.Equal(t, ps.patternRoutes.Load().index.lens, []int{0, 5})
+	ps.UnsubscribeAll(c)
+	assert.Equal(t, len(ps.patternRoutes.Load().index.prefixes), 0)
+	assert.Equal(t, len(ps.patternRoutes.Load().index.lens), 0)
+}
+
+func benchmarkPatterns(b *testing.B, opts ...Option) {
+	ps := New(-1, opts...)
+	// match the patterns on every publish
+	ps.routeCache.disabled = true
+	c := make(chan Event)
+	for i := 0; i < 1000; i++ {
+		ps.PSubscribe(fmt.Sprintf("service%d.*.event", i), c)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish("service42.node.event", i)
+	}
+}
+
+func BenchmarkPublishPatternsIndexed(b *testing.B) {
+	benchmarkPatterns(b)
+}
+
+func BenchmarkPublishPatternsLinear(b *testing.B) {
+	benchmarkPatterns(b, WithMatcher(MatcherFunc(GlobMatcher{}.Match)))
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"slices"
+	"sort"
+)
+
+// Topics returns the sorted names which have subscribers, by name or in a queue
+// group.
+func (p *Pubsub) Topics() []string {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	ret := []string{}
+	for _, sh := range p.shards {
+		sh.mu.Lock()
+		for name := range sh.channels {
+			ret = append(ret, name)
+		}
+		sh.mu.Unlock()
+	}
+	for name := range p.queues {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return slices.Compact(ret)
+}
+
+// Patterns returns the sorted patterns which have subscribers.
+func (p *Pubsub) Patterns() []string {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	return sortedKeys(p.patterns)
+}
+
+// NumSubscribers returns the number of channels subscribed to name.
+func (p *Pubsub) NumSubscribers(name string) int {
+	name = p.normalize(name)
+	sh := p.shard(name)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return len(sh.channels[name])
+}
+
+// NumPSubscribers returns the number of channels subscribed to pattern.
+func (p *Pubsub) NumPSubscribers(pattern string) int {
+	pattern = p.normalize(pattern)
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	return len(p.patterns[pattern])
+}
+
+func sortedKeys(collection map[string][]*subscriber) []string {
+	ret := make([]string, 0, len(collection))
+	for name := range collection {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,27 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestIntrospect(t *testing.T) {
+	ps := New(-1)
+	c1 := make(chan Event)
+	c2 := make(chan Event)
+	ps.Subscribe("b", c1)
+	ps.Subscribe("a", c1)
+	ps.Subscribe("a", c2)
+	ps.PSubscribe("a*", c2)
+
+	assert.Equal(t, ps.Topics(), []string{"a", "b"})
+	assert.Equal(t, ps.Patterns(), []string{"a*"})
+	assert.Equal(t, ps.NumSubscribers("a"), 2)
+	assert.Equal(t, ps.NumSubscribers("c"), 0)
+	assert.Equal(t, ps.NumPSubscribers("a*"), 1)
+
+	ps.UnsubscribeAll(c2)
+	assert.Equal(t, ps.Patterns(), []string{})
+	assert.Equal(t, ps.NumSubscribers("a"), 1)
+}
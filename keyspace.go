@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyspacePrefix is the prefix of the topics of the keyspace notifications, like
+// the keyspace notifications of Redis. A notification of name is published to
+// KeyspacePrefix + event + "/" + name, e.g. "$SYS/keyspace/subscribe/orders".
+const KeyspacePrefix = "$SYS/keyspace/"
+
+// KeyspaceEvent is a set of events of the keyspace notifications.
+type KeyspaceEvent uint8
+
+const (
+	// KeyspaceSubscribe is a channel subscribed to a name, by name or to a queue
+	// group.
+	KeyspaceSubscribe KeyspaceEvent = 1 << iota
+	// KeyspaceUnsubscribe is a channel unsubscribed from a name.
+	KeyspaceUnsubscribe
+	// KeyspacePublish is a message published to a name.
+	KeyspacePublish
+	// KeyspaceDrop is a message of a name dropped for a channel.
+	KeyspaceDrop
+
+	KeyspaceAll = KeyspaceSubscribe | KeyspaceUnsubscribe | KeyspacePublish | KeyspaceDrop
+)
+
+func (e KeyspaceEvent) String() string {
+	switch e {
+	case KeyspaceSubscribe:
+		return "subscribe"
+	case KeyspaceUnsubscribe:
+		return "unsubscribe"
+	case KeyspacePublish:
+		return "publish"
+	case KeyspaceDrop:
+		return "drop"
+	}
+	return "unknown"
+}
+
+// Keyspace is the message of a keyspace notification.
+type Keyspace struct {
+	Event   KeyspaceEvent
+	Time    time.Time
+	Name    string
+	Channel chan Event // the channel, except for KeyspacePublish
+	ID      string     // the ID of the message, for KeyspacePublish and KeyspaceDrop
+}
+
+// WithKeyspaceEvents publishes the keyspace notifications of events, e.g.
+// KeyspaceSubscribe|KeyspaceUnsubscribe, so they can be observed by subscribing to
+// their topics, e.g. with PSubscribe(KeyspacePrefix+"drop/*", c). Notifications
+// are published in order, from a goroutine, and dropped like the Notices while
+// their queue is full. The names with the $SYS/ prefix have no notifications.
+func WithKeyspaceEvents(events KeyspaceEvent) Option {
+	return func(p *Pubsub) {
+		p.keyspace.events = events
+	}
+}
+
+type keyspace struct {
+	events KeyspaceEvent
+
+	mu      sync.Mutex
+	queue   []Keyspace
+	running bool
+	dropped atomic.Uint64
+}
+
+// notifyKeyspace queues the keyspace notification of event of name, if enabled.
+func (p *Pubsub) notifyKeyspace(event KeyspaceEvent, name string, c chan Event, id string) {
+	q := &p.keyspace
+	if q.events&event == 0 || p.system(name) {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) >= systemQueueSize {
+		q.dropped.Add(1)
+		return
+	}
+	q.queue = append(q.queue, Keyspace{Event: event, Time: time.Now(), Name: name, Channel: c, ID: id})
+	if !q.running {
+		q.running = true
+		go p.publishKeyspace()
+	}
+}
+
+func (p *Pubsub) publishKeyspace() {
+	q := &p.keyspace
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		k := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+
+		p.Publish(KeyspacePrefix+k.Event.String()+"/"+k.Name, k)
+	}
+}
@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func receiveKeyspace(t *testing.T, c chan Event) (string, Keyspace) {
+	t.Helper()
+	select {
+	case e := <-c:
+		return e.Name, e.Message.(Keyspace)
+	case <-time.After(time.Second):
+		t.Fatal("no keyspace notification")
+	}
+	return "", Keyspace{}
+}
+
+func TestKeyspaceEvents(t *testing.T) {
+	ps := New(-1, WithKeyspaceEvents(KeyspaceAll), WithIDGenerator(UUIDv7()))
+	events := make(chan Event, 16)
+	ps.PSubscribe(KeyspacePrefix+"*/orders", events)
+
+	c := make(chan Event)
+	ps.Subscribe("orders", c)
+	name, k := receiveKeyspace(t, events)
+	assert.Equal(t, name, "$SYS/keyspace/subscribe/orders")
+	assert.Equal(t, k.Event, KeyspaceSubscribe)
+	assert.Equal(t, k.Name, "orders")
+	assert.Equal(t, k.Channel, c)
+
+	ps.Publish("orders", 1)
+	name, k = receiveKeyspace(t, events)
+	assert.Equal(t, name, "$SYS/keyspace/publish/orders")
+	id := k.ID
+	assert.Equal(t, id != "", true)
+	name, k = receiveKeyspace(t, events)
+	assert.Equal(t, name, "$SYS/keyspace/drop/orders")
+	assert.Equal(t, k.ID, id)
+	assert.Equal(t, k.Channel, c)
+
+	ps.Unsubscribe("orders", c)
+	name, k = receiveKeyspace(t, events)
+	assert.Equal(t, name, "$SYS/keyspace/unsubscribe/orders")
+	assert.Equal(t, k.Channel, c)
+
+	ps.QueueSubscribe("orders", "g", c)
+	_, k = receiveKeyspace(t, events)
+	assert.Equal(t, k.Event, KeyspaceSubscribe)
+}
+
+func TestKeyspaceEventsSelected(t *testing.T) {
+	ps := New(-1, WithKeyspaceEvents(KeyspaceUnsubscribe))
+	events := make(chan Event, 16)
+	ps.PSubscribe(KeyspacePrefix+"*/*", events)
+
+	c := make(chan Event, 1)
+	ps.Subscribe("a", c)
+	ps.Publish("a", 1)
+	ps.Unsubscribe("a", c)
+	_, k := receiveKeyspace(t, events)
+	assert.Equal(t, k.Event, KeyspaceUnsubscribe)
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected notification %v", e.Name)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestKeyspaceEventsNormalized(t *testing.T) {
+	ps := New(-1, WithKeyspaceEvents(KeyspacePublish), WithTopicNormalizer(CaseInsensitive))
+	events := make(chan Event, 16)
+	ps.PSubscribe(KeyspacePrefix+"*/*", events)
+
+	ps.Publish("Orders", 1)
+	name, k := receiveKeyspace(t, events)
+	assert.Equal(t, name, "$sys/keyspace/publish/orders")
+	assert.Equal(t, k.Name, "orders")
+	// the notifications aren't notified themselves
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected notification %v", e.Name)
+	case <-time.After(10 * time.Millisecond):
+	}
+	assert.Equal(t, ps.Health().Published, uint64(2))
+}
@@ -0,0 +1,223 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Error of subscribing to a topic which rejects late subscribers after its first
+// message.
+var ErrLateSubscriber = errors.New("topic rejects late subscribers")
+
+// Error of subscribing with a LatePolicy the topic doesn't support.
+var ErrUnsupportedPolicy = errors.New("late policy isn't supported by the topic")
+
+// LatePolicy is what a subscriber joining a topic after messages were published
+// to it receives.
+type LatePolicy struct {
+	mode  lateMode
+	n     int
+	since time.Time
+}
+
+type lateMode int
+
+const (
+	lateLive lateMode = iota
+	lateRetained
+	lateReplay
+	lateReject
+)
+
+var (
+	// LiveOnly subscribers only receive the messages published after they
+	// subscribed, like Subscribe.
+	LiveOnly = LatePolicy{mode: lateLive}
+	// BackfillRetained subscribers receive the retained message first, like
+	// SubscribeRetained.
+	BackfillRetained = LatePolicy{mode: lateRetained}
+	// RejectLate topics reject subscribers after the first message was published
+	// with ErrLateSubscriber. It can only be declared WithLatePolicy.
+	RejectLate = LatePolicy{mode: lateReject}
+)
+
+// BackfillLast subscribers receive the last n kept messages first, like Replay.
+// The Pubsub must be created WithHistory.
+func BackfillLast(n int) LatePolicy {
+	return LatePolicy{mode: lateReplay, n: n}
+}
+
+// BackfillSince subscribers receive the kept messages published after since first,
+// like ReplaySince. The Pubsub must be created WithHistory.
+func BackfillSince(since time.Time) LatePolicy {
+	return LatePolicy{mode: lateReplay, since: since}
+}
+
+// allows returns whether a topic declared with policy supports subscribers with
+// the policy requested.
+func (policy LatePolicy) allows(requested LatePolicy) bool {
+	switch requested.mode {
+	case lateLive:
+		return true
+	case lateRetained:
+		return policy.mode == lateRetained || policy.mode == lateReplay
+	case lateReplay:
+		return policy.mode == lateReplay && (policy.n <= 0 || requested.n <= policy.n)
+	}
+	return false
+}
+
+// WithLatePolicy declares the LatePolicy of the topics matching pattern, matched by
+// the Matcher of the Pubsub, which Subscribe applies. SubscribeLate can only
+// request what the policy allows: LiveOnly always, BackfillRetained if the topic
+// backfills, and BackfillLast with at most as many messages as the topic. The
+// first matching declaration wins.
+func WithLatePolicy(pattern string, policy LatePolicy) Option {
+	return func(p *Pubsub) {
+		if p.late == nil {
+			p.late = &latePolicies{}
+		}
+		p.late.rules = append(p.late.rules, lateRule{pattern, policy})
+	}
+}
+
+type lateRule struct {
+	pattern string
+	policy  LatePolicy
+}
+
+type latePolicies struct {
+	rules []lateRule
+	cache sync.Map // name -> *lateTopic, nil if not declared
+}
+
+type lateTopic struct {
+	policy    LatePolicy
+	started   atomic.Bool // whether a message was published
+	forgotten atomic.Bool // dropped from the cache by CompactTopics
+}
+
+// lateTopic returns the declared late policy of name, or nil.
+func (p *Pubsub) lateTopic(name string) *lateTopic {
+	if p.late == nil {
+		return nil
+	}
+	if v, ok := p.late.cache.Load(name); ok {
+		return v.(*lateTopic)
+	}
+	var topic *lateTopic
+	for _, rule := range p.late.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			topic = &lateTopic{policy: rule.policy}
+			break
+		}
+	}
+	v, _ := p.late.cache.LoadOrStore(name, topic)
+	return v.(*lateTopic)
+}
+
+// SubscribeLate subscribes channel c to name like Subscribe, and backfills c
+// according to policy. It returns ErrUnsupportedPolicy if the declared policy of
+// name doesn't allow policy, or if policy needs a history the Pubsub doesn't keep.
+func (p *Pubsub) SubscribeLate(name string, c chan Event, policy LatePolicy) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+	if policy.mode == lateReject {
+		return ErrUnsupportedPolicy
+	}
+	topic := p.lateTopic(name)
+	if topic != nil && topic.policy.mode != lateReject {
+		if policy.mode == lateReplay && policy.n <= 0 {
+			// at most as many messages as the topic backfills
+			policy.n = topic.policy.n
+		}
+		if !topic.policy.allows(policy) {
+			return ErrUnsupportedPolicy
+		}
+	}
+	if policy.mode == lateReplay && p.history == nil {
+		return ErrUnsupportedPolicy
+	}
+
+	p.locker.Lock()
+	if topic != nil && topic.policy.mode == lateReject && topic.started.Load() {
+		p.locker.Unlock()
+		return ErrLateSubscriber
+	}
+	backfill, err := p.subscribeLate(name, c, policy)
+	p.locker.Unlock()
+	backfill()
+	return err
+}
+
+// subscribeLate subscribes c with policy, with the write lock held, and returns
+// the function backfilling c, to call once the lock is released.
+func (p *Pubsub) subscribeLate(name string, c chan Event, policy LatePolicy) (func(), error) {
+	switch policy.mode {
+	case lateRetained:
+		return p.subscribeRetained(name, c)
+	case lateReplay:
+		return p.replayLocked(name, c, policy.n, policy.since)
+	}
+	_, err := p.add(byName, name, c)
+	return noop, err
+}
+
+// subscribeDeclared subscribes c to name with the declared late policy of name. It
+// returns false if name has no declared policy.
+func (p *Pubsub) subscribeDeclared(name string, c chan Event) (bool, error) {
+	topic := p.lateTopic(name)
+	if topic == nil {
+		return false, nil
+	}
+
+	policy := topic.policy
+	p.locker.Lock()
+	if policy.mode == lateReject {
+		if topic.started.Load() {
+			p.locker.Unlock()
+			return true, ErrLateSubscriber
+		}
+		policy = LiveOnly
+	}
+	backfill, err := p.subscribeLate(name, c, policy)
+	p.locker.Unlock()
+	backfill()
+	return true, err
+}
+
+// started marks a topic which rejects late subscribers as started.
+func (p *Pubsub) started(name string) {
+	for {
+		topic := p.lateTopic(name)
+		if topic == nil || topic.policy.mode != lateReject {
+			return
+		}
+		topic.started.Store(true)
+		if !topic.forgotten.Load() {
+			return
+		}
+		// compacted meanwhile, the topic is looked up again
+	}
+}
+
+// forgetLate drops the cached late policy of name, unless it rejects late
+// subscribers since a message was published.
+func (p *Pubsub) forgetLate(name string) {
+	v, ok := p.late.cache.Load(name)
+	if !ok {
+		return
+	}
+	if topic := v.(*lateTopic); topic != nil {
+		topic.forgotten.Store(true)
+		if topic.started.Load() {
+			topic.forgotten.Store(false)
+			return
+		}
+	}
+	p.late.cache.Delete(name)
+}
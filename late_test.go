@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestLatePolicyDeclared(t *testing.T) {
+	ps := New(-1, WithHistory(10),
+		WithLatePolicy("ticks", BackfillLast(2)),
+		WithLatePolicy("config", BackfillRetained),
+		WithLatePolicy("game", RejectLate),
+	)
+	for i := 0; i < 3; i++ {
+		ps.Publish("ticks", i)
+	}
+	ticks := make(chan Event, 5)
+	assert.Equal(t, ps.Subscribe("ticks", ticks), nil)
+	assert.Equal(t, len(ticks), 2)
+	assert.Equal(t, (<-ticks).Message, 1)
+
+	ps.PublishRetain("config", "v1")
+	config := make(chan Event, 1)
+	assert.Equal(t, ps.Subscribe("config", config), nil)
+	assert.Equal(t, (<-config).Message, "v1")
+
+	early := make(chan Event, 1)
+	assert.Equal(t, ps.Subscribe("game", early), nil)
+	ps.Publish("game", "start")
+	assert.Equal(t, ps.Subscribe("game", make(chan Event)), ErrLateSubscriber)
+	assert.Equal(t, ps.SubscribeLate("game", make(chan Event), LiveOnly), ErrLateSubscriber)
+}
+
+func TestSubscribeLate(t *testing.T) {
+	ps := New(-1, WithHistory(10), WithLatePolicy("ticks", BackfillLast(2)), WithLatePolicy("live", LiveOnly))
+	for i := 0; i < 3; i++ {
+		ps.Publish("ticks", i)
+		ps.Publish("live", i)
+		ps.Publish("other", i)
+	}
+
+	c := make(chan Event, 5)
+	assert.Equal(t, ps.SubscribeLate("ticks", c, BackfillLast(3)), ErrUnsupportedPolicy)
+	assert.Equal(t, ps.SubscribeLate("ticks", c, BackfillLast(1)), nil)
+	assert.Equal(t, len(c), 1)
+	ps.UnsubscribeAll(c)
+	<-c
+
+	assert.Equal(t, ps.SubscribeLate("ticks", c, BackfillSince(time.Time{})), nil)
+	assert.Equal(t, len(c), 2)
+	ps.UnsubscribeAll(c)
+	<-c
+	<-c
+
+	assert.Equal(t, ps.SubscribeLate("live", c, BackfillRetained), ErrUnsupportedPolicy)
+	assert.Equal(t, ps.SubscribeLate("live", c, LiveOnly), nil)
+	assert.Equal(t, ps.SubscribeLate("other", c, BackfillLast(5)), nil)
+	assert.Equal(t, len(c), 3)
+	assert.Equal(t, ps.SubscribeLate("other", c, RejectLate), ErrUnsupportedPolicy)
+
+	assert.Equal(t, New(-1).SubscribeLate("other", c, BackfillLast(1)), ErrUnsupportedPolicy)
+}
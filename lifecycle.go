@@ -0,0 +1,104 @@
+package pubsub
+
+import "sync"
+
+// OnTopicCreated calls fn when a name gets its first subscriber by name or member
+// of a queue group.
+func OnTopicCreated(fn func(name string)) Option {
+	return onLifecycle(byName, true, fn)
+}
+
+// OnTopicDeleted calls fn when a name loses its last subscriber by name and member
+// of a queue group.
+func OnTopicDeleted(fn func(name string)) Option {
+	return onLifecycle(byName, false, fn)
+}
+
+// OnPatternCreated calls fn when a pattern of PSubscribe or a filter of HSubscribe
+// gets its first subscriber.
+func OnPatternCreated(fn func(pattern string)) Option {
+	return onLifecycle(byPattern, true, fn)
+}
+
+// OnPatternDeleted calls fn when a pattern of PSubscribe or a filter of HSubscribe
+// loses its last subscriber.
+func OnPatternDeleted(fn func(pattern string)) Option {
+	return onLifecycle(byPattern, false, fn)
+}
+
+func onLifecycle(k kind, created bool, fn func(string)) Option {
+	return func(p *Pubsub) {
+		if p.lifecycle == nil {
+			p.lifecycle = &lifecycle{p: p}
+		}
+		p.lifecycle.hooks = append(p.lifecycle.hooks, lifecycleHook{k, created, fn})
+	}
+}
+
+// lifecycle calls the hooks in the order of the changes, from a goroutine, so they
+// are never called with a lock held and may subscribe or publish.
+type lifecycle struct {
+	p     *Pubsub
+	hooks []lifecycleHook
+
+	mu      sync.Mutex
+	queue   []lifecycleEvent
+	running bool
+}
+
+type lifecycleHook struct {
+	k       kind
+	created bool
+	fn      func(string)
+}
+
+type lifecycleEvent struct {
+	k       kind
+	created bool
+	name    string
+}
+
+// emit queues the change of name for the hooks.
+func (p *Pubsub) emit(k kind, created bool, name string) {
+	l := p.lifecycle
+	if l == nil {
+		return
+	}
+	if k == byFilter {
+		k = byPattern
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queue = append(l.queue, lifecycleEvent{k, created, name})
+	if !l.running {
+		l.running = true
+		go l.run()
+	}
+}
+
+// call calls a hook with name, reporting its panic as a NoticePanic.
+func (l *lifecycle) call(fn func(string), name string) {
+	defer l.p.isolate(Event{Name: name})
+	fn(name)
+}
+
+func (l *lifecycle) run() {
+	for {
+		l.mu.Lock()
+		if len(l.queue) == 0 {
+			l.running = false
+			l.mu.Unlock()
+			return
+		}
+		event := l.queue[0]
+		l.queue = l.queue[1:]
+		l.mu.Unlock()
+
+		for _, hook := range l.hooks {
+			if hook.k == event.k && hook.created == event.created {
+				l.call(hook.fn, event.name)
+			}
+		}
+	}
+}
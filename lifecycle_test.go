@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestLifecycle(t *testing.T) {
+	events := make(chan string, 10)
+	record := func(prefix string) func(string) {
+		return func(name string) {
+			events <- prefix + name
+		}
+	}
+	var ps *Pubsub
+	ps = New(-1,
+		OnTopicCreated(record("+")),
+		OnTopicDeleted(record("-")),
+		OnPatternCreated(record("+p ")),
+		OnPatternDeleted(func(pattern string) {
+			// hooks may use the Pubsub
+			ps.Publish("deleted", pattern)
+			events <- "-p " + pattern
+		}),
+	)
+
+	c := make(chan Event, 1)
+	d := make(chan Event, 1)
+	ps.Subscribe("a", c)
+	ps.Subscribe("a", d)
+	ps.PSubscribe("b*", c)
+	ps.HSubscribe("c/#", c)
+	ps.Unsubscribe("a", c)
+	ps.UnsubscribeAll(c)
+	ps.Unsubscribe("a", d)
+
+	var got []string
+	for len(got) < 6 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v", got)
+		}
+	}
+	assert.Equal(t, got[:3], []string{"+a", "+p b*", "+p c/#"})
+	assert.Equal(t, got[3:5], []string{"-p b*", "-p c/#"})
+	assert.Equal(t, got[5:], []string{"-a"})
+}
+
+func TestLifecycleQueue(t *testing.T) {
+	events := make(chan string, 10)
+	ps := New(-1,
+		OnTopicCreated(func(name string) { events <- "+" + name }),
+		OnTopicDeleted(func(name string) { events <- "-" + name }),
+	)
+
+	c, d := make(chan Event), make(chan Event)
+	ps.QueueSubscribe("a", "workers", c)
+	ps.QueueSubscribe("a", "others", d)
+	ps.Subscribe("a", d)
+	ps.QueueUnsubscribe("a", "workers", c)
+	ps.QueueUnsubscribe("a", "others", d)
+	ps.Unsubscribe("a", d)
+	ps.QueueSubscribe("b", "workers", c)
+	ps.UnsubscribeAll(c)
+
+	var got []string
+	for len(got) < 4 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v", got)
+		}
+	}
+	assert.Equal(t, got, []string{"+a", "-a", "+b", "-b"})
+}
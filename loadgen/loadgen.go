@@ -0,0 +1,246 @@
+// Package loadgen generates publish workloads against a Pubsub and reports the
+// throughput, latency and drops, to plan capacity and to detect regressions.
+//
+//	report, err := loadgen.Run(ctx, pubsub.New(-1), loadgen.Config{
+//		Topics:      100,
+//		Subscribers: 4,
+//		Publishers:  8,
+//		Rate:        100000,
+//		Duration:    10 * time.Second,
+//	})
+//	fmt.Println(report)
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// Error of running a workload with a negative setting.
+var ErrInvalidConfig = errors.New("invalid load config")
+
+// Config describes a workload. Zero values are replaced by the defaults.
+type Config struct {
+	Prefix      string        // prefix of the topics, "load" by default
+	Topics      int           // number of topics Prefix/0 to Prefix/n-1, 1 by default
+	Subscribers int           // channels subscribed to every topic, 1 by default
+	Patterns    int           // channels subscribed to the pattern Prefix/*
+	Buffer      int           // buffer of the subscribed channels, 64 by default
+	Publishers  int           // concurrent publishers, 1 by default
+	Rate        int           // messages per second of all publishers, 0 is unlimited
+	PayloadSize int           // bytes of every message
+	Messages    int           // messages to publish, 0 is unlimited
+	Duration    time.Duration // duration of the workload, 1s by default without Messages
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if c.Topics < 0 || c.Subscribers < 0 || c.Patterns < 0 || c.Buffer < 0 ||
+		c.Publishers < 0 || c.Rate < 0 || c.PayloadSize < 0 || c.Messages < 0 || c.Duration < 0 {
+		return c, ErrInvalidConfig
+	}
+	if c.Prefix == "" {
+		c.Prefix = "load"
+	}
+	if c.Topics == 0 {
+		c.Topics = 1
+	}
+	if c.Subscribers == 0 {
+		c.Subscribers = 1
+	}
+	if c.Buffer == 0 {
+		c.Buffer = 64
+	}
+	if c.Publishers == 0 {
+		c.Publishers = 1
+	}
+	if c.Messages == 0 && c.Duration == 0 {
+		c.Duration = time.Second
+	}
+	return c, nil
+}
+
+// Payload is the message published by the workload.
+type Payload struct {
+	Sent time.Time
+	Data []byte
+}
+
+// Report is the outcome of a workload.
+type Report struct {
+	Published  uint64        // messages published
+	Delivered  uint64        // messages delivered to the subscribed channels
+	Dropped    uint64        // messages dropped because a channel was full
+	Elapsed    time.Duration // duration of publishing
+	Throughput float64       // messages published per second
+	Latency    Latency       // from Publish to the receiving subscriber
+}
+
+// Latency summarizes the latencies of the delivered messages.
+type Latency struct {
+	Min, Mean, P50, P99, Max time.Duration
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("published %d (%.0f/s), delivered %d, dropped %d in %v, latency min %v mean %v p50 %v p99 %v max %v",
+		r.Published, r.Throughput, r.Delivered, r.Dropped, r.Elapsed,
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P99, r.Latency.Max)
+}
+
+// Run runs the workload described by c against ps until the messages are published,
+// the duration elapsed or ctx is done. The subscribed channels are unsubscribed
+// before Run returns.
+func Run(ctx context.Context, ps *pubsub.Pubsub, c Config) (Report, error) {
+	c, err := c.withDefaults()
+	if err != nil {
+		return Report{}, err
+	}
+	if c.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Duration)
+		defer cancel()
+	}
+
+	topics := make([]string, c.Topics)
+	for i := range topics {
+		topics[i] = c.Prefix + "/" + strconv.Itoa(i)
+	}
+
+	var channels []chan pubsub.Event
+	defer func() {
+		for _, ch := range channels {
+			ps.UnsubscribeAll(ch)
+		}
+	}()
+	for _, topic := range topics {
+		for i := 0; i < c.Subscribers; i++ {
+			ch := make(chan pubsub.Event, c.Buffer)
+			channels = append(channels, ch)
+			if err := ps.Subscribe(topic, ch); err != nil {
+				return Report{}, err
+			}
+		}
+	}
+	for i := 0; i < c.Patterns; i++ {
+		ch := make(chan pubsub.Event, c.Buffer)
+		channels = append(channels, ch)
+		if err := ps.PSubscribe(c.Prefix+"/*", ch); err != nil {
+			return Report{}, err
+		}
+	}
+
+	// receive
+	var (
+		received  sync.WaitGroup
+		stop      = make(chan struct{})
+		latencies = make([][]time.Duration, len(channels))
+	)
+	for i, ch := range channels {
+		received.Add(1)
+		go func(i int, ch chan pubsub.Event) {
+			defer received.Done()
+			receive := func(event pubsub.Event) {
+				latencies[i] = append(latencies[i], time.Since(event.Message.(Payload).Sent))
+			}
+			for {
+				select {
+				case event := <-ch:
+					receive(event)
+				case <-stop:
+					for len(ch) > 0 {
+						receive(<-ch)
+					}
+					return
+				}
+			}
+		}(i, ch)
+	}
+
+	// publish
+	var (
+		reserved  atomic.Int64 // messages started, with Messages
+		published atomic.Uint64
+		delivered atomic.Uint64
+		dropped   atomic.Uint64
+		wg        sync.WaitGroup
+		data      = make([]byte, c.PayloadSize)
+		interval  time.Duration
+	)
+	if c.Rate > 0 {
+		interval = time.Second * time.Duration(c.Publishers) / time.Duration(c.Rate)
+	}
+	start := time.Now()
+	for i := 0; i < c.Publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				if c.Messages > 0 && reserved.Add(1) > int64(c.Messages) {
+					return
+				}
+				if interval > 0 {
+					// pace to the rate, without accumulating the sleep errors
+					if wait := time.Until(start.Add(time.Duration(n) * interval)); wait > 0 {
+						select {
+						case <-time.After(wait):
+						case <-ctx.Done():
+						}
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				topic := topics[(n*c.Publishers+i)%len(topics)]
+				result := ps.Publish(topic, Payload{Sent: time.Now(), Data: data})
+				published.Add(1)
+				delivered.Add(uint64(result.Delivered))
+				dropped.Add(uint64(result.Dropped))
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(stop)
+	received.Wait()
+
+	report := Report{
+		Published: published.Load(),
+		Delivered: delivered.Load(),
+		Dropped:   dropped.Load(),
+		Elapsed:   elapsed,
+		Latency:   summarize(latencies),
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Published) / elapsed.Seconds()
+	}
+	return report, nil
+}
+
+func summarize(latencies [][]time.Duration) Latency {
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	if len(all) == 0 {
+		return Latency{}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	var sum time.Duration
+	for _, l := range all {
+		sum += l
+	}
+	return Latency{
+		Min:  all[0],
+		Mean: sum / time.Duration(len(all)),
+		P50:  all[len(all)/2],
+		P99:  all[len(all)*99/100],
+		Max:  all[len(all)-1],
+	}
+}
@@ -0,0 +1,52 @@
+package loadgen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestRunMessages(t *testing.T) {
+	ps := pubsub.New(-1)
+	report, err := Run(context.Background(), ps, Config{
+		Topics:      4,
+		Subscribers: 2,
+		Patterns:    1,
+		Buffer:      1000,
+		Publishers:  3,
+		PayloadSize: 16,
+		Messages:    100,
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, report.Published, uint64(100))
+	assert.Equal(t, report.Delivered, uint64(300))
+	assert.Equal(t, report.Dropped, uint64(0))
+	assert.Equal(t, report.Latency.Min <= report.Latency.P50, true)
+	assert.Equal(t, report.Latency.P99 <= report.Latency.Max, true)
+	assert.Equal(t, ps.Topics(), []string{})
+}
+
+func TestRunRate(t *testing.T) {
+	report, err := Run(context.Background(), pubsub.New(-1), Config{
+		Publishers: 2,
+		Rate:       1000,
+		Duration:   100 * time.Millisecond,
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, report.Published > 50 && report.Published <= 110, true)
+}
+
+func TestRunInvalid(t *testing.T) {
+	_, err := Run(context.Background(), pubsub.New(-1), Config{Topics: -1})
+	assert.Equal(t, err, ErrInvalidConfig)
+}
+
+func TestRunMaxSubscribe(t *testing.T) {
+	ps := pubsub.New(1)
+	_, err := Run(context.Background(), ps, Config{Topics: 2, Subscribers: 2})
+	assert.Equal(t, err, pubsub.ErrMaxSubscribe)
+	assert.Equal(t, ps.Topics(), []string{})
+}
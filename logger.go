@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// WithLogger logs with l the subscriptions added and removed and the dropped
+// messages at the debug level, and the Notices, e.g. slow subscribers, malformed
+// patterns and errors of the bridges, at the warn level. Records have the topic,
+// and the subscriber as the address of its channel. The names with the $SYS/
+// prefix aren't logged. Nothing is logged without WithLogger.
+func WithLogger(l *slog.Logger) Option {
+	return func(p *Pubsub) {
+		p.logger = l
+	}
+}
+
+// logSubscription logs the subscription of c to name, of kind k, being added or
+// removed.
+func (p *Pubsub) logSubscription(msg string, k RouteKind, name, group string, c chan Event) {
+	if !p.logs(slog.LevelDebug, name) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("topic", name),
+		slog.String("kind", k.String()),
+		subscriberAttr(c),
+	}
+	if group != "" {
+		attrs = append(attrs, slog.String("group", group))
+	}
+	p.logger.LogAttrs(context.Background(), slog.LevelDebug, msg, attrs...)
+}
+
+// logDrop logs event dropped for c, subscribed to pattern if any.
+func (p *Pubsub) logDrop(c chan Event, event Event, pattern string) {
+	if !p.logs(slog.LevelDebug, event.Name) {
+		return
+	}
+	attrs := []slog.Attr{slog.String("topic", event.Name), subscriberAttr(c)}
+	if pattern != "" {
+		attrs = append(attrs, slog.String("pattern", pattern))
+	}
+	if event.ID != "" {
+		attrs = append(attrs, slog.String("id", event.ID))
+	}
+	p.logger.LogAttrs(context.Background(), slog.LevelDebug, "pubsub drop", attrs...)
+}
+
+// logNotice logs n.
+func (p *Pubsub) logNotice(n Notice) {
+	if !p.logs(slog.LevelWarn, n.Name) {
+		return
+	}
+	attrs := []slog.Attr{slog.String("kind", n.Kind.String())}
+	if n.Name != "" {
+		attrs = append(attrs, slog.String("topic", n.Name))
+	}
+	if n.Channel != nil {
+		attrs = append(attrs, subscriberAttr(n.Channel))
+	}
+	if n.Drops != 0 {
+		attrs = append(attrs, slog.Int("drops", n.Drops))
+	}
+	if n.Err != nil {
+		attrs = append(attrs, slog.String("error", n.Err.Error()))
+	}
+	p.logger.LogAttrs(context.Background(), slog.LevelWarn, "pubsub notice", attrs...)
+}
+
+// logs returns whether the records of name are logged at level.
+func (p *Pubsub) logs(level slog.Level, name string) bool {
+	return p.logger != nil && !p.system(name) && p.logger.Enabled(context.Background(), level)
+}
+
+func subscriberAttr(c chan Event) slog.Attr {
+	return slog.String("subscriber", fmt.Sprintf("%p", c))
+}
@@ -0,0 +1,80 @@
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+// logRecords is a slog.Handler keeping the records.
+type logRecords struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (h *logRecords) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *logRecords) Handle(_ context.Context, r slog.Record) error {
+	s := r.Level.String() + " " + r.Message
+	r.Attrs(func(a slog.Attr) bool {
+		s += " " + a.String()
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, s)
+	return nil
+}
+
+func (h *logRecords) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *logRecords) WithGroup(string) slog.Handler      { return h }
+
+func (h *logRecords) get() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.records...)
+}
+
+func TestWithLogger(t *testing.T) {
+	h := &logRecords{}
+	ps := New(-1, WithLogger(slog.New(h)))
+	c := make(chan Event)
+	sub := fmt.Sprintf("%p", c)
+
+	ps.Subscribe("orders", c)
+	ps.QueueSubscribe("jobs", "workers", c)
+	ps.Publish("orders", 1)
+	ps.Unsubscribe("orders", c)
+	_, stop, err := ps.Events() // $SYS/ names aren't logged
+	assert.Equal(t, err, nil)
+	stop()
+	assert.Equal(t, h.get(), []string{
+		"DEBUG pubsub subscribe topic=orders kind=name subscriber=" + sub,
+		"DEBUG pubsub subscribe topic=jobs kind=queue subscriber=" + sub + " group=workers",
+		"DEBUG pubsub drop topic=orders subscriber=" + sub,
+		"DEBUG pubsub unsubscribe topic=orders kind=name subscriber=" + sub,
+	})
+
+	ps.Notify(Notice{Kind: NoticeBridgeError, Err: errors.New("down")})
+	deadline := time.Now().Add(time.Second)
+	for len(h.get()) < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, h.get()[4:], []string{"WARN pubsub notice kind=bridge error error=down"})
+}
+
+func TestWithLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	ps := New(-1, WithLogger(l))
+	ps.Subscribe("orders", make(chan Event))
+	ps.Publish("orders", 1)
+	assert.Equal(t, buf.String(), "")
+}
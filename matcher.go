@@ -0,0 +1,158 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Matcher matches published topics against subscribed patterns.
+type Matcher interface {
+	Match(pattern, topic string) bool
+}
+
+// Error of subscribing to a pattern the Matcher can't compile.
+var ErrBadPattern = errors.New("malformed pattern")
+
+// PatternValidator is implemented by the Matchers which can tell malformed patterns,
+// which PSubscribe refuses with ErrBadPattern.
+type PatternValidator interface {
+	Validate(pattern string) error
+}
+
+// validatePattern returns an error wrapping ErrBadPattern and the error of the
+// Matcher if pattern is malformed, and reports it as a NoticeBadPattern.
+func (p *Pubsub) validatePattern(pattern string) error {
+	v, ok := p.matcher.(PatternValidator)
+	if !ok {
+		return nil
+	}
+	if err := v.Validate(pattern); err != nil {
+		err = fmt.Errorf("pubsub: %w %q: %w", ErrBadPattern, pattern, err)
+		p.Notify(Notice{Kind: NoticeBadPattern, Name: pattern, Err: err})
+		return err
+	}
+	return nil
+}
+
+// MatcherFunc is an adapter to allow the use of ordinary functions as Matcher.
+type MatcherFunc func(pattern, topic string) bool
+
+// Match calls f(pattern, topic).
+func (f MatcherFunc) Match(pattern, topic string) bool {
+	return f(pattern, topic)
+}
+
+// WithMatcher matches patterns of PSubscribe with m instead of GlobMatcher.
+func WithMatcher(m Matcher) Option {
+	return func(p *Pubsub) {
+		p.matcher = m
+	}
+}
+
+// GlobMatcher matches with the glob-style patterns of filepath.Match, which is the
+// default. Note that * and ? don't match the path separator.
+type GlobMatcher struct{}
+
+func (GlobMatcher) Match(pattern, topic string) bool {
+	ok, err := filepath.Match(pattern, topic)
+	return err == nil && ok
+}
+
+// Validate returns filepath.ErrBadPattern if pattern is malformed.
+func (GlobMatcher) Validate(pattern string) error {
+	_, err := filepath.Match(pattern, "")
+	return err
+}
+
+// PrefixMatcher matches topics which start with the pattern.
+type PrefixMatcher struct{}
+
+func (PrefixMatcher) Match(pattern, topic string) bool {
+	return strings.HasPrefix(topic, pattern)
+}
+
+// MQTTMatcher matches with MQTT topic filters, where topic levels are separated by
+// /, + matches exactly one level and a trailing # matches any number of levels,
+// including the parent level. Wildcards at the first level don't match topics
+// starting with $.
+type MQTTMatcher struct{}
+
+func (MQTTMatcher) Match(pattern, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(pattern, "+") || strings.HasPrefix(pattern, "#")) {
+		return false
+	}
+	for {
+		level, rest, more := strings.Cut(pattern, "/")
+		if level == "#" {
+			return !more
+		}
+		tlevel, trest, tmore := strings.Cut(topic, "/")
+		if level != "+" && level != tlevel {
+			return false
+		}
+		if !more || !tmore {
+			if more && !tmore {
+				// a/# matches a
+				return rest == "#"
+			}
+			return more == tmore
+		}
+		pattern, topic = rest, trest
+	}
+}
+
+// Validate returns an error if a wildcard isn't a whole level, or # isn't the last
+// level.
+func (MQTTMatcher) Validate(pattern string) error {
+	for {
+		level, rest, more := strings.Cut(pattern, "/")
+		if level != "+" && level != "#" && strings.ContainsAny(level, "+#") {
+			return fmt.Errorf("wildcard in level %q", level)
+		}
+		if level == "#" && more {
+			return errors.New("# isn't the last level")
+		}
+		if !more {
+			return nil
+		}
+		pattern = rest
+	}
+}
+
+// RegexpMatcher matches with regular expressions which must match the whole topic.
+// Compiled expressions are cached, invalid expressions never match.
+type RegexpMatcher struct {
+	cache sync.Map // pattern -> *regexp.Regexp, nil if invalid
+}
+
+// NewRegexpMatcher returns a new RegexpMatcher.
+func NewRegexpMatcher() *RegexpMatcher {
+	return &RegexpMatcher{}
+}
+
+func (m *RegexpMatcher) Match(pattern, topic string) bool {
+	re := m.compile(pattern)
+	return re != nil && re.MatchString(topic)
+}
+
+// Validate returns the error of compiling pattern.
+func (m *RegexpMatcher) Validate(pattern string) error {
+	_, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	return err
+}
+
+func (m *RegexpMatcher) compile(pattern string) *regexp.Regexp {
+	if v, ok := m.cache.Load(pattern); ok {
+		return v.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		re = nil
+	}
+	m.cache.Store(pattern, re)
+	return re
+}
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		matcher Matcher
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{GlobMatcher{}, "a*", "abc", true},
+		{GlobMatcher{}, "a*", "a/b", false},
+		{GlobMatcher{}, "h[llo", "hllo", false},
+		{PrefixMatcher{}, "user.", "user.created", true},
+		{PrefixMatcher{}, "user.", "users", false},
+		{MQTTMatcher{}, "a/+/c", "a/b/c", true},
+		{MQTTMatcher{}, "a/+/c", "a/b/d", false},
+		{MQTTMatcher{}, "a/+", "a/b/c", false},
+		{MQTTMatcher{}, "a/#", "a/b/c", true},
+		{MQTTMatcher{}, "a/#", "a", true},
+		{MQTTMatcher{}, "a/b", "a", false},
+		{MQTTMatcher{}, "a", "a/b", false},
+		{MQTTMatcher{}, "#", "a/b", true},
+		{MQTTMatcher{}, "#", "$SYS/health", false},
+		{MQTTMatcher{}, "+/health", "$SYS/health", false},
+		{MQTTMatcher{}, "$SYS/#", "$SYS/health", true},
+		{MQTTMatcher{}, "a/+", "a/", true},
+		{NewRegexpMatcher(), `user\.\d+`, "user.42", true},
+		{NewRegexpMatcher(), `user\.\d+`, "user.42.x", false},
+		{NewRegexpMatcher(), `user(`, "user(", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.matcher.Match(test.pattern, test.topic), test.want, test)
+	}
+}
+
+func TestWithMatcher(t *testing.T) {
+	ps := New(-1, WithMatcher(MQTTMatcher{}))
+	c := make(chan Event, 1)
+	ps.PSubscribe("devices/+/telemetry", c)
+	assert.Equal(t, ps.Publish("devices/1/telemetry", 1).Delivered, 1)
+	assert.Equal(t, ps.Publish("devices/1/status", 1).Delivered, 0)
+}
+
+func TestValidatePattern(t *testing.T) {
+	for _, tc := range []struct {
+		matcher Matcher
+		pattern string
+		ok      bool
+	}{
+		{GlobMatcher{}, "h[ae]llo", true},
+		{GlobMatcher{}, "h[llo", false},
+		{MQTTMatcher{}, "a/+/#", true},
+		{MQTTMatcher{}, "a/b+", false},
+		{MQTTMatcher{}, "a/#/b", false},
+		{NewRegexpMatcher(), "a.(b|c)", true},
+		{NewRegexpMatcher(), "a(", false},
+		{PrefixMatcher{}, "[", true},
+	} {
+		ps := New(-1, WithMatcher(tc.matcher))
+		err := ps.PSubscribe(tc.pattern, make(chan Event))
+		assert.Equal(t, errors.Is(err, ErrBadPattern), !tc.ok)
+		assert.Equal(t, len(ps.Patterns()), map[bool]int{true: 1}[tc.ok])
+	}
+
+	ps := New(-1)
+	defer func() {
+		assert.Equal(t, errors.Is(recover().(error), ErrBadPattern), true)
+	}()
+	ps.MustPSubscribe("h[llo", make(chan Event))
+}
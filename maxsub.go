@@ -0,0 +1,133 @@
+package pubsub
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Error of subscribing when the Pubsub has WithMaxSubscriptions subscriptions.
+var ErrMaxSubscriptions = errors.New("subscriptions of the pubsub are maximum")
+
+// Error of subscribing to a new topic when the Pubsub has WithMaxTopics topics.
+var ErrMaxTopics = errors.New("topics are maximum")
+
+// WithMaxSubscriptions limits the subscriptions of the Pubsub, by name, pattern,
+// filter or queue group, to n. No limit if n <= 0.
+func WithMaxSubscriptions(n int) Option {
+	return func(p *Pubsub) {
+		p.maxSubs = n
+	}
+}
+
+// WithMaxTopics limits the names with subscribers, including the $SYS/ names, to n.
+// Queue groups and patterns don't count. No limit if n <= 0.
+func WithMaxTopics(n int) Option {
+	return func(p *Pubsub) {
+		p.maxTopics = n
+	}
+}
+
+// SetMaxFor sets the max subscriptions of the names matching pattern, matched by
+// the Matcher of the Pubsub, instead of the max given to New. No limit if n <= 0.
+// The first matching pattern wins, setting a pattern again replaces its max. It
+// applies to the subscriptions from then on.
+func (p *Pubsub) SetMaxFor(pattern string, n int) {
+	p.maxes.mu.Lock()
+	defer p.maxes.mu.Unlock()
+
+	rules := append([]maxRule(nil), p.maxes.rules...)
+	replaced := false
+	for i := range rules {
+		if rules[i].pattern == pattern {
+			rules[i].max = n
+			replaced = true
+		}
+	}
+	if !replaced {
+		rules = append(rules, maxRule{pattern, n})
+	}
+	p.maxes.rules = rules
+	clearMap(&p.maxes.cache)
+}
+
+type maxRule struct {
+	pattern string
+	max     int
+}
+
+type maxes struct {
+	mu    sync.Mutex
+	rules []maxRule
+	cache sync.Map // name -> int
+}
+
+// maxFor returns the max subscriptions of name, or of a pattern or filter.
+func (p *Pubsub) maxFor(k kind, name string) int {
+	if k != byName {
+		return p.max
+	}
+	p.maxes.mu.Lock()
+	defer p.maxes.mu.Unlock()
+	if len(p.maxes.rules) == 0 {
+		return p.max
+	}
+	if v, ok := p.maxes.cache.Load(name); ok {
+		return v.(int)
+	}
+	max := p.max
+	for _, rule := range p.maxes.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			max = rule.max
+			break
+		}
+	}
+	p.maxes.cache.Store(name, max)
+	return max
+}
+
+// clearMap deletes the entries of m.
+func clearMap(m *sync.Map) {
+	m.Range(func(k, _ interface{}) bool {
+		m.Delete(k)
+		return true
+	})
+}
+
+// reserve adds one to counter, unless it's max already. No limit if max <= 0.
+func reserve(counter *atomic.Int64, max int) bool {
+	if max <= 0 {
+		counter.Add(1)
+		return true
+	}
+	for {
+		n := counter.Load()
+		if n >= int64(max) {
+			return false
+		}
+		if counter.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// admitSubscriber reserves a subscription of c to name, which has n subscriptions,
+// in the limits of the Pubsub.
+func (p *Pubsub) admitSubscriber(k kind, name string, c chan Event, n int) error {
+	if max := p.maxFor(k, name); max > 0 && n >= max {
+		p.Notify(Notice{Kind: NoticeMaxSubscribe, Name: name, Channel: c, Err: ErrMaxSubscribe})
+		return ErrMaxSubscribe
+	}
+	if k == byName && n == 0 && !reserve(&p.numTopics, p.maxTopics) {
+		p.Notify(Notice{Kind: NoticeMaxSubscribe, Name: name, Channel: c, Err: ErrMaxTopics})
+		return ErrMaxTopics
+	}
+	if !reserve(&p.numSubs, p.maxSubs) {
+		if k == byName && n == 0 {
+			p.numTopics.Add(-1)
+		}
+		p.Notify(Notice{Kind: NoticeMaxSubscribe, Name: name, Channel: c, Err: ErrMaxSubscriptions})
+		return ErrMaxSubscriptions
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSetMaxFor(t *testing.T) {
+	ps := New(1)
+	ps.SetMaxFor("busy/*", 3)
+	ps.SetMaxFor("busy/quiet", 1)
+	ps.SetMaxFor("free/*", 0)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, ps.Subscribe("busy/a", make(chan Event)), nil)
+	}
+	assert.Equal(t, ps.Subscribe("busy/a", make(chan Event)), ErrMaxSubscribe)
+	// the first matching pattern wins
+	assert.Equal(t, ps.Subscribe("busy/quiet", make(chan Event)), nil)
+	assert.Equal(t, ps.Subscribe("busy/quiet", make(chan Event)), nil)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, ps.Subscribe("free/a", make(chan Event)), nil)
+	}
+	assert.Equal(t, ps.Subscribe("other", make(chan Event)), nil)
+	assert.Equal(t, ps.Subscribe("other", make(chan Event)), ErrMaxSubscribe)
+
+	// setting a pattern again replaces its max
+	ps.SetMaxFor("busy/*", 4)
+	assert.Equal(t, ps.Subscribe("busy/a", make(chan Event)), nil)
+	assert.Equal(t, ps.Subscribe("busy/a", make(chan Event)), ErrMaxSubscribe)
+
+	c := make(chan Event)
+	assert.Equal(t, ps.QueueSubscribe("busy/b", "g", c), nil)
+	assert.Equal(t, ps.QueueSubscribe("other", "g", c), nil)
+	assert.Equal(t, ps.QueueSubscribe("other", "g", make(chan Event)), ErrMaxSubscribe)
+}
+
+func TestMaxSubscriptions(t *testing.T) {
+	ps := New(-1, WithMaxSubscriptions(3))
+	c1, c2 := make(chan Event), make(chan Event)
+	assert.Equal(t, ps.Subscribe("a", c1), nil)
+	assert.Equal(t, ps.Subscribe("a", c1), nil)
+	assert.Equal(t, ps.PSubscribe("*", c1), nil)
+	assert.Equal(t, ps.QueueSubscribe("b", "g", c1), nil)
+	assert.Equal(t, ps.Subscribe("a", c2), ErrMaxSubscriptions)
+	assert.Equal(t, ps.QueueSubscribe("b", "g", c2), ErrMaxSubscriptions)
+	assert.Equal(t, ps.QueueGroups("b"), []string{"g"})
+
+	_, err := ps.SubscribeMany(c2, "x", "y")
+	assert.Equal(t, err, ErrMaxSubscriptions)
+	assert.Equal(t, ps.Topics(), []string{"a", "b"})
+
+	ps.Unsubscribe("a", c1)
+	assert.Equal(t, ps.Subscribe("a", c2), nil)
+	assert.Equal(t, ps.Subscribe("c", c2), ErrMaxSubscriptions)
+	ps.UnsubscribeAll(c1)
+	_, err = ps.SubscribeMany(c2, "a", "x", "y")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ps.Subscribe("z", c2), ErrMaxSubscriptions)
+}
+
+func TestMaxTopics(t *testing.T) {
+	// Events subscribes to NoticeTopic
+	ps := New(-1, WithMaxTopics(3))
+	events, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+	c := make(chan Event)
+	assert.Equal(t, ps.Subscribe("a", c), nil)
+	assert.Equal(t, ps.Subscribe("b", c), nil)
+	assert.Equal(t, ps.Subscribe("b", make(chan Event)), nil)
+	assert.Equal(t, ps.PSubscribe("*", c), nil)
+	assert.Equal(t, ps.Subscribe("c", c), ErrMaxTopics)
+	n := receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticeMaxSubscribe)
+	assert.Equal(t, n.Name, "c")
+	assert.Equal(t, n.Err, ErrMaxTopics)
+
+	ps.Unsubscribe("a", c)
+	assert.Equal(t, ps.Subscribe("c", c), nil)
+	assert.Equal(t, ps.Topics(), []string{NoticeTopic, "b", "c"})
+}
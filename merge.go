@@ -0,0 +1,66 @@
+package pubsub
+
+import (
+	"io"
+	"sync"
+)
+
+// Merge subscribes channel c to all patterns at once, so one goroutine can receive
+// the messages of several topics from c. Every pattern is subscribed like
+// PSubscribe and matched by the Matcher of the Pubsub, so each received Event has
+// its topic in Name and the pattern which matched it in Pattern. A name without
+// wildcards only matches itself with the GlobMatcher and the MQTTMatcher, but
+// matches the topics it prefixes with the PrefixMatcher, for example. A message
+// matching several patterns is received once per pattern. If a pattern is
+// malformed or has max subscriptions, c isn't subscribed to any of them and the
+// error is returned. Close of the returned io.Closer unsubscribes c from the
+// patterns.
+func (p *Pubsub) Merge(c chan Event, patterns ...string) (io.Closer, error) {
+	normalized := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		normalized[i] = p.normalize(pattern)
+		if err := p.validatePattern(normalized[i]); err != nil {
+			return nil, err
+		}
+	}
+	m := &merge{p: p, c: c}
+	if c == nil {
+		return m, nil
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	for _, pattern := range normalized {
+		subscribed := p.findChan(p.patterns[pattern], c) >= 0
+		if _, err := p.add(byPattern, pattern, c); err != nil {
+			for _, pattern := range m.patterns {
+				p.remove(byPattern, pattern, c)
+			}
+			return nil, err
+		}
+		if !subscribed {
+			m.patterns = append(m.patterns, pattern)
+		}
+	}
+	return m, nil
+}
+
+type merge struct {
+	p        *Pubsub
+	c        chan Event
+	patterns []string // subscribed by Merge
+	once     sync.Once
+}
+
+// Close unsubscribes the channel from the patterns it was subscribed to by Merge.
+func (m *merge) Close() error {
+	m.once.Do(func() {
+		m.p.locker.Lock()
+		defer m.p.locker.Unlock()
+		for _, pattern := range m.patterns {
+			m.p.remove(byPattern, pattern, m.c)
+		}
+	})
+	return nil
+}
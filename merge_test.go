@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestMerge(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 4)
+	m, err := ps.Merge(c, "orders", "users/*")
+	assert.Equal(t, err, nil)
+
+	ps.Publish("orders", 1)
+	ps.Publish("users/1", 2)
+	ps.Publish("orders/1", 3)
+	event := <-c
+	assert.Equal(t, event.Name, "orders")
+	assert.Equal(t, event.Pattern, "orders")
+	event = <-c
+	assert.Equal(t, event.Name, "users/1")
+	assert.Equal(t, event.Pattern, "users/*")
+	assert.Equal(t, len(c), 0)
+
+	// Close keeps the other subscriptions of c
+	assert.Equal(t, ps.PSubscribe("other", c), nil)
+	assert.Equal(t, m.Close(), nil)
+	assert.Equal(t, m.Close(), nil)
+	assert.Equal(t, ps.Patterns(), []string{"other"})
+}
+
+func TestMergeRollback(t *testing.T) {
+	ps := New(1)
+	assert.Equal(t, ps.PSubscribe("b", make(chan Event)), nil)
+	c := make(chan Event)
+	_, err := ps.Merge(c, "a", "b")
+	assert.Equal(t, err, ErrMaxSubscribe)
+	assert.Equal(t, ps.NumPSubscribers("a"), 0)
+
+	ps = New(-1, WithMatcher(MQTTMatcher{}))
+	_, err = ps.Merge(c, "a/#/b")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestMergeMatcher(t *testing.T) {
+	ps := New(-1, WithMatcher(PrefixMatcher{}))
+	c := make(chan Event, 2)
+	_, err := ps.Merge(c, "orders")
+	assert.Equal(t, err, nil)
+
+	ps.Publish("orders/1", 1)
+	event := <-c
+	assert.Equal(t, event.Name, "orders/1")
+	assert.Equal(t, event.Pattern, "orders")
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import "slices"
+
+// Middleware intercepts published messages before they are delivered. It calls
+// next to continue publishing, possibly with another topic or message, or doesn't
+// call it to filter the message out.
+type Middleware func(topic string, message interface{}, next func(topic string, message interface{}))
+
+// Use appends middleware to the chain which every published message goes through
+// once before it's delivered, until remove is called. It's safe to call Use and
+// remove while publishing, messages being published keep the chain they started
+// with.
+func (p *Pubsub) Use(middleware ...Middleware) (remove func()) {
+	added := make([]*Middleware, len(middleware))
+	for i := range middleware {
+		added[i] = &middleware[i]
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	var chain []*Middleware
+	if old := p.middleware.Load(); old != nil {
+		chain = append(chain, *old...)
+	}
+	chain = append(chain, added...)
+	p.middleware.Store(&chain)
+	return func() {
+		p.locker.Lock()
+		defer p.locker.Unlock()
+
+		old := p.middleware.Load()
+		if old == nil {
+			return
+		}
+		var chain []*Middleware
+		for _, m := range *old {
+			if !slices.Contains(added, m) {
+				chain = append(chain, m)
+			}
+		}
+		if len(chain) == 0 {
+			p.middleware.Store(nil)
+			return
+		}
+		p.middleware.Store(&chain)
+	}
+}
+
+// intercept runs event through the middleware chain, and calls deliver with the
+// event of every call of the last next.
+func (p *Pubsub) intercept(event Event, deliver func(Event)) {
+	chain := p.middleware.Load()
+	if chain == nil {
+		deliver(event)
+		return
+	}
+	var call func(i int, topic string, message interface{})
+	call = func(i int, topic string, message interface{}) {
+		if i == len(*chain) {
+			e := event
+			e.Name, e.Message = topic, message
+			deliver(e)
+			return
+		}
+		(*(*chain)[i])(topic, message, func(topic string, message interface{}) {
+			call(i+1, topic, message)
+		})
+	}
+	call(0, event.Name, event.Message)
+}
+
+// publishVia publishes event through the middleware chain.
+func (p *Pubsub) publishVia(event Event) PublishResult {
+	return p.publishRouted(event, nil)
+}
+
+// publishRouted publishes event through the middleware chain to the subscribers
+// found by route.
+func (p *Pubsub) publishRouted(event Event, route router) PublishResult {
+	if p.middleware.Load() == nil {
+		return p.publishOn(event, route)
+	}
+	var result PublishResult
+	p.intercept(event, func(e Event) {
+		result.merge(p.publishOn(e, route))
+	})
+	return result
+}
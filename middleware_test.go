@@ -0,0 +1,47 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestUse(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 2)
+	ps.Subscribe("a", c)
+	ps.Subscribe("A", c)
+
+	var seen []string
+	ps.Use(func(topic string, message interface{}, next func(string, interface{})) {
+		seen = append(seen, topic)
+		next(topic, message)
+	})
+	remove := ps.Use(func(topic string, message interface{}, next func(string, interface{})) {
+		if message == "drop" {
+			return
+		}
+		next(strings.ToUpper(topic), message)
+	})
+
+	assert.Equal(t, ps.Publish("a", 1), PublishResult{Delivered: 1})
+	assert.Equal(t, <-c, Event{Name: "A", Message: 1})
+	assert.Equal(t, ps.Publish("a", "drop"), PublishResult{})
+	assert.Equal(t, len(c), 0)
+
+	assert.Equal(t, ps.PublishCtx(context.Background(), "a", 2), nil)
+	assert.Equal(t, (<-c).Name, "A")
+	ps.PublishRetain("a", 3)
+	assert.Equal(t, (<-c).Name, "A")
+	msg, _ := ps.Retained("A")
+	assert.Equal(t, msg, 3)
+	assert.Equal(t, seen, []string{"a", "a", "a", "a"})
+
+	remove()
+	remove()
+	assert.Equal(t, ps.Publish("a", 4).Delivered, 1)
+	assert.Equal(t, <-c, Event{Name: "a", Message: 4})
+	assert.Equal(t, seen, []string{"a", "a", "a", "a", "a"})
+}
@@ -0,0 +1,476 @@
+// Package mqtt is a minimal MQTT 3.1.1 server in front of a Pubsub, so devices can
+// publish and subscribe with any MQTT client:
+//
+//	ps := pubsub.New(-1)
+//	l, _ := net.Listen("tcp", ":1883")
+//	go mqtt.New(ps).Serve(l)
+//
+// Topic filters are subscribed with HSubscribe, so + and # match levels like in
+// MQTT whatever the Matcher of the Pubsub. Published payloads are []byte messages,
+// retained messages are the retained messages of the Pubsub, and the will of a
+// client is published when it disconnects without DISCONNECT. The messages of the
+// Pubsub are sent with QoS 0, []byte, json.RawMessage and string messages as they
+// are and other messages encoded with the Codec of the Pubsub. Messages published
+// with QoS 1 and 2 are acknowledged. Sessions aren't persisted, a client
+// connecting without a clean session starts a new one. WithQuotas limits the
+// clients by principal: subscriptions over the quota fail in SUBACK, and clients
+// publishing faster than the publish rate are disconnected, MQTT 3.1.1 having no
+// way to reject a PUBLISH. WithBatch writes the messages sent to a client in
+// batches.
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("mqtt: server closed")
+
+// connectTimeout is how long a new connection has to send CONNECT.
+const connectTimeout = 10 * time.Second
+
+// Server serves MQTT connections to a Pubsub.
+type Server struct {
+	ps         *pubsub.Pubsub
+	codec      pubsub.Codec
+	buffer     int
+	maxPacket  int
+	authorize  func(clientID, username string, password []byte) bool
+	quotas     *pubsub.Quotas
+	batch      int
+	delay      time.Duration
+	generateID atomic.Uint64
+
+	mu        sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	clients   map[string]*client
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithBuffer buffers up to n messages per client, 64 by default. Messages are
+// dropped when a client is slower.
+func WithBuffer(n int) Option {
+	return func(s *Server) {
+		s.buffer = n
+	}
+}
+
+// WithCodec encodes the messages sent to clients with c instead of the Codec of
+// the Pubsub.
+func WithCodec(c pubsub.Codec) Option {
+	return func(s *Server) {
+		s.codec = c
+	}
+}
+
+// WithMaxPacketSize closes the connections sending packets larger than n bytes,
+// 1 MiB by default.
+func WithMaxPacketSize(n int) Option {
+	return func(s *Server) {
+		s.maxPacket = n
+	}
+}
+
+// WithAuthorizer only accepts the clients for which fn returns true, given the
+// credentials of their CONNECT packet.
+func WithAuthorizer(fn func(clientID, username string, password []byte) bool) Option {
+	return func(s *Server) {
+		s.authorize = fn
+	}
+}
+
+// WithQuotas enforces the quotas of q on the clients, for the principal of their
+// username, or of their client ID without one.
+func WithQuotas(q *pubsub.Quotas) Option {
+	return func(s *Server) {
+		s.quotas = q
+	}
+}
+
+// WithBatch writes the messages sent to a client in batches of up to maxSize
+// PUBLISH packets, written at once when full or maxDelay after their first
+// message, e.g. to send many small messages in fewer writes.
+func WithBatch(maxSize int, maxDelay time.Duration) Option {
+	return func(s *Server) {
+		s.batch = maxSize
+		s.delay = maxDelay
+	}
+}
+
+// New creates a Server of ps.
+func New(ps *pubsub.Pubsub, opts ...Option) *Server {
+	s := &Server{
+		ps:        ps,
+		buffer:    64,
+		maxPacket: 1 << 20,
+		listeners: make(map[net.Listener]struct{}),
+		clients:   make(map[string]*client),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.codec == nil {
+		s.codec = ps.Codec()
+	}
+	return s
+}
+
+// Serve accepts connections on l and serves them, until l fails or the Server is
+// closed.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.listeners, l)
+			if s.closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// Close closes the listeners and the connections of the Server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for _, c := range s.clients {
+		c.conn.Close()
+	}
+	return nil
+}
+
+// ServeConn serves an MQTT connection until it's closed.
+func (s *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(connectTimeout))
+	p, err := readPacket(r, s.maxPacket)
+	if err != nil || p.kind != typeConnect {
+		return
+	}
+	req, err := decodeConnect(p.body)
+	if err != nil || req.will != nil && !validTopic(req.will.topic) {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &client{
+		conn:    conn,
+		c:       make(chan pubsub.Event, s.buffer),
+		ctx:     ctx,
+		cancel:  cancel,
+		will:    req.will,
+		pending: make(map[uint16]bool),
+	}
+	if code := s.accept(c, &req); code != connAccepted {
+		c.write(typeConnack, 0, []byte{0, code})
+		return
+	}
+	defer s.disconnect(c)
+	if c.write(typeConnack, 0, []byte{0, connAccepted}) != nil {
+		return
+	}
+
+	go s.forward(c)
+	keepAlive := time.Duration(req.keepAlive) * time.Second * 3 / 2
+	for {
+		var deadline time.Time
+		if keepAlive > 0 {
+			deadline = time.Now().Add(keepAlive)
+		}
+		conn.SetReadDeadline(deadline)
+		p, err := readPacket(r, s.maxPacket)
+		if err != nil {
+			return
+		}
+		if p.kind == typeDisconnect {
+			c.will = nil
+			return
+		}
+		if s.handle(c, p) != nil {
+			return
+		}
+	}
+}
+
+// accept registers the client c connecting with req, and returns the return
+// code of CONNACK.
+func (s *Server) accept(c *client, req *connect) byte {
+	if req.protocol != "MQTT" || req.level != 4 {
+		return connBadProtocol
+	}
+	if req.clientID == "" {
+		if !req.cleanSession {
+			return connIdentifierRejected
+		}
+		req.clientID = "pubsub-" + strconv.FormatUint(s.generateID.Add(1), 10)
+	}
+	if s.authorize != nil && !s.authorize(req.clientID, req.username, req.password) {
+		if req.username == "" && req.password == nil {
+			return connNotAuthorized
+		}
+		return connBadCredentials
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return connServerUnavailable
+	}
+	// a client connecting again takes over the connection of its ID
+	if old := s.clients[req.clientID]; old != nil {
+		old.conn.Close()
+	}
+	c.id = req.clientID
+	s.clients[c.id] = c
+	principal := req.username
+	if principal == "" {
+		principal = c.id
+	}
+	c.quota = s.quotas.Session(principal)
+	return connAccepted
+}
+
+// disconnect unregisters c and publishes its will if any.
+func (s *Server) disconnect(c *client) {
+	c.cancel()
+	s.ps.UnsubscribeAll(c.c)
+	c.quota.Close()
+	s.mu.Lock()
+	if s.clients[c.id] == c {
+		delete(s.clients, c.id)
+	}
+	s.mu.Unlock()
+	if w := c.will; w != nil {
+		s.publish(w.topic, w.message, w.retain)
+	}
+}
+
+func (s *Server) handle(c *client, p packet) error {
+	switch p.kind {
+	case typePublish:
+		msg, err := decodePublish(p.flags, p.body)
+		if err != nil || !validTopic(msg.topic) {
+			return errMalformed
+		}
+		if msg.qos < 2 || !c.pending[msg.id] {
+			if err := c.quota.Publish(); err != nil {
+				return err
+			}
+		}
+		switch msg.qos {
+		case 0:
+			s.publish(msg.topic, msg.payload, msg.retain)
+		case 1:
+			s.publish(msg.topic, msg.payload, msg.retain)
+			return c.write(typePuback, 0, appendUint16(nil, msg.id))
+		case 2:
+			// a message sent again before PUBREL is published once
+			if !c.pending[msg.id] {
+				c.pending[msg.id] = true
+				s.publish(msg.topic, msg.payload, msg.retain)
+			}
+			return c.write(typePubrec, 0, appendUint16(nil, msg.id))
+		}
+	case typePubrel:
+		d := decoder{buf: p.body}
+		id := d.uint16()
+		if d.err != nil {
+			return d.err
+		}
+		delete(c.pending, id)
+		return c.write(typePubcomp, 0, appendUint16(nil, id))
+	case typeSubscribe:
+		id, subs, err := decodeSubscribe(p.body)
+		if err != nil {
+			return err
+		}
+		return s.subscribe(c, id, subs)
+	case typeUnsubscribe:
+		id, filters, err := decodeUnsubscribe(p.body)
+		if err != nil {
+			return err
+		}
+		for _, filter := range filters {
+			s.ps.HUnsubscribe(filter, c.c)
+			c.quota.Unsubscribe(filter, wildcard(filter))
+		}
+		return c.write(typeUnsuback, 0, appendUint16(nil, id))
+	case typePingreq:
+		return c.write(typePingresp, 0, nil)
+	case typePuback, typePubrec, typePubcomp:
+		// the server only sends QoS 0 messages
+	default:
+		return errMalformed
+	}
+	return nil
+}
+
+// subscribe subscribes c to the filters of a SUBSCRIBE packet, granting QoS 0, and
+// sends the retained messages of the topics matching them.
+func (s *Server) subscribe(c *client, id uint16, subs []subscription) error {
+	codes := appendUint16(nil, id)
+	var retained []pubsub.Event
+	for _, sub := range subs {
+		if sub.qos > 2 || c.quota.Subscribe(sub.filter, wildcard(sub.filter)) != nil {
+			codes = append(codes, subackFailure)
+			continue
+		}
+		if s.ps.HSubscribe(sub.filter, c.c) != nil {
+			c.quota.Unsubscribe(sub.filter, wildcard(sub.filter))
+			codes = append(codes, subackFailure)
+			continue
+		}
+		codes = append(codes, 0)
+		events, _ := s.ps.RetainedMatching(sub.filter)
+		retained = append(retained, events...)
+	}
+	if err := c.write(typeSuback, 0, codes); err != nil {
+		return err
+	}
+	for _, event := range retained {
+		if err := s.send(c, event, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publish publishes a message of a client to the Pubsub. An empty retained
+// message clears the retained message of topic.
+func (s *Server) publish(topic string, payload []byte, retain bool) {
+	switch {
+	case !retain:
+		s.ps.Publish(topic, payload)
+	case len(payload) == 0:
+		s.ps.Publish(topic, payload)
+		s.ps.PublishRetain(topic, nil)
+	default:
+		s.ps.PublishRetain(topic, payload)
+	}
+}
+
+// forward sends the messages of the subscriptions of c until it's disconnected,
+// the PUBLISH packets of a batch in a single write.
+func (s *Server) forward(c *client) {
+	size, delay := s.batch, s.delay
+	if size <= 0 {
+		size, delay = 1, 0
+	}
+	for events := range pubsub.Batch(c.ctx, c.c, size, delay) {
+		var buf bytes.Buffer
+		for _, event := range events {
+			if flags, body, ok := s.encode(event, false); ok {
+				writePacket(&buf, typePublish, flags, body)
+			}
+		}
+		if buf.Len() > 0 && c.writeRaw(buf.Bytes()) != nil {
+			// the reader sees the error and closes the connection
+			c.conn.Close()
+			return
+		}
+	}
+}
+
+// send sends event to c as a QoS 0 PUBLISH packet. Messages which can't be
+// encoded are skipped.
+func (s *Server) send(c *client, event pubsub.Event, retain bool) error {
+	flags, body, ok := s.encode(event, retain)
+	if !ok {
+		return nil
+	}
+	return c.write(typePublish, flags, body)
+}
+
+// encode returns the QoS 0 PUBLISH packet of event, or false if its message can't
+// be encoded.
+func (s *Server) encode(event pubsub.Event, retain bool) (flags byte, body []byte, ok bool) {
+	var payload []byte
+	switch msg := event.Message.(type) {
+	case []byte:
+		payload = msg
+	case json.RawMessage:
+		payload = msg
+	case string:
+		payload = []byte(msg)
+	default:
+		data, err := s.codec.Encode(msg)
+		if err != nil {
+			return 0, nil, false
+		}
+		payload = data
+	}
+	flags, body = encodePublish(event.Name, payload, retain)
+	return flags, body, true
+}
+
+// validTopic returns whether name is a topic name which can be published to.
+func validTopic(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "+#")
+}
+
+// wildcard returns whether filter is a topic filter with wildcards, counted by the
+// quotas as a pattern subscription.
+func wildcard(filter string) bool {
+	return strings.ContainsAny(filter, "+#")
+}
+
+// client is a connected client.
+type client struct {
+	id      string
+	conn    net.Conn
+	mu      sync.Mutex // serializes the writes
+	c       chan pubsub.Event
+	ctx     context.Context // done when disconnected
+	cancel  context.CancelFunc
+	will    *will
+	pending map[uint16]bool // QoS 2 messages waiting for PUBREL
+	quota   *pubsub.QuotaSession
+}
+
+func (c *client) write(kind, flags byte, body []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return writePacket(c.conn, kind, flags, body)
+}
+
+// writeRaw writes packets encoded with writePacket.
+func (c *client) writeRaw(packets []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.conn.Write(packets)
+	return err
+}
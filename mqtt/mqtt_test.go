@@ -0,0 +1,257 @@
+package mqtt
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// testClient speaks MQTT over a connection to a Server.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dial(t *testing.T, addr string) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func (c *testClient) send(kind, flags byte, body []byte) {
+	if err := writePacket(c.conn, kind, flags, body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() packet {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	p, err := readPacket(c.r, 1<<20)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+// connect connects as id, with the will published to topic if any.
+func (c *testClient) connect(id, willTopic string) byte {
+	c.t.Helper()
+	body := appendString(nil, "MQTT")
+	flags := byte(0x02)
+	if willTopic != "" {
+		flags |= 0x04
+	}
+	body = append(body, 4, flags, 0, 60)
+	body = appendString(body, id)
+	if willTopic != "" {
+		body = appendString(appendString(body, willTopic), "gone")
+	}
+	c.send(typeConnect, 0, body)
+	p := c.read()
+	assert.Equal(c.t, p.kind, byte(typeConnack))
+	return p.body[1]
+}
+
+func (c *testClient) subscribe(id uint16, filters ...string) []byte {
+	c.t.Helper()
+	body := appendUint16(nil, id)
+	for _, filter := range filters {
+		body = append(appendString(body, filter), 1)
+	}
+	c.send(typeSubscribe, 0x02, body)
+	p := c.read()
+	assert.Equal(c.t, p.kind, byte(typeSuback))
+	assert.Equal(c.t, p.body[:2], appendUint16(nil, id))
+	return p.body[2:]
+}
+
+func (c *testClient) publish(topic, payload string, qos byte, retain bool) {
+	flags, body := encodePublish(topic, nil, retain)
+	flags |= qos << 1
+	if qos > 0 {
+		body = appendUint16(body, 7)
+	}
+	c.send(typePublish, flags, append(body, payload...))
+}
+
+func (c *testClient) message() publish {
+	c.t.Helper()
+	p := c.read()
+	assert.Equal(c.t, p.kind, byte(typePublish))
+	msg, err := decodePublish(p.flags, p.body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func serve(t *testing.T, ps *pubsub.Pubsub, opts ...Option) (*Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(ps, opts...)
+	done := make(chan error)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Close()
+		assert.Equal(t, <-done, ErrServerClosed)
+	})
+	return s, l.Addr().String()
+}
+
+func TestServer(t *testing.T) {
+	ps := pubsub.New(-1)
+	_, addr := serve(t, ps)
+	ps.PublishRetain("devices/1/status", "on")
+
+	sub := dial(t, addr)
+	assert.Equal(t, sub.connect("sub", ""), byte(connAccepted))
+	assert.Equal(t, sub.subscribe(1, "devices/+/status", "bad/#/filter"), []byte{0, subackFailure})
+	retained := sub.message()
+	assert.Equal(t, retained.topic, "devices/1/status")
+	assert.Equal(t, string(retained.payload), "on")
+	assert.Equal(t, retained.retain, true)
+
+	// messages of the Pubsub
+	ps.Publish("devices/2/status", map[string]int{"level": 3})
+	msg := sub.message()
+	assert.Equal(t, msg.topic, "devices/2/status")
+	assert.Equal(t, string(msg.payload), `{"level":3}`)
+	assert.Equal(t, msg.retain, false)
+
+	// messages of other clients
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("devices/3/status", c)
+	pub := dial(t, addr)
+	assert.Equal(t, pub.connect("", ""), byte(connAccepted))
+	pub.publish("devices/3/status", "off", 1, false)
+	ack := pub.read()
+	assert.Equal(t, ack.kind, byte(typePuback))
+	assert.Equal(t, ack.body, appendUint16(nil, 7))
+	assert.Equal(t, (<-c).Message, []byte("off"))
+	assert.Equal(t, string(sub.message().payload), "off")
+
+	// QoS 2 messages sent again are published once
+	pub.publish("devices/3/status", "on", 2, true)
+	pub.publish("devices/3/status", "on", 2, true)
+	assert.Equal(t, pub.read().kind, byte(typePubrec))
+	assert.Equal(t, pub.read().kind, byte(typePubrec))
+	pub.send(typePubrel, 0x02, appendUint16(nil, 7))
+	assert.Equal(t, pub.read().kind, byte(typePubcomp))
+	assert.Equal(t, string(sub.message().payload), "on")
+	retainedMsg, _ := ps.Retained("devices/3/status")
+	assert.Equal(t, retainedMsg, []byte("on"))
+
+	// an empty retained message clears the retained message
+	pub.publish("devices/3/status", "", 0, true)
+	assert.Equal(t, string(sub.message().payload), "")
+	_, ok := ps.Retained("devices/3/status")
+	assert.Equal(t, ok, false)
+
+	pub.send(typePingreq, 0, nil)
+	assert.Equal(t, pub.read().kind, byte(typePingresp))
+
+	// unsubscribing
+	sub.send(typeUnsubscribe, 0x02, appendString(appendUint16(nil, 2), "devices/+/status"))
+	assert.Equal(t, sub.read().kind, byte(typeUnsuback))
+	assert.Equal(t, ps.Filters(), []string{})
+}
+
+func TestServerBatch(t *testing.T) {
+	ps := pubsub.New(-1)
+	_, addr := serve(t, ps, WithBatch(2, 50*time.Millisecond))
+	sub := dial(t, addr)
+	assert.Equal(t, sub.connect("sub", ""), byte(connAccepted))
+	assert.Equal(t, sub.subscribe(1, "a"), []byte{0})
+
+	ps.Publish("a", "1")
+	ps.Publish("a", "2")
+	assert.Equal(t, string(sub.message().payload), "1")
+	assert.Equal(t, string(sub.message().payload), "2")
+
+	// written after the delay
+	start := time.Now()
+	ps.Publish("a", "3")
+	assert.Equal(t, string(sub.message().payload), "3")
+	assert.Equal(t, time.Since(start) >= 50*time.Millisecond, true)
+}
+
+func TestServerWill(t *testing.T) {
+	ps := pubsub.New(-1)
+	_, addr := serve(t, ps)
+	c := make(chan pubsub.Event, 2)
+	ps.Subscribe("wills", c)
+
+	graceful := dial(t, addr)
+	assert.Equal(t, graceful.connect("graceful", "wills"), byte(connAccepted))
+	graceful.send(typeDisconnect, 0, nil)
+
+	lost := dial(t, addr)
+	assert.Equal(t, lost.connect("lost", "wills"), byte(connAccepted))
+	lost.conn.Close()
+
+	select {
+	case event := <-c:
+		assert.Equal(t, event.Message, []byte("gone"))
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	select {
+	case event := <-c:
+		t.Fatalf("unexpected will %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestServerConnect(t *testing.T) {
+	ps := pubsub.New(-1)
+	_, addr := serve(t, ps, WithAuthorizer(func(clientID, username string, password []byte) bool {
+		return clientID != "intruder"
+	}))
+
+	assert.Equal(t, dial(t, addr).connect("intruder", ""), byte(connNotAuthorized))
+
+	old := dial(t, addr)
+	assert.Equal(t, old.connect("device", ""), byte(connAccepted))
+	assert.Equal(t, dial(t, addr).connect("device", ""), byte(connAccepted))
+	// the new connection took over the old one
+	old.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := readPacket(old.r, 1<<20)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestServerQuotas(t *testing.T) {
+	ps := pubsub.New(-1)
+	_, addr := serve(t, ps, WithQuotas(pubsub.NewQuotas(func(principal string) pubsub.Quota {
+		if principal != "device" {
+			return pubsub.Quota{}
+		}
+		return pubsub.Quota{MaxSubscriptions: 1, MaxPatternSubscriptions: 1, PublishRate: 0.001}
+	})))
+
+	c := dial(t, addr)
+	assert.Equal(t, c.connect("device", ""), byte(connAccepted))
+	assert.Equal(t, c.subscribe(1, "a", "b", "c/+", "d/#"), []byte{0, subackFailure, 0, subackFailure})
+	assert.Equal(t, ps.Filters(), []string{"a", "c/+"})
+	c.send(typeUnsubscribe, 0x02, appendString(appendUint16(nil, 2), "c/+"))
+	assert.Equal(t, c.read().kind, byte(typeUnsuback))
+	assert.Equal(t, c.subscribe(3, "d/#"), []byte{0})
+
+	// publishing over the rate disconnects the client
+	c.publish("x", "1", 1, false)
+	assert.Equal(t, c.read().kind, byte(typePuback))
+	c.publish("x", "2", 1, false)
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := readPacket(c.r, 1<<20)
+	assert.Equal(t, err != nil, true)
+}
@@ -0,0 +1,255 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Types of the control packets, the high nibble of the fixed header.
+const (
+	typeConnect     = 1
+	typeConnack     = 2
+	typePublish     = 3
+	typePuback      = 4
+	typePubrec      = 5
+	typePubrel      = 6
+	typePubcomp     = 7
+	typeSubscribe   = 8
+	typeSuback      = 9
+	typeUnsubscribe = 10
+	typeUnsuback    = 11
+	typePingreq     = 12
+	typePingresp    = 13
+	typeDisconnect  = 14
+)
+
+// Return codes of CONNACK.
+const (
+	connAccepted           = 0
+	connBadProtocol        = 1
+	connIdentifierRejected = 2
+	connServerUnavailable  = 3
+	connBadCredentials     = 4
+	connNotAuthorized      = 5
+)
+
+// subackFailure is the return code of SUBACK for a rejected filter.
+const subackFailure = 0x80
+
+var errMalformed = errors.New("mqtt: malformed packet")
+
+// packet is a control packet.
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+// readPacket reads a packet of at most max bytes of body from r.
+func readPacket(r *bufio.Reader, max int) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	n, shift := 0, 0
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return packet{}, errMalformed
+		}
+		shift += 7
+	}
+	if n > max {
+		return packet{}, errMalformed
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{kind: header >> 4, flags: header & 0x0f, body: body}, nil
+}
+
+// writePacket writes a packet to w.
+func writePacket(w io.Writer, kind, flags byte, body []byte) error {
+	buf := make([]byte, 0, 5+len(body))
+	buf = append(buf, kind<<4|flags)
+	n := len(body)
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(buf, body...))
+	return err
+}
+
+// decoder reads the fields of the body of a packet.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.buf) < 2 {
+		d.err = errMalformed
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.buf)
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.buf) < 1 {
+		d.err = errMalformed
+		return 0
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v
+}
+
+// bytes reads a length prefixed field.
+func (d *decoder) bytes() []byte {
+	n := int(d.uint16())
+	if d.err != nil || len(d.buf) < n {
+		d.err = errMalformed
+		return nil
+	}
+	v := d.buf[:n:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return binary.BigEndian.AppendUint16(buf, v)
+}
+
+func appendString(buf []byte, s string) []byte {
+	return append(appendUint16(buf, uint16(len(s))), s...)
+}
+
+// connect is the content of a CONNECT packet.
+type connect struct {
+	protocol     string
+	level        byte
+	cleanSession bool
+	keepAlive    uint16
+	clientID     string
+	will         *will
+	username     string
+	password     []byte
+}
+
+// will is the message published when a client disconnects without DISCONNECT.
+type will struct {
+	topic   string
+	message []byte
+	retain  bool
+}
+
+func decodeConnect(body []byte) (connect, error) {
+	d := decoder{buf: body}
+	c := connect{protocol: d.string(), level: d.byte()}
+	flags := d.byte()
+	c.keepAlive = d.uint16()
+	if d.err != nil || flags&0x01 != 0 {
+		return c, errMalformed
+	}
+	if c.protocol != "MQTT" || c.level != 4 {
+		return c, nil
+	}
+	c.cleanSession = flags&0x02 != 0
+	c.clientID = d.string()
+	if flags&0x04 != 0 {
+		c.will = &will{topic: d.string(), message: d.bytes(), retain: flags&0x20 != 0}
+	}
+	if flags&0x80 != 0 {
+		c.username = d.string()
+	}
+	if flags&0x40 != 0 {
+		c.password = d.bytes()
+	}
+	return c, d.err
+}
+
+// subscription is a topic filter of a SUBSCRIBE packet.
+type subscription struct {
+	filter string
+	qos    byte
+}
+
+func decodeSubscribe(body []byte) (uint16, []subscription, error) {
+	d := decoder{buf: body}
+	id := d.uint16()
+	var subs []subscription
+	for d.err == nil && len(d.buf) > 0 {
+		subs = append(subs, subscription{filter: d.string(), qos: d.byte()})
+	}
+	if d.err != nil || len(subs) == 0 {
+		return 0, nil, errMalformed
+	}
+	return id, subs, nil
+}
+
+func decodeUnsubscribe(body []byte) (uint16, []string, error) {
+	d := decoder{buf: body}
+	id := d.uint16()
+	var filters []string
+	for d.err == nil && len(d.buf) > 0 {
+		filters = append(filters, d.string())
+	}
+	if d.err != nil || len(filters) == 0 {
+		return 0, nil, errMalformed
+	}
+	return id, filters, nil
+}
+
+// publish is the content of a PUBLISH packet.
+type publish struct {
+	topic   string
+	qos     byte
+	retain  bool
+	id      uint16
+	payload []byte
+}
+
+func decodePublish(flags byte, body []byte) (publish, error) {
+	d := decoder{buf: body}
+	p := publish{topic: d.string(), qos: flags >> 1 & 0x03, retain: flags&0x01 != 0}
+	if p.qos > 0 {
+		p.id = d.uint16()
+	}
+	if d.err != nil || p.qos > 2 {
+		return p, errMalformed
+	}
+	p.payload = d.buf
+	return p, nil
+}
+
+func encodePublish(topic string, payload []byte, retain bool) (flags byte, body []byte) {
+	if retain {
+		flags = 0x01
+	}
+	body = make([]byte, 0, 2+len(topic)+len(payload))
+	return flags, append(appendString(body, topic), payload...)
+}
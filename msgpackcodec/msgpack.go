@@ -0,0 +1,33 @@
+// Package msgpackcodec provides a MessagePack pubsub.Codec.
+//
+//	ps := pubsub.New(-1, pubsub.WithCodec(msgpackcodec.Codec))
+package msgpackcodec
+
+import (
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ContentType is the content type of the messages encoded by Codec.
+const ContentType = "application/msgpack"
+
+// Codec encodes with MessagePack.
+var Codec pubsub.Codec = codec{}
+
+type codec struct{}
+
+func (codec) ContentType() string {
+	return ContentType
+}
+
+func (codec) Encode(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (codec) Decode(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func init() {
+	pubsub.RegisterCodec(Codec)
+}
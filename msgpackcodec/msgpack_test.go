@@ -0,0 +1,22 @@
+package msgpackcodec
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestCodec(t *testing.T) {
+	type order struct {
+		ID    int
+		Items []string
+	}
+	data, err := Codec.Encode(order{1, []string{"a"}})
+	assert.Equal(t, err, nil)
+
+	var got order
+	e := pubsub.Event{Message: data, Headers: map[string]string{pubsub.HeaderContentType: ContentType}}
+	assert.Equal(t, e.Into(&got), nil)
+	assert.Equal(t, got, order{1, []string{"a"}})
+}
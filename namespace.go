@@ -0,0 +1,121 @@
+package pubsub
+
+import (
+	"context"
+	"strings"
+)
+
+// Namespace is a view of a Pubsub prefixing the names and patterns of its calls,
+// e.g. to isolate the topics of a tenant. The subscriptions and messages are those
+// of the Pubsub: the events received keep their full name, see Local.
+type Namespace struct {
+	p      *Pubsub
+	prefix string // normalized
+}
+
+// Namespace returns the view of the Pubsub prefixing names and patterns with
+// prefix, e.g. "tenant42.". Patterns are prefixed as is, the prefix shouldn't hold
+// the special characters of the Matcher. The prefix is normalized as the names
+// of the Pubsub, e.g. CaseInsensitive.
+func (p *Pubsub) Namespace(prefix string) *Namespace {
+	return &Namespace{p: p, prefix: p.normalize(prefix)}
+}
+
+// Namespace returns the view nested in the namespace with prefix.
+func (n *Namespace) Namespace(prefix string) *Namespace {
+	return n.p.Namespace(n.prefix + prefix)
+}
+
+// Prefix returns the prefix of the namespace.
+func (n *Namespace) Prefix() string {
+	return n.prefix
+}
+
+// Local returns name without the prefix of the namespace, e.g. the name of a
+// received event.
+func (n *Namespace) Local(name string) string {
+	return strings.TrimPrefix(name, n.prefix)
+}
+
+func (n *Namespace) Subscribe(name string, c chan Event) error {
+	return n.p.Subscribe(n.prefix+name, c)
+}
+
+func (n *Namespace) Unsubscribe(name string, c chan Event) {
+	n.p.Unsubscribe(n.prefix+name, c)
+}
+
+func (n *Namespace) PSubscribe(pattern string, c chan Event) error {
+	return n.p.PSubscribe(n.prefix+pattern, c)
+}
+
+func (n *Namespace) PUnsubscribe(pattern string, c chan Event) {
+	n.p.PUnsubscribe(n.prefix+pattern, c)
+}
+
+// UnsubscribeAll unsubscribes c from everything, in the namespace or not.
+func (n *Namespace) UnsubscribeAll(c chan Event) {
+	n.p.UnsubscribeAll(c)
+}
+
+func (n *Namespace) SubscribeCtx(ctx context.Context, name string, c chan Event) error {
+	return n.p.SubscribeCtx(ctx, n.prefix+name, c)
+}
+
+func (n *Namespace) PSubscribeCtx(ctx context.Context, pattern string, c chan Event) error {
+	return n.p.PSubscribeCtx(ctx, n.prefix+pattern, c)
+}
+
+func (n *Namespace) SubscribeFunc(ctx context.Context, name string, fn Handler) (*Subscription, error) {
+	return n.p.SubscribeFunc(ctx, n.prefix+name, fn)
+}
+
+func (n *Namespace) SubscribeRetained(name string, c chan Event) error {
+	return n.p.SubscribeRetained(n.prefix+name, c)
+}
+
+func (n *Namespace) Publish(name string, message interface{}) PublishResult {
+	return n.p.Publish(n.prefix+name, message)
+}
+
+func (n *Namespace) PublishMsg(name string, message interface{}, headers map[string]string) PublishResult {
+	return n.p.PublishMsg(n.prefix+name, message, headers)
+}
+
+func (n *Namespace) PublishCtx(ctx context.Context, name string, message interface{}) error {
+	return n.p.PublishCtx(ctx, n.prefix+name, message)
+}
+
+func (n *Namespace) PublishRetain(name string, message interface{}) PublishResult {
+	return n.p.PublishRetain(n.prefix+name, message)
+}
+
+func (n *Namespace) Retained(name string) (interface{}, bool) {
+	return n.p.Retained(n.prefix + name)
+}
+
+func (n *Namespace) NumSubscribers(name string) int {
+	return n.p.NumSubscribers(n.prefix + name)
+}
+
+// Topics returns the subscribed topics of the namespace, without the prefix.
+func (n *Namespace) Topics() []string {
+	var ret []string
+	for _, name := range n.p.Topics() {
+		if strings.HasPrefix(name, n.prefix) {
+			ret = append(ret, n.Local(name))
+		}
+	}
+	return ret
+}
+
+// Patterns returns the subscribed patterns of the namespace, without the prefix.
+func (n *Namespace) Patterns() []string {
+	var ret []string
+	for _, pattern := range n.p.Patterns() {
+		if strings.HasPrefix(pattern, n.prefix) {
+			ret = append(ret, n.Local(pattern))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,53 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestNamespace(t *testing.T) {
+	ps := New(-1)
+	a := ps.Namespace("tenant1.")
+	b := ps.Namespace("tenant2.")
+
+	ca := make(chan Event, 10)
+	cb := make(chan Event, 10)
+	a.PSubscribe("*", ca)
+	b.Subscribe("orders", cb)
+
+	a.Publish("orders", 1)
+	b.Publish("orders", 2)
+	ps.Publish("orders", 3)
+
+	assert.Equal(t, len(ca), 1)
+	e := <-ca
+	assert.Equal(t, e.Name, "tenant1.orders")
+	assert.Equal(t, a.Local(e.Name), "orders")
+	assert.Equal(t, (<-cb).Message, 2)
+
+	assert.Equal(t, b.Topics(), []string{"orders"})
+	assert.Equal(t, a.Patterns(), []string{"*"})
+	assert.Equal(t, ps.Topics(), []string{"tenant2.orders"})
+
+	nested := a.Namespace("eu.")
+	assert.Equal(t, nested.Prefix(), "tenant1.eu.")
+	nested.PublishRetain("x", 4)
+	v, _ := ps.Retained("tenant1.eu.x")
+	assert.Equal(t, v, 4)
+}
+
+func TestNamespaceNormalized(t *testing.T) {
+	ps := New(-1, WithTopicNormalizer(CaseInsensitive))
+	a := ps.Namespace("Tenant1.")
+	assert.Equal(t, a.Prefix(), "tenant1.")
+
+	c := make(chan Event, 10)
+	a.Subscribe("Orders", c)
+	a.PSubscribe("*", c)
+	a.Publish("orders", 1)
+	assert.Equal(t, a.Local((<-c).Name), "orders")
+	assert.Equal(t, a.Topics(), []string{"orders"})
+	assert.Equal(t, a.Patterns(), []string{"*"})
+	assert.Equal(t, a.Namespace("EU.").Prefix(), "tenant1.eu.")
+}
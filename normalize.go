@@ -0,0 +1,36 @@
+package pubsub
+
+import "strings"
+
+// WithTopicNormalizer normalizes the names, patterns and filters given to the
+// Pubsub with fn before using them, so the names normalized the same are the same
+// topic. fn must be idempotent, e.g. CaseInsensitive. Received events have the
+// normalized names.
+func WithTopicNormalizer(fn func(string) string) Option {
+	return func(p *Pubsub) {
+		p.normalizer = fn
+	}
+}
+
+// CaseInsensitive is a topic normalizer making names case-insensitive, by lowering
+// their case.
+func CaseInsensitive(name string) string {
+	return strings.ToLower(name)
+}
+
+// sysPrefix is the prefix of the topics of the Pubsub itself, e.g. NoticeTopic.
+const sysPrefix = "$SYS/"
+
+// system returns whether the normalized name is a topic of the Pubsub itself, which
+// isn't reported by keyspace notifications, notices, logs and audit records.
+func (p *Pubsub) system(name string) bool {
+	return strings.HasPrefix(name, p.sysPrefix)
+}
+
+// normalize returns name normalized by the normalizer of the Pubsub.
+func (p *Pubsub) normalize(name string) string {
+	if p.normalizer == nil {
+		return name
+	}
+	return p.normalizer(name)
+}
@@ -0,0 +1,33 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestTopicNormalizer(t *testing.T) {
+	ps := New(-1, WithTopicNormalizer(CaseInsensitive), WithHistory(10))
+	c := make(chan Event, 10)
+	ps.Subscribe("Orders", c)
+	ps.PSubscribe("ORDERS.*", c)
+	ps.SubscribeMany(c, "Users", "users")
+
+	ps.Publish("orders", 1)
+	ps.Publish("ORDERS.New", 2)
+	ps.PublishRetain("USERS", 3)
+
+	events := receiveN(t, c, 3)
+	assert.Equal(t, events[0].Name, "orders")
+	assert.Equal(t, events[1].Name, "orders.new")
+	assert.Equal(t, events[1].Pattern, "orders.*")
+	assert.Equal(t, ps.NumSubscribers("oRdErS"), 1)
+	assert.Equal(t, len(ps.History("Orders")), 1)
+	v, _ := ps.Retained("Users")
+	assert.Equal(t, v, 3)
+
+	ps.Unsubscribe("ORDERS", c)
+	ps.PUnsubscribe("orders.*", c)
+	assert.Equal(t, ps.Topics(), []string{"users"})
+	assert.Equal(t, ps.Patterns(), []string{})
+}
@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NoticeTopic is the topic which the Pubsub publishes its Notices to.
+const NoticeTopic = "$SYS/notices"
+
+// NoticeKind is the kind of a Notice.
+type NoticeKind int
+
+const (
+	// NoticeSlowSubscriber is a channel which dropped SlowThreshold consecutive
+	// messages.
+	NoticeSlowSubscriber NoticeKind = iota
+	// NoticeMaxSubscribe is a subscription refused because the name or pattern has
+	// max subscriptions.
+	NoticeMaxSubscribe
+	// NoticeBadPattern is a pattern the Matcher can't compile.
+	NoticeBadPattern
+	// NoticeBridgeError is an error of a bridge to another Pubsub or broker.
+	NoticeBridgeError
+	// NoticePanic is a panic recovered from a handler, with a *PanicError.
+	NoticePanic
+	// NoticeUnassignable is a message skipped by SubscribeChan because it can't be
+	// stored in the element type of the channel.
+	NoticeUnassignable
+)
+
+func (k NoticeKind) String() string {
+	switch k {
+	case NoticeSlowSubscriber:
+		return "slow subscriber"
+	case NoticeMaxSubscribe:
+		return "max subscribe"
+	case NoticeBadPattern:
+		return "bad pattern"
+	case NoticeBridgeError:
+		return "bridge error"
+	case NoticePanic:
+		return "panic"
+	case NoticeUnassignable:
+		return "unassignable"
+	}
+	return "unknown"
+}
+
+// Notice is a problem of the Pubsub, which are otherwise invisible, published to
+// NoticeTopic.
+type Notice struct {
+	Kind    NoticeKind
+	Time    time.Time
+	Name    string     // the topic or pattern, if any
+	Channel chan Event // the subscribed channel, if any
+	Drops   int        // the consecutive drops of a slow subscriber
+	Err     error
+}
+
+// DefaultSlowThreshold is the number of consecutive drops of a slow subscriber
+// without WithSlowThreshold.
+const DefaultSlowThreshold = 10
+
+// WithSlowThreshold reports a channel as a slow subscriber when it dropped n
+// consecutive messages. It's reported again after it received a message.
+func WithSlowThreshold(n int) Option {
+	return func(p *Pubsub) {
+		p.slowThreshold = n
+	}
+}
+
+// systemQueueSize is the size of the queues of the Notices and the keyspace
+// notifications waiting to be published.
+const systemQueueSize = 1024
+
+// Events returns a new channel receiving the Notices of the Pubsub as messages of
+// NoticeTopic, and the function unsubscribing it, or the error of subscribing it,
+// e.g. ErrMaxSubscribe. Notices are dropped if the channel isn't ready to receive.
+func (p *Pubsub) Events() (events <-chan Event, stop func(), err error) {
+	c := make(chan Event, 64)
+	if err := p.Subscribe(NoticeTopic, c); err != nil {
+		return nil, nil, err
+	}
+	return c, func() {
+		p.Unsubscribe(NoticeTopic, c)
+	}, nil
+}
+
+// Notify publishes n to NoticeTopic and logs it WithLogger, from a goroutine so it's
+// never published with a lock held. It's used by the packages bridging the Pubsub to
+// report their errors. Notices are dropped, and counted in Stats.SystemDropped,
+// while systemQueueSize notices are waiting.
+func (p *Pubsub) Notify(n Notice) {
+	if n.Time.IsZero() {
+		n.Time = p.clock.Now()
+	}
+	q := &p.notices
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) >= systemQueueSize {
+		q.dropped.Add(1)
+		return
+	}
+	q.queue = append(q.queue, n)
+	if !q.running {
+		q.running = true
+		go p.publishNotices()
+	}
+}
+
+type notices struct {
+	mu      sync.Mutex
+	queue   []Notice
+	running bool
+	dropped atomic.Uint64
+}
+
+func (p *Pubsub) publishNotices() {
+	q := &p.notices
+	for {
+		q.mu.Lock()
+		if len(q.queue) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		n := q.queue[0]
+		q.queue = q.queue[1:]
+		q.mu.Unlock()
+
+		p.logNotice(n)
+		p.Publish(NoticeTopic, n)
+	}
+}
+
+// countDrops counts the consecutive drops of the channel of s, and reports it as a
+// slow subscriber when reaching the threshold.
+func (p *Pubsub) countDrops(s *subscriber, name string, o outcome) {
+	switch o {
+	case delivered:
+		if s.sub.drops.Load() != 0 {
+			s.sub.drops.Store(0)
+		}
+	case dropped:
+		threshold := p.slowThreshold
+		if threshold <= 0 {
+			threshold = DefaultSlowThreshold
+		}
+		if n := s.sub.drops.Add(1); n == int64(threshold) && !p.system(name) {
+			p.Notify(Notice{Kind: NoticeSlowSubscriber, Name: name, Channel: s.c, Drops: int(n)})
+		}
+	}
+}
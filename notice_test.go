@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func receiveNotice(t *testing.T, events <-chan Event) Notice {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e.Message.(Notice)
+	case <-time.After(time.Second):
+		t.Fatal("no notice")
+	}
+	return Notice{}
+}
+
+func TestNotices(t *testing.T) {
+	ps := New(1, WithSlowThreshold(3))
+	events, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+
+	slow := make(chan Event)
+	ps.Subscribe("a", slow)
+	for i := 0; i < 5; i++ {
+		ps.Publish("a", i)
+	}
+	n := receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticeSlowSubscriber)
+	assert.Equal(t, n.Name, "a")
+	assert.Equal(t, n.Channel, slow)
+	assert.Equal(t, n.Drops, 3)
+
+	assert.Equal(t, ps.Subscribe("a", make(chan Event)), ErrMaxSubscribe)
+	n = receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticeMaxSubscribe)
+	assert.Equal(t, n.Err, ErrMaxSubscribe)
+
+	assert.Equal(t, errors.Is(ps.PSubscribe("h[llo", make(chan Event)), ErrBadPattern), true)
+	n = receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticeBadPattern)
+	assert.Equal(t, errors.Is(n.Err, filepath.ErrBadPattern), true)
+	assert.Equal(t, n.Kind.String(), "bad pattern")
+
+	ps.Notify(Notice{Kind: NoticeBridgeError, Err: errors.New("down")})
+	n = receiveNotice(t, events)
+	assert.Equal(t, n.Err.Error(), "down")
+	assert.Equal(t, n.Time.IsZero(), false)
+}
+
+func TestEventsStop(t *testing.T) {
+	ps := New(-1)
+	_, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ps.NumSubscribers(NoticeTopic), 1)
+	stop()
+	assert.Equal(t, ps.NumSubscribers(NoticeTopic), 0)
+}
+
+func TestNoticesBounded(t *testing.T) {
+	ps := New(-1, WithKeyspaceEvents(KeyspacePublish))
+	// as if the queues were being published
+	ps.notices.running = true
+	ps.keyspace.running = true
+	for i := 0; i < systemQueueSize+2; i++ {
+		ps.Notify(Notice{Kind: NoticeBridgeError})
+		ps.Publish("a", i)
+	}
+	assert.Equal(t, len(ps.notices.queue), systemQueueSize)
+	assert.Equal(t, len(ps.keyspace.queue), systemQueueSize)
+	assert.Equal(t, ps.Stats().SystemDropped, uint64(4))
+}
+
+func TestEventsError(t *testing.T) {
+	ps := New(1)
+	_, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+	events, stop2, err := ps.Events()
+	assert.Equal(t, err, ErrMaxSubscribe)
+	assert.Equal(t, events == nil && stop2 == nil, true)
+}
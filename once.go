@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SubscribeOnce subscribes channel c to name like Subscribe, until one message is
+// sent to c. Concurrent Publish never sends more than one message to c, a dropped
+// message doesn't count.
+func (p *Pubsub) SubscribeOnce(name string, c chan Event) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	_, err := p.addWith(byName, name, c, func(s *subscriber) {
+		s.limit = &subscriberLimit{n: 1, k: byName, name: name}
+	})
+	return err
+}
+
+// SubscribeOnceFunc subscribes fn to name like SubscribeFunc, until fn is called
+// with one message.
+func (p *Pubsub) SubscribeOnceFunc(ctx context.Context, name string, fn Handler) (*Subscription, error) {
+	name = p.normalize(name)
+	return p.subscribeFunc(ctx, fn, func(c chan Event) error {
+		return p.SubscribeOnce(name, c)
+	})
+}
+
+// subscriberLimit is the number of messages sent to a subscriber before it's
+// removed.
+type subscriberLimit struct {
+	n         int64
+	claimed   atomic.Int64 // messages being sent or sent
+	delivered atomic.Int64
+	k         kind
+	name      string
+}
+
+// claim returns whether a message can be sent to a limited subscriber. The claim is
+// released if the message is dropped.
+func (s *subscriber) claim() bool {
+	l := s.limit
+	if l == nil {
+		return true
+	}
+	for {
+		claimed := l.claimed.Load()
+		if claimed >= l.n {
+			return false
+		}
+		if l.claimed.CompareAndSwap(claimed, claimed+1) {
+			return true
+		}
+	}
+}
+
+// sent does the bookkeeping after sending a message of name to s. It removes a
+// limited subscriber after its last message was sent to it. Publish can hold the
+// write lock, so the subscriber is removed from another goroutine.
+func (p *Pubsub) sent(s *subscriber, name string, o outcome) {
+	p.countDrops(s, name, o)
+	countRoute(s, o)
+	if o == delivered {
+		p.delivered.Add(1)
+		if p.stats != nil {
+			p.stats.topic(name).delivered.Add(1)
+		}
+	}
+	l := s.limit
+	if l == nil || o == skipped {
+		return
+	}
+	if o != delivered {
+		l.claimed.Add(-1)
+		return
+	}
+	if l.delivered.Add(1) < l.n {
+		return
+	}
+	go func() {
+		if l.k == byName {
+			p.locker.RLock()
+			defer p.locker.RUnlock()
+		} else {
+			p.locker.Lock()
+			defer p.locker.Unlock()
+		}
+
+		collection, unlock := p.collection(l.k, l.name)
+		defer unlock()
+		for i, sub := range collection[l.name] {
+			if sub == s {
+				p.removeAt(l.k, collection, l.name, i)
+				p.changed(l.k, l.name)
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,45 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSubscribeOnce(t *testing.T) {
+	ps := New(-1)
+	full := make(chan Event)
+	assert.Equal(t, ps.SubscribeOnce("a", full), nil)
+	assert.Equal(t, ps.Publish("a", 0), PublishResult{Dropped: 1})
+	ps.Unsubscribe("a", full)
+
+	c := make(chan Event, 10)
+	assert.Equal(t, ps.SubscribeOnce("a", c), nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ps.Publish("a", i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, len(c), 1)
+	waitFor(t, func() bool { return ps.NumSubscribers("a") == 0 })
+	assert.Equal(t, ps.Subscription(c), (*Subscription)(nil))
+}
+
+func TestSubscribeOnceFunc(t *testing.T) {
+	ps := New(-1)
+	got := make(chan interface{}, 2)
+	s, err := ps.SubscribeOnceFunc(context.Background(), "a", func(_ context.Context, e Event) {
+		got <- e.Message
+	})
+	assert.Equal(t, err, nil)
+	ps.Publish("a", 1)
+	assert.Equal(t, <-got, 1)
+	waitFor(t, func() bool { return s.State() == Closed })
+	assert.Equal(t, ps.Publish("a", 2), PublishResult{})
+}
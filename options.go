@@ -0,0 +1,4 @@
+package pubsub
+
+// Option configures a Pubsub created by New.
+type Option func(p *Pubsub)
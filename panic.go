@@ -0,0 +1,41 @@
+package pubsub
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is a panic recovered from a handler, reported as a NoticePanic.
+type PanicError struct {
+	Topic   string
+	Message interface{} // the message being handled, if any
+	Value   interface{} // the value passed to panic
+	Stack   []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pubsub: panic handling a message of %q: %v", e.Topic, e.Value)
+}
+
+// Unwrap returns the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// isolate recovers a panic while handling event, and reports it as a NoticePanic
+// rather than crashing the process. It must be deferred.
+func (p *Pubsub) isolate(event Event) {
+	if v := recover(); v != nil {
+		p.Notify(Notice{
+			Kind: NoticePanic,
+			Name: event.Name,
+			Err: &PanicError{
+				Topic:   event.Name,
+				Message: event.Message,
+				Value:   v,
+				Stack:   debug.Stack(),
+			},
+		})
+	}
+}
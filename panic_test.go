@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	var created []string
+	ps := New(-1, OnTopicCreated(func(name string) {
+		if name == "bad" {
+			panic("hook")
+		}
+		created = append(created, name)
+	}))
+	events, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+
+	errBoom := errors.New("boom")
+	handled := make(chan Event, 1)
+	ps.SubscribeFunc(context.Background(), "a", func(ctx context.Context, event Event) {
+		if event.Message == 1 {
+			panic(errBoom)
+		}
+		handled <- event
+	})
+
+	ps.Publish("a", 1)
+	n := receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticePanic)
+	assert.Equal(t, n.Name, "a")
+	var perr *PanicError
+	assert.Equal(t, errors.As(n.Err, &perr), true)
+	assert.Equal(t, perr.Message, 1)
+	assert.Equal(t, errors.Is(n.Err, errBoom), true)
+	assert.Equal(t, len(perr.Stack) > 0, true)
+
+	// the handler keeps handling messages
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-handled).Message, 2)
+
+	ps.Subscribe("bad", make(chan Event))
+	n = receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticePanic)
+	assert.Equal(t, n.Err.(*PanicError).Value, "hook")
+}
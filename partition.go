@@ -0,0 +1,23 @@
+package pubsub
+
+// HeaderKey is the header of the partition key of a message.
+const HeaderKey = "Pubsub-Key"
+
+// PublishKeyed publishes a message with key like Publish. Each queue group of name
+// delivers the messages with the same key to the same member, the member of the
+// partition of the hash of key, so the messages of an entity are handled in order
+// by one worker. The message is dropped for a group if the member isn't ready to
+// receive, rather than delivered out of order by another member. The partitions
+// move when the members of a group change. An empty key publishes like Publish.
+func (p *Pubsub) PublishKeyed(name, key string, message interface{}) PublishResult {
+	event := p.newEvent(name, message)
+	if key != "" {
+		event.Headers = map[string]string{HeaderKey: key}
+	}
+	return p.publishVia(event)
+}
+
+// Key returns the partition key of the message from its HeaderKey header.
+func (e Event) Key() string {
+	return e.Headers[HeaderKey]
+}
@@ -0,0 +1,58 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishKeyed(t *testing.T) {
+	ps := New(-1)
+	members := []chan Event{make(chan Event, 100), make(chan Event, 100), make(chan Event, 100)}
+	for _, c := range members {
+		ps.QueueSubscribe("orders", "workers", c)
+	}
+	all := make(chan Event, 100)
+	ps.Subscribe("orders", all)
+
+	for i := 0; i < 30; i++ {
+		ps.PublishKeyed("orders", fmt.Sprintf("customer-%d", i%5), i)
+	}
+	assert.Equal(t, len(all), 30)
+
+	owners := map[string]int{}
+	total := 0
+	for m, c := range members {
+		for len(c) > 0 {
+			event := <-c
+			key := event.Key()
+			if owner, ok := owners[key]; ok {
+				assert.Equal(t, owner, m)
+			}
+			owners[key] = m
+			total++
+		}
+	}
+	assert.Equal(t, total, 30)
+	assert.Equal(t, len(owners), 5)
+}
+
+func TestPublishKeyedDrops(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	ps.QueueSubscribe("a", "g", c)
+	ready := make(chan Event, 1)
+	ps.QueueSubscribe("a", "g", ready)
+
+	// a key of the unbuffered member isn't delivered to the other one
+	for i := 0; ; i++ {
+		key := fmt.Sprint(i)
+		result := ps.PublishKeyed("a", key, key)
+		if result.Dropped == 1 {
+			assert.Equal(t, len(ready), 0)
+			break
+		}
+		<-ready
+	}
+}
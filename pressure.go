@@ -0,0 +1,38 @@
+package pubsub
+
+// Pressure returns how full the channels which a message published to name would be
+// sent to are, from 0 if they are empty to 1 if one is full, so a publisher can slow
+// down before messages are dropped. It is the fill of the fullest channel, or of
+// the fullest lane WithPriorityLanes, while a queue group counts as its emptiest
+// member. Unbuffered channels are ignored, their readiness can't be measured.
+func (p *Pubsub) Pressure(name string) float64 {
+	name = p.normalize(name)
+	var pressure float64
+	p.route(name, func(s *subscriber, _ string) {
+		pressure = max(pressure, s.fill())
+	})
+	p.routeQueues(name, func(g queueGroup) {
+		least := 1.0
+		for _, s := range g.members {
+			least = min(least, s.fill())
+		}
+		pressure = max(pressure, least)
+	})
+	return pressure
+}
+
+// fill returns how full the channel of s is, from 0 to 1.
+func (s *subscriber) fill() float64 {
+	var fill float64
+	if n := cap(s.c); n > 0 {
+		fill = float64(len(s.c)) / float64(n)
+	}
+	if l := s.lanes; l != nil && l.size > 0 {
+		l.mu.Lock()
+		for _, queue := range l.queues {
+			fill = max(fill, float64(len(queue))/float64(l.size))
+		}
+		l.mu.Unlock()
+	}
+	return fill
+}
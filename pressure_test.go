@@ -0,0 +1,39 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPressure(t *testing.T) {
+	ps := New(-1)
+	assert.Equal(t, ps.Pressure("a/b"), 0.0)
+
+	full, quarter := make(chan Event, 4), make(chan Event, 4)
+	assert.Equal(t, ps.Subscribe("a/b", quarter), nil)
+	assert.Equal(t, ps.Subscribe("a/b", make(chan Event)), nil)
+	ps.Publish("a/b", 1)
+	assert.Equal(t, ps.Pressure("a/b"), 0.25)
+
+	assert.Equal(t, ps.PSubscribe("a/*", full), nil)
+	for i := 0; i < 4; i++ {
+		ps.Publish("a/c", i)
+	}
+	assert.Equal(t, ps.Pressure("a/b"), 1.0)
+	assert.Equal(t, ps.Pressure("a/c"), 1.0)
+	assert.Equal(t, ps.Pressure("other"), 0.0)
+}
+
+func TestPressureQueue(t *testing.T) {
+	ps := New(-1)
+	busy, idle := make(chan Event, 2), make(chan Event, 2)
+	assert.Equal(t, ps.QueueSubscribe("jobs", "workers", busy), nil)
+	busy <- Event{}
+	busy <- Event{}
+	assert.Equal(t, ps.Pressure("jobs"), 1.0)
+
+	// the group can still deliver to its idle member
+	assert.Equal(t, ps.QueueSubscribe("jobs", "workers", idle), nil)
+	assert.Equal(t, ps.Pressure("jobs"), 0.0)
+}
@@ -0,0 +1,158 @@
+package pubsub
+
+import "sync"
+
+// HeaderPriority is the header of the Priority of a message, e.g. "high".
+const HeaderPriority = "Pubsub-Priority"
+
+// PublishPriority publishes a message like Publish with priority, which replaces
+// the priority declared for its topic WithPriority. Subscriptions WithPriorityLanes
+// receive the messages of higher priority first.
+func (p *Pubsub) PublishPriority(name string, message interface{}, priority Priority) PublishResult {
+	event := p.newEvent(name, message)
+	event.Headers = map[string]string{HeaderPriority: priority.String()}
+	return p.publishVia(event)
+}
+
+// Priority returns the priority of the message from its HeaderPriority header, or
+// PriorityNormal.
+func (e Event) Priority() Priority {
+	priority, _ := e.priority()
+	return priority
+}
+
+// priorityOf returns the priority of event, or the declared priority of its topic.
+func (p *Pubsub) priorityOf(event Event) Priority {
+	if priority, ok := event.priority(); ok {
+		return priority
+	}
+	return p.priority(event.Name)
+}
+
+func (e Event) priority() (Priority, bool) {
+	switch e.Headers[HeaderPriority] {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	case "critical":
+		return PriorityCritical, true
+	}
+	return PriorityNormal, false
+}
+
+// WithPriorityLanes queues the messages in a lane per Priority of size messages,
+// and sends the messages of the highest priority first, in order within a lane, so
+// control messages don't wait behind a backlog of bulk data. The messages are sent
+// from a goroutine, the channel should have a small buffer because the messages
+// buffered in it are received in order. A message is dropped if its lane is full,
+// or the oldest message of the lane WithOverflow(OverflowDropOldest).
+func WithPriorityLanes(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.lanes = size
+	}
+}
+
+// lanes are the queues of the messages of each priority of a subscriber, sent by
+// a goroutine running while there are queued messages.
+type lanes struct {
+	s          *subscriber
+	size       int
+	dropOldest bool
+
+	mu      sync.Mutex
+	queues  [PriorityCritical + 1][]Event
+	running bool
+	stopped bool          // the subscriber was removed
+	quit    chan struct{} // closed when stopped
+}
+
+// pushLane queues event in its lane of l. The oldest message it replaces is
+// reported once l is unlocked, as the drop hooks may publish.
+func (p *Pubsub) pushLane(l *lanes, event Event) outcome {
+	priority := p.priorityOf(event)
+
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		return dropped
+	}
+	queue := l.queues[priority]
+	var old Event
+	evicted := false
+	if len(queue) >= l.size {
+		if !l.dropOldest {
+			l.mu.Unlock()
+			return dropped
+		}
+		old, evicted = queue[0], true
+		queue = queue[1:]
+	}
+	l.queues[priority] = append(queue, event)
+	if !l.running {
+		l.running = true
+		if l.quit == nil {
+			l.quit = make(chan struct{})
+		}
+		go l.flush(l.quit)
+	}
+	l.mu.Unlock()
+
+	if evicted {
+		p.drop(l.s.c, old, old.Pattern)
+	}
+	return delivered
+}
+
+// next removes the next message to send, with l.mu held.
+func (l *lanes) next() (Event, bool) {
+	for priority := len(l.queues) - 1; priority >= 0; priority-- {
+		if queue := l.queues[priority]; len(queue) > 0 {
+			l.queues[priority] = queue[1:]
+			return queue[0], true
+		}
+	}
+	return Event{}, false
+}
+
+// stop discards the queued messages once the subscriber is removed, while the
+// subscription of its channel may go on.
+func (l *lanes) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return
+	}
+	l.stopped = true
+	l.queues = [len(l.queues)][]Event{}
+	if l.quit != nil {
+		close(l.quit)
+	}
+}
+
+// flush sends the queued messages until there is none, or the subscriber is
+// removed.
+func (l *lanes) flush(quit chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for {
+		event, ok := l.next()
+		if !ok {
+			break
+		}
+		l.mu.Unlock()
+
+		select {
+		case l.s.c <- event:
+			l.mu.Lock()
+		case <-l.s.sub.done:
+			l.mu.Lock()
+			l.queues = [len(l.queues)][]Event{}
+		case <-quit:
+			l.mu.Lock()
+		}
+	}
+	l.running = false
+}
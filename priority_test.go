@@ -0,0 +1,108 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishPriority(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.Subscribe("a", c)
+	ps.PublishPriority("a", 1, PriorityHigh)
+	event := <-c
+	assert.Equal(t, event.Priority(), PriorityHigh)
+	assert.Equal(t, event.Headers[HeaderPriority], "high")
+
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-c).Priority(), PriorityNormal)
+}
+
+func TestPriorityLanes(t *testing.T) {
+	ps := New(-1, WithPriority("control", PriorityCritical))
+	c := make(chan Event)
+	_, err := ps.PSubscribeWith("*", WithChannel(c), WithPriorityLanes(4))
+	assert.Equal(t, err, nil)
+
+	// the first message is sent while the others are queued
+	ps.Publish("bulk", 0)
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < 6; i++ {
+		ps.PublishPriority("bulk", i, PriorityLow)
+	}
+	ps.PublishPriority("bulk", "urgent", PriorityHigh)
+	ps.Publish("control", "cancel")
+
+	var got []interface{}
+	for len(got) < 7 {
+		select {
+		case event := <-c:
+			got = append(got, event.Message)
+		case <-time.After(time.Second):
+			t.Fatalf("got %v", got)
+		}
+	}
+	// the fifth low message was dropped
+	assert.Equal(t, got, []interface{}{0, "cancel", "urgent", 1, 2, 3, 4})
+}
+
+func TestPriorityLanesDropOldest(t *testing.T) {
+	drops := make(chan DroppedMessage, 8)
+	ps := New(-1, OnDrop(func(d DroppedMessage) { drops <- d }))
+	c := make(chan Event)
+	ps.SubscribeWith("a", WithChannel(c), WithPriorityLanes(1), WithOverflow(OverflowDropOldest))
+	ps.Publish("a", 0)
+	time.Sleep(10 * time.Millisecond)
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-c).Message, 0)
+	assert.Equal(t, (<-c).Message, 2)
+	assert.Equal(t, (<-drops).Message, 1)
+}
+
+func TestPriorityLanesUnsubscribe(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	ps.SubscribeWith("a", WithChannel(c), WithPriorityLanes(4))
+	ps.SubscribeWith("b", WithChannel(c), WithPriorityLanes(4))
+
+	// the first message of a is being sent while the second one is queued
+	ps.Publish("a", 0)
+	time.Sleep(10 * time.Millisecond)
+	ps.Publish("a", 1)
+
+	// unsubscribing from a stops sending its messages while c stays subscribed to b
+	ps.Unsubscribe("a", c)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case e := <-c:
+		t.Fatalf("unexpected %v", e)
+	default:
+	}
+	ps.Publish("b", 2)
+	assert.Equal(t, (<-c).Message, 2)
+}
+
+func TestPriorityLanesDropHook(t *testing.T) {
+	var ps *Pubsub
+	drops := make(chan DroppedMessage, 8)
+	ps = New(-1, OnDrop(func(d DroppedMessage) {
+		// the hook may publish to the lanes that dropped the message
+		drops <- d
+		if d.Message == 1 {
+			ps.Publish("a", 3)
+		}
+	}))
+	c := make(chan Event)
+	ps.SubscribeWith("a", WithChannel(c), WithPriorityLanes(1), WithOverflow(OverflowDropOldest))
+	ps.Publish("a", 0)
+	time.Sleep(10 * time.Millisecond)
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-drops).Message, 1)
+	assert.Equal(t, (<-drops).Message, 2)
+	assert.Equal(t, (<-c).Message, 0)
+	assert.Equal(t, (<-c).Message, 3)
+}
@@ -0,0 +1,56 @@
+package pubsub
+
+import "sync/atomic"
+
+// Producer publishes to a single topic. It keeps the subscribers of the topic
+// between messages and only looks them up again after the subscriptions of the
+// Pubsub changed, which saves the lookup when publishing many messages to the same
+// topic. A Producer is safe for concurrent use.
+type Producer struct {
+	p      *Pubsub
+	topic  string
+	routes atomic.Pointer[producerRoutes]
+}
+
+type producerRoutes struct {
+	generation uint64
+	targets    []target
+}
+
+// Producer returns a Producer publishing to topic.
+func (p *Pubsub) Producer(topic string) *Producer {
+	topic = p.normalize(topic)
+	return &Producer{p: p, topic: topic}
+}
+
+// Topic returns the topic of the Producer.
+func (pr *Producer) Topic() string {
+	return pr.topic
+}
+
+// Send publishes message to the topic of the Producer like Publish.
+func (pr *Producer) Send(message interface{}) PublishResult {
+	return pr.p.publishRouted(pr.p.newEvent(pr.topic, message), pr.route)
+}
+
+// route is the route of the Pubsub, cached for the topic of the Producer.
+func (pr *Producer) route(name string, fn func(s *subscriber, pattern string)) {
+	if name != pr.topic {
+		// renamed by a middleware
+		pr.p.route(name, fn)
+		return
+	}
+	// load the generation first, so changes while routing refresh the routes again
+	generation := pr.p.generation.Load()
+	routes := pr.routes.Load()
+	if routes == nil || routes.generation != generation {
+		routes = &producerRoutes{generation: generation}
+		pr.p.route(name, func(s *subscriber, pattern string) {
+			routes.targets = append(routes.targets, target{s, pattern})
+		})
+		pr.routes.Store(routes)
+	}
+	for _, t := range routes.targets {
+		fn(t.s, t.pattern)
+	}
+}
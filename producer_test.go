@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestProducer(t *testing.T) {
+	ps := New(-1)
+	pr := ps.Producer("orders/1")
+	assert.Equal(t, pr.Topic(), "orders/1")
+	assert.Equal(t, pr.Send(0), PublishResult{})
+
+	c := make(chan Event, 10)
+	ps.Subscribe("orders/1", c)
+	assert.Equal(t, pr.Send(1), PublishResult{Delivered: 1})
+	assert.Equal(t, (<-c).Message, 1)
+
+	p := make(chan Event, 10)
+	ps.PSubscribe("orders/*", p)
+	assert.Equal(t, pr.Send(2), PublishResult{Delivered: 2})
+	assert.Equal(t, (<-c).Message, 2)
+	e := <-p
+	assert.Equal(t, e.Message, 2)
+	assert.Equal(t, e.Pattern, "orders/*")
+
+	ps.Unsubscribe("orders/1", c)
+	assert.Equal(t, pr.Send(3), PublishResult{Delivered: 1})
+	assert.Equal(t, (<-p).Message, 3)
+	assert.Equal(t, len(c), 0)
+}
+
+func TestProducerMiddleware(t *testing.T) {
+	ps := New(-1)
+	ps.Use(func(topic string, message interface{}, next func(string, interface{})) {
+		next(topic, message)
+		next("copy", message)
+	})
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.Subscribe("copy", c)
+
+	pr := ps.Producer("a")
+	assert.Equal(t, pr.Send(1), PublishResult{Delivered: 2})
+	assert.Equal(t, (<-c).Name, "a")
+	assert.Equal(t, (<-c).Name, "copy")
+}
+
+func BenchmarkProducer(b *testing.B) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.Subscribe("a", c)
+	ps.PSubscribe("b/*", make(chan Event))
+	pr := ps.Producer("a")
+	for i := 0; i < b.N; i++ {
+		pr.Send(i)
+		<-c
+	}
+}
@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// TimeoutError is returned by PublishCtx when some subscribed channels didn't
+// receive the message before the context is done.
+type TimeoutError struct {
+	Name     string
+	Channels []chan Event
+	Err      error // the error of the context
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("pubsub: %d subscribers of %q didn't receive the message: %v", len(e.Channels), e.Name, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// PublishCtx publishes a message like Publish, but blocks until every subscribed
+// channel received the message or ctx is done. It returns a *TimeoutError with the
+// channels which didn't receive the message in time, they are reported as dropped,
+// or a *SchemaError if the schema of the topic rejects the message.
+func (p *Pubsub) PublishCtx(ctx context.Context, name string, message interface{}) error {
+	var err error
+	p.intercept(p.newEventAs(IdentityFromContext(ctx), name, message), func(event Event) {
+		if e := p.publishCtx(ctx, event); err == nil {
+			err = e
+		}
+	})
+	return err
+}
+
+func (p *Pubsub) publishCtx(ctx context.Context, event Event) error {
+	if err := p.validate(event); err != nil {
+		return err
+	}
+	if p.limits != nil && !p.admit(ctx, event) {
+		return ctx.Err()
+	}
+	var result PublishResult
+	if end := p.trace(ctx, &event); end != nil {
+		defer func() { end(result) }()
+	}
+	name := event.Name
+	// the sequence is released once the message is sent to the channels which
+	// aren't full, before waiting for the others
+	release := p.sequence(&event)
+	p.record(event)
+
+	var targets []target
+	p.route(name, func(s *subscriber, pattern string) {
+		targets = append(targets, target{s, pattern})
+	})
+	p.routeQueues(name, func(g queueGroup) {
+		g.pick(event.Key(), func(s *subscriber) bool {
+			targets = append(targets, target{s, ""})
+			return true
+		})
+	})
+
+	var (
+		wg, tried sync.WaitGroup
+		mu        sync.Mutex
+		timeouts  []chan Event
+	)
+	for _, t := range targets {
+		wg.Add(1)
+		tried.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			o := p.sendCtx(ctx, t.s, event, t.pattern, tried.Done)
+			mu.Lock()
+			defer mu.Unlock()
+			result.add(o)
+			if o == dropped {
+				timeouts = append(timeouts, t.s.c)
+			}
+		}(t)
+	}
+	tried.Wait()
+	release()
+	wg.Wait()
+
+	if len(timeouts) > 0 {
+		return &TimeoutError{
+			Name:     name,
+			Channels: timeouts,
+			Err:      ctx.Err(),
+		}
+	}
+	return nil
+}
+
+// sendCtx sends event to s, waiting until ctx is done or s is unsubscribed. It
+// calls tried once it tried to send event without waiting. Unsubscribing s waits
+// for sendCtx to return.
+func (p *Pubsub) sendCtx(ctx context.Context, s *subscriber, event Event, pattern string, tried func()) outcome {
+	var once sync.Once
+	defer once.Do(tried)
+	s.sub.sending.RLock()
+	defer s.sub.sending.RUnlock()
+
+	if s.sub.State() != Active || (s.pred != nil && !s.pred(event)) ||
+		(s.sample != nil && !s.sample.sample()) {
+		return skipped
+	}
+	id, dup := p.duplicate(s.sub, event)
+	if dup {
+		return skipped
+	}
+	if !s.claim() {
+		if id != "" {
+			s.sub.seen.remove(id)
+		}
+		return skipped
+	}
+	event.Pattern = pattern
+	if s.transform != nil {
+		event.Message = s.transform(event.Message)
+	}
+	select {
+	case s.c <- event:
+		p.sent(s, event.Name, delivered)
+		return delivered
+	default:
+	}
+	once.Do(tried)
+	select {
+	case s.c <- event:
+		p.sent(s, event.Name, delivered)
+	case <-s.sub.done:
+		// unsubscribed while waiting
+		if id != "" {
+			s.sub.seen.remove(id)
+		}
+		return skipped
+	case <-ctx.Done():
+		if id != "" {
+			s.sub.seen.remove(id)
+		}
+		p.sent(s, event.Name, dropped)
+		p.drop(s.c, event, pattern)
+		return dropped
+	}
+	return delivered
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishCtx(t *testing.T) {
+	ps := New(-1)
+	c1 := make(chan Event)
+	c2 := make(chan Event)
+	ps.Subscribe("cmd", c1)
+	ps.PSubscribe("c*", c2)
+
+	go func() {
+		<-c1
+		<-c2
+	}()
+	assert.Equal(t, ps.PublishCtx(context.Background(), "cmd", "run"), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	go func() {
+		<-c2
+	}()
+	err := ps.PublishCtx(ctx, "cmd", "run")
+	var timeout *TimeoutError
+	assert.Equal(t, errors.As(err, &timeout), true)
+	assert.Equal(t, timeout.Channels, []chan Event{c1})
+	assert.Equal(t, errors.Is(err, context.DeadlineExceeded), true)
+	assert.Equal(t, ps.Health().Dropped, uint64(1))
+}
+
+func TestPublishCtxUnsubscribe(t *testing.T) {
+	ps := New(-1)
+	full := make(chan Event)
+	ps.Subscribe("cmd", full)
+
+	done := make(chan error)
+	go func() {
+		done <- ps.PublishCtx(context.Background(), "cmd", "run")
+	}()
+	// let PublishCtx block on full
+	time.Sleep(10 * time.Millisecond)
+
+	// unsubscribing unblocks the blocked send, without stalling other subscribers
+	unsubscribed := make(chan struct{})
+	go func() {
+		ps.Unsubscribe("cmd", full)
+		close(unsubscribed)
+	}()
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("Unsubscribe blocked")
+	}
+	assert.Equal(t, <-done, nil)
+	assert.Equal(t, ps.Subscribe("other", make(chan Event)), nil)
+	assert.Equal(t, ps.Publish("cmd", "run").Delivered, 0)
+}
@@ -4,137 +4,638 @@
 package pubsub
 
 import (
+	"context"
 	"errors"
-	"path/filepath"
+	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Error of meeting max subscribe number.
 var ErrMaxSubscribe = errors.New("subscription is maximum")
 
+// Event is a published message, as received by the subscribed channels.
 type Event struct {
+	ID      string // set if the Pubsub is created WithIDGenerator
 	Name    string
+	Pattern string // the pattern or filter which matched Name, empty if subscribed by name
 	Message interface{}
+	Reply   string            // the topic to reply to, set by Request
+	Time    time.Time         // set if the Pubsub is created WithTimestamps
+	Headers map[string]string // set by PublishMsg
+	Offset  uint64            // the offset in the Store of a durable topic, from 1
+	Expires time.Time         // set by PublishTTL or the HeaderTTL header, zero if the message doesn't expire
+	Seq     uint64            // the number of the message in its topic WithSequence, from 1
+}
+
+// WithTimestamps sets the Time of every published message.
+func WithTimestamps() Option {
+	return func(p *Pubsub) {
+		p.timestamps = true
+	}
 }
 
 // Pubsub implement the Publish/Subscribe messaging paradigm.
+//
+// Subscription changes swap in new copy-on-write routing snapshots, Publish only
+// reads the snapshots and never waits for a lock. Subscriptions by name are split
+// into shards by the hash of the name, so subscribing to names in different shards
+// doesn't serialize, other changes are serialized by a mutex.
 type Pubsub struct {
 	locker   sync.RWMutex
 	max      int
-	channels map[string][]chan Event
-	patterns map[string][]chan Event
+	shards   []*shard
+	patterns map[string][]*subscriber
+	filters  map[string][]*subscriber
+	queues   map[string]map[string]*queueGroup // name -> group -> members
+
+	routes        sync.Map // name -> []*subscriber, snapshot of channels
+	queueRoutes   sync.Map // name -> []queueGroup, snapshot of queues
+	patternRoutes atomic.Pointer[patternRoutes]
+	filterRoutes  atomic.Pointer[filterRoutes]
+	ringRoutes    atomic.Pointer[map[string]*Ring]
+	middleware    atomic.Pointer[[]*Middleware]
+	generation    atomic.Uint64 // incremented after the routing snapshots changed
+	routeCache    routeCache
+
+	deadLetter  chan DroppedMessage
+	onDrop      func(DroppedMessage)
+	topicDrops  *topicDrops
+	window      *statsWindow
+	retained    map[string]Event
+	history     *history
+	rings       map[string]*Ring
+	registry    sync.Mutex
+	subs        map[chan Event]*Subscription // guarded by registry
+	ids         IDGenerator
+	timestamps  bool
+	matcher     Matcher
+	differ      Differ
+	diffs       sync.Mutex // orders the diffs of the retained messages, guards stale
+	codec       Codec
+	normalizer  func(string) string
+	sysPrefix   string // normalized
+	overflow    Overflow
+	shed        *shedder
+	concurrency *concurrency
+	late        *latePolicies
+	sequences   *sequences
+	dispatch    *dispatcher
+	durable     *durables
+	limits      *rateLimits
+	lifecycle   *lifecycle
+	sampler     Sampler
+	tracer      Tracer
+	contextKeys []contextKey
+	auditor     *auditor
+	keys        KeyProvider
+	logger      *slog.Logger
+	flags       *flags
+
+	published atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	stats     *topicStats
+	inboxes   atomic.Uint64
+
+	healthInterval  time.Duration
+	collector       StatsCollector
+	collectInterval time.Duration
+	done            chan struct{}
+	closeOnce       sync.Once
+	shutdown        shutdownHooks
+	scheduler       scheduler
+	bridgeOnce      sync.Once
+	viaID           string // set by bridgeID
+	notices         notices
+	slowThreshold   int
+	maxes           maxes
+	maxSubs         int
+	maxTopics       int
+	numSubs         atomic.Int64
+	numTopics       atomic.Int64
+	expiry          time.Duration
+	keyspace        keyspace
+	dedup           *dedup
+	clock           Clock
+	schemas         schemas
+	activity        sync.Map // name -> *atomic.Int64, unix nanos of the last publish or unsubscribe
+}
+
+// subscriber is a subscription of a channel to a name, a pattern or a filter.
+type subscriber struct {
+	c   chan Event
+	sub *Subscription
+
+	pred      func(Event) bool              // set by SubscribeFiltered
+	sample    *samplerRate                  // set by PSubscribeSampled
+	limit     *subscriberLimit              // set by SubscribeOnce and WithAutoUnsubscribeAfter
+	overflow  Overflow                      // set by WithOverflow
+	diff      bool                          // subscribed with SubscribeDiff, guarded by the write lock
+	stale     bool                          // the diff subscriber missed the last retained message
+	conflate  *conflater                    // set by SubscribeConflated
+	lanes     *lanes                        // set by WithPriorityLanes
+	transform func(interface{}) interface{} // set by WithTransform
+	counters  *topicCounters                // the messages of the route, kept WithTopicStats
+	unwatch   chan struct{}                 // closed when removed, stops watching WithDone
+	ctx       *ctxWatch                     // stopped when removed, set by SubscribeCtx
+}
+
+// shard is a part of the subscriptions by name, guarded by its own mutex.
+type shard struct {
+	mu       sync.Mutex
+	channels map[string][]*subscriber
 }
 
+// defaultShards is the number of shards of a Pubsub created without WithShards.
+const defaultShards = 16
+
+// WithShards splits the subscriptions by name into n shards. More shards reduce the
+// contention of subscribing to many different names concurrently.
+func WithShards(n int) Option {
+	return func(p *Pubsub) {
+		if n > 0 {
+			p.shards = make([]*shard, n)
+		}
+	}
+}
+
+// shard returns the shard of name, using the FNV-1a hash of name.
+func (p *Pubsub) shard(name string) *shard {
+	if len(p.shards) == 1 {
+		return p.shards[0]
+	}
+	return p.shards[fnv32(name)%uint32(len(p.shards))]
+}
+
+// fnv32 returns the FNV-1a hash of s.
+func fnv32(s string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return h
+}
+
+// kind is the kind of subscriptions: by name, by pattern or by filter.
+type kind int
+
+const (
+	byName kind = iota
+	byPattern
+	byFilter
+)
+
 // New return a new Pubsub. The same name or pattern can only have max subscription. No limit if max <= 0.
-func New(max int) *Pubsub {
-	return &Pubsub{
+func New(max int, opts ...Option) *Pubsub {
+	p := &Pubsub{
 		max:      max,
-		channels: make(map[string][]chan Event),
-		patterns: make(map[string][]chan Event),
+		shards:   make([]*shard, defaultShards),
+		patterns: make(map[string][]*subscriber),
+		filters:  make(map[string][]*subscriber),
+		queues:   make(map[string]map[string]*queueGroup),
+		retained: make(map[string]Event),
+		rings:    make(map[string]*Ring),
+		subs:     make(map[chan Event]*Subscription),
+		matcher:  GlobMatcher{},
+		flags:    newFlags(),
+		clock:    systemClock{},
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.sysPrefix = p.normalize(sysPrefix)
+	for i := range p.shards {
+		p.shards[i] = &shard{channels: make(map[string][]*subscriber)}
+	}
+	p.changed(byPattern, "")
+	p.changed(byFilter, "")
+	p.startDispatch()
+	if p.healthInterval > 0 {
+		go p.reportHealth(p.healthInterval)
+	}
+	if p.collector != nil && p.collectInterval > 0 {
+		go p.collectStats(p.collectInterval)
+	}
+	if p.shed != nil && p.shed.enabled && p.shed.interval > 0 {
+		go p.reportShed(p.shed.interval)
+	}
+	if p.expiry > 0 {
+		go p.expireTopics(p.expiry)
 	}
+	return p
 }
 
-// Subscribe the message with specified name and send to channel c.
+// Close stops the background goroutines of the Pubsub, discards the scheduled
+// messages and delivers the dispatched ones. Subscriptions are kept and Publish
+// keeps working after Close. See Shutdown to drain the subscriptions first.
+func (p *Pubsub) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		if p.dispatch != nil {
+			p.dispatch.stop()
+		}
+	})
+	return nil
+}
+
+// Subscribe the message with specified name and send to channel c. If name has a
+// declared LatePolicy, c is backfilled according to the policy.
 func (p *Pubsub) Subscribe(name string, c chan Event) error {
+	name = p.normalize(name)
 	if c == nil {
 		return nil
 	}
+	if p.late != nil {
+		if ok, err := p.subscribeDeclared(name, c); ok {
+			return err
+		}
+	}
+
+	// the shard of name guards the subscription
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	_, err := p.add(byName, name, c)
+	return err
+}
+
+// SubscribeMany subscribes channel c to all names at once. If any of the names has
+// max subscriptions, c isn't subscribed to any of them and ErrMaxSubscribe is
+// returned, likewise for the error of another limit of the Pubsub. Unsubscribe of
+// the returned MultiSubscription unsubscribes c from the names it wasn't
+// subscribed to before.
+func (p *Pubsub) SubscribeMany(c chan Event, names ...string) (*MultiSubscription, error) {
+	if c == nil || len(names) == 0 {
+		return nil, nil
+	}
+	if p.normalizer != nil {
+		normalized := make([]string, len(names))
+		for i, name := range names {
+			normalized[i] = p.normalize(name)
+		}
+		names = normalized
+	}
 
 	p.locker.Lock()
 	defer p.locker.Unlock()
 
-	if p.subscribe(p.channels, name, c) {
-		return nil
+	// the write lock excludes Subscribe, the shards can't change
+	for _, name := range names {
+		subs := p.shard(name).channels[name]
+		if p.findChan(subs, c) >= 0 {
+			continue
+		}
+		if max := p.maxFor(byName, name); max > 0 && len(subs) >= max {
+			return nil, ErrMaxSubscribe
+		}
+	}
+	var (
+		sub   *Subscription
+		added []string
+	)
+	for _, name := range names {
+		subscribed := p.findChan(p.shard(name).channels[name], c) >= 0
+		s, err := p.add(byName, name, c)
+		if err != nil {
+			// another limit of the Pubsub
+			for _, name := range added {
+				p.remove(byName, name, c)
+			}
+			return nil, err
+		}
+		if !subscribed {
+			added = append(added, name)
+		}
+		sub = s.sub
 	}
-	return ErrMaxSubscribe
+	return &MultiSubscription{Subscription: sub, names: added}, nil
 }
 
-// Unsubscribe the channel c with specified name.
-func (p *Pubsub) Unsubscribe(name string, c chan Event) {
+// SubscribeFiltered subscribes channel c to name like Subscribe, but only sends the
+// messages for which pred returns true. pred is called by Publish, so it should be
+// fast and must not block. Subscribing c to name again with SubscribeFiltered
+// replaces pred.
+func (p *Pubsub) SubscribeFiltered(name string, c chan Event, pred func(Event) bool) error {
+	name = p.normalize(name)
 	if c == nil {
-		return
+		return nil
 	}
 
-	p.locker.Lock()
-	defer p.locker.Unlock()
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 
-	chans, ok := p.channels[name]
-	if !ok {
-		return
-	}
-	i := p.findChan(chans, c)
-	if i < 0 {
+	_, err := p.addWith(byName, name, c, func(s *subscriber) { s.pred = pred })
+	return err
+}
+
+// Unsubscribe the channel c with specified name. A concurrent Publish may still
+// send to c until Unsubscribe returns.
+func (p *Pubsub) Unsubscribe(name string, c chan Event) {
+	name = p.normalize(name)
+	if c == nil {
 		return
 	}
-	p.unsubscribe(p.channels, name, i)
+
+	defer p.awaitSends(p.Subscription(c))
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	p.remove(byName, name, c)
 }
 
 // PSubscribe subscribe the message with the specified pattern and send to channel c.
-// Pattern supported glob-style patterns:
+// Patterns are matched by the Matcher of the Pubsub, the default GlobMatcher
+// supports glob-style patterns:
+//
+//   - h?llo matches hello, hallo and hxllo
+//   - h*llo matches hllo and heeeello
+//   - h[ae]llo matches hello and hallo, but not hillo
 //
-//  - h?llo matches hello, hallo and hxllo
-//  - h*llo matches hllo and heeeello
-//  - h[ae]llo matches hello and hallo, but not hillo
+// It returns an error wrapping ErrBadPattern if the Matcher is a PatternValidator
+// and pattern is malformed, e.g. h[llo.
 func (p *Pubsub) PSubscribe(pattern string, c chan Event) error {
+	pattern = p.normalize(pattern)
 	if c == nil {
 		return nil
 	}
+	if err := p.validatePattern(pattern); err != nil {
+		return err
+	}
 
 	p.locker.Lock()
 	defer p.locker.Unlock()
 
-	if p.subscribe(p.patterns, pattern, c) {
-		return nil
+	_, err := p.add(byPattern, pattern, c)
+	return err
+}
+
+// MustPSubscribe is like PSubscribe but panics if the pattern is malformed or has
+// max subscriptions.
+func (p *Pubsub) MustPSubscribe(pattern string, c chan Event) {
+	if err := p.PSubscribe(pattern, c); err != nil {
+		panic(err)
 	}
-	return ErrMaxSubscribe
 }
 
 // PUnsubscribe unsubscribes the channel c with the specified pattern.
 func (p *Pubsub) PUnsubscribe(pattern string, c chan Event) {
+	pattern = p.normalize(pattern)
 	if c == nil {
 		return
 	}
 
+	defer p.awaitSends(p.Subscription(c))
 	p.locker.Lock()
 	defer p.locker.Unlock()
+	p.remove(byPattern, pattern, c)
+}
 
-	chans, ok := p.patterns[pattern]
-	if !ok {
-		return
-	}
-	i := p.findChan(chans, c)
-	if i < 0 {
-		return
-	}
-	p.unsubscribe(p.patterns, pattern, i)
+// PublishResult is the result of delivering a published message.
+type PublishResult struct {
+	Delivered int   // number of channels which received the message
+	Dropped   int   // number of channels which weren't ready to receive the message
+	Err       error // the error rejecting the message, e.g. a *SchemaError
 }
 
 // Publish a message with specifid name. Publish won't be blocked by channel receiving,
-// if a channel doesn't ready when publish, it will be ignored.
-func (p *Pubsub) Publish(name string, message interface{}) {
-	p.locker.RLock()
-	defer p.locker.RUnlock()
+// if a channel doesn't ready when publish, it will be ignored and reported as dropped.
+func (p *Pubsub) Publish(name string, message interface{}) PublishResult {
+	return p.publishVia(p.newEvent(name, message))
+}
+
+// PublishMsg publishes a message like Publish, with headers. The headers must not
+// be modified after PublishMsg, they are shared by all receivers.
+func (p *Pubsub) PublishMsg(name string, message interface{}, headers map[string]string) PublishResult {
+	event := p.newEvent(name, message)
+	event.Headers = headers
+	event.expiresFrom(headers, p.clock.Now())
+	return p.publishVia(event)
+}
+
+// PublishPattern publishes a message like Publish to every name which has
+// subscribers and matches pattern, matched by the Matcher of the Pubsub.
+func (p *Pubsub) PublishPattern(pattern string, message interface{}) PublishResult {
+	pattern = p.normalize(pattern)
+	var result PublishResult
+	for _, name := range p.Topics() {
+		if p.matcher.Match(pattern, name) {
+			result.merge(p.Publish(name, message))
+		}
+	}
+	return result
+}
+
+func (p *Pubsub) newEvent(name string, message interface{}) Event {
+	return p.newEventAs("", name, message)
+}
+
+// newEventAs returns the event of a message published by identity.
+func (p *Pubsub) newEventAs(identity, name string, message interface{}) Event {
 	event := Event{
-		Name:    name,
+		Name:    p.normalize(name),
 		Message: message,
 	}
-	if chans, ok := p.channels[name]; ok {
-		for _, c := range chans {
-			select {
-			case c <- event:
-			default:
-			}
+	if p.ids != nil {
+		event.ID = p.ids.NewID()
+	}
+	if p.timestamps {
+		event.Time = p.clock.Now()
+	}
+	if p.auditor != nil {
+		p.audit(AuditRecord{Action: AuditPublish, Identity: identity, Name: event.Name, ID: event.ID, Message: message})
+	}
+	return event
+}
+
+func (p *Pubsub) publish(event Event) PublishResult {
+	return p.publishOn(event, nil)
+}
+
+// publishOn publishes event to the subscribers found by route, or queues it for the
+// dispatch workers.
+func (p *Pubsub) publishOn(event Event, route router) PublishResult {
+	if err := p.validate(event); err != nil {
+		return PublishResult{Err: err}
+	}
+	if p.dispatch != nil && p.dispatch.enqueue(event, route) {
+		return PublishResult{}
+	}
+	return p.publishNow(event, route)
+}
+
+// publishNow publishes event to the subscribers found by route, under the rate limit
+// of its topic.
+func (p *Pubsub) publishNow(event Event, route router) PublishResult {
+	if p.limits != nil && !p.admit(context.Background(), event) {
+		return PublishResult{}
+	}
+	return p.publishTo(event, route)
+}
+
+// publishTo publishes event to the subscribers found by route.
+func (p *Pubsub) publishTo(event Event, route router) PublishResult {
+	if p.tracer != nil {
+		// a copy, so event doesn't escape when not traced
+		traced := event
+		if end := p.trace(context.Background(), &traced); end != nil {
+			result := p.deliver(traced, route)
+			end(result)
+			return result
 		}
 	}
-	for pattern, chans := range p.patterns {
-		if ok, err := filepath.Match(pattern, name); err == nil && ok {
-			for _, c := range chans {
-				select {
-				case c <- event:
-				default:
-				}
+	return p.deliver(event, route)
+}
+
+// deliver sends event to the subscribers found by route.
+func (p *Pubsub) deliver(event Event, route router) PublishResult {
+	defer p.sequence(&event)()
+	p.record(event)
+	var result PublishResult
+	if route == nil && !p.flags.parallelFanout.Load() {
+		// the common case, without the closures of route
+		if v, ok := p.routes.Load(event.Name); ok {
+			for _, s := range v.([]*subscriber) {
+				result.add(p.send(s, event, ""))
 			}
 		}
+		for _, t := range p.matches(event.Name) {
+			result.add(p.send(t.s, event, t.pattern))
+		}
+	} else {
+		result = p.deliverRouted(event, route)
+	}
+	p.sendQueues(event, &result)
+	return result
+}
+
+// deliverRouted sends event to the subscribers found by route, but not to the
+// queue groups.
+func (p *Pubsub) deliverRouted(event Event, route router) PublishResult {
+	if route == nil {
+		route = p.route
+	}
+	var result PublishResult
+	if p.flags.parallelFanout.Load() {
+		var targets []target
+		route(event.Name, func(s *subscriber, pattern string) {
+			targets = append(targets, target{s, pattern})
+		})
+		if len(targets) >= parallelFanoutMin {
+			return p.fanout(targets, event)
+		}
+		for _, t := range targets {
+			result.add(p.send(t.s, event, t.pattern))
+		}
+		return result
+	}
+	route(event.Name, func(s *subscriber, pattern string) {
+		result.add(p.send(s, event, pattern))
+	})
+	return result
+}
+
+// router finds the subscribers of a name, like route. A nil router is route, with
+// the matches of the patterns and filters kept in the route cache.
+type router func(name string, fn func(s *subscriber, pattern string))
+
+// route calls fn with every subscriber of name, with the pattern or filter which
+// matched name, or an empty pattern if subscribed by name.
+func (p *Pubsub) route(name string, fn func(s *subscriber, pattern string)) {
+	p.routeGroups(name, func(_ kind, pattern string, subs []*subscriber) {
+		for _, s := range subs {
+			fn(s, pattern)
+		}
+	})
+}
+
+// routeGroups calls fn with the subscribers of name by name, with an empty pattern,
+// then of every pattern and filter matching name, in the order of delivery.
+func (p *Pubsub) routeGroups(name string, fn func(k kind, pattern string, subs []*subscriber)) {
+	if v, ok := p.routes.Load(name); ok {
+		fn(byName, "", v.([]*subscriber))
+	}
+	patterns := p.patternRoutes.Load()
+	match := func(pattern string) {
+		if p.matcher.Match(pattern, name) {
+			fn(byPattern, pattern, patterns.subs[pattern])
+		}
+	}
+	if patterns.index != nil && p.flags.patternIndex.Load() {
+		patterns.index.candidates(name, match)
+	} else {
+		for pattern := range patterns.subs {
+			match(pattern)
+		}
+	}
+	if filters := p.filterRoutes.Load(); len(filters.subs) > 0 {
+		filters.trie.match(name, func(filter string) {
+			fn(byFilter, filter, filters.subs[filter])
+		})
+	}
+}
+
+// target is a subscriber which a message is routed to.
+type target struct {
+	s       *subscriber
+	pattern string
+}
+
+// outcome is the outcome of sending a message to a subscriber.
+type outcome int
+
+const (
+	skipped outcome = iota
+	delivered
+	dropped
+)
+
+func (r *PublishResult) merge(o PublishResult) {
+	r.Delivered += o.Delivered
+	r.Dropped += o.Dropped
+	if o.Err != nil {
+		r.Err = errors.Join(r.Err, o.Err)
+	}
+}
+
+func (r *PublishResult) add(o outcome) {
+	switch o {
+	case delivered:
+		r.Delivered++
+	case dropped:
+		r.Dropped++
+	}
+}
+
+// record does the bookkeeping of a published event before it's delivered.
+func (p *Pubsub) record(event Event) {
+	p.published.Add(1)
+	p.touch(event.Name)
+	p.notifyKeyspace(KeyspacePublish, event.Name, nil, event.ID)
+	if p.stats != nil {
+		p.stats.topic(event.Name).published.Add(1)
+	}
+	if p.late != nil {
+		p.started(event.Name)
+	}
+	if p.durable != nil {
+		p.appendDurable(event)
+	}
+	if p.window != nil || p.history != nil {
+		now := p.clock.Now()
+		if p.window != nil {
+			p.window.published(event.Name, now)
+		}
+		if p.history != nil {
+			p.history.add(event, now)
+		}
+	}
+	if rings := p.ringRoutes.Load(); rings != nil {
+		if r, ok := (*rings)[event.Name]; ok {
+			r.publish(event)
+		}
 	}
 }
 
@@ -144,56 +645,238 @@ func (p *Pubsub) UnsubscribeAll(c chan Event) {
 		return
 	}
 
+	defer p.awaitSends(p.Subscription(c))
 	p.locker.Lock()
 	defer p.locker.Unlock()
+	p.removeAll(c)
+}
 
-	type Find struct {
-		name  string
-		index int
+// removeAll unsubscribes c from everything. It must be called with the write lock
+// held.
+func (p *Pubsub) removeAll(c chan Event) {
+	for _, sh := range p.shards {
+		sh.mu.Lock()
+		p.unsubscribeAll(byName, sh.channels, c)
+		sh.mu.Unlock()
 	}
-	for _, collection := range []map[string][]chan Event{p.channels, p.patterns} {
-		var finds []Find
-		for name, chans := range collection {
-			if i := p.findChan(chans, c); i >= 0 {
-				finds = append(finds, Find{name, i})
-			}
+	p.unsubscribeAll(byPattern, p.patterns, c)
+	p.unsubscribeAll(byFilter, p.filters, c)
+	p.removeQueues(c)
+}
+
+// unsubscribeAll unsubscribes c from every name of collection.
+func (p *Pubsub) unsubscribeAll(k kind, collection map[string][]*subscriber, c chan Event) {
+	var names []string
+	for name, subs := range collection {
+		if p.findChan(subs, c) >= 0 {
+			names = append(names, name)
 		}
-		for _, find := range finds {
-			p.unsubscribe(collection, find.name, find.index)
+	}
+	for _, name := range names {
+		p.removeAt(k, collection, name, p.findChan(collection[name], c))
+		if k == byName {
+			p.changed(k, name)
 		}
 	}
+	if k != byName && len(names) > 0 {
+		p.changed(k, "")
+	}
 }
 
-func (p *Pubsub) subscribe(collection map[string][]chan Event, name string, c chan Event) bool {
-	chans, ok := collection[name]
-	if !ok {
-		chans = []chan Event{c}
-	} else {
-		if p.findChan(chans, c) >= 0 {
-			return true
+func (p *Pubsub) send(s *subscriber, event Event, pattern string) outcome {
+	o := p.trySend(s, event, pattern)
+	if o == dropped {
+		p.drop(s.c, event, pattern)
+	}
+	return o
+}
+
+// trySend sends event to s without blocking, and without reporting it dropped.
+func (p *Pubsub) trySend(s *subscriber, event Event, pattern string) outcome {
+	sub := s.sub
+	sub.sending.RLock()
+	defer sub.sending.RUnlock()
+
+	if sub.State() != Active {
+		return skipped
+	}
+	if s.pred != nil && !s.pred(event) {
+		return skipped
+	}
+	if s.sample != nil && !s.sample.sample() {
+		return skipped
+	}
+	id, dup := p.duplicate(sub, event)
+	if dup {
+		return skipped
+	}
+	if !s.claim() {
+		if id != "" {
+			sub.seen.remove(id)
 		}
-		if p.max > 0 && len(chans) >= p.max {
-			return false
+		return skipped
+	}
+	o := dropped
+	if !p.shedding(s.c, event) {
+		event.Pattern = pattern
+		if s.transform != nil {
+			event.Message = s.transform(event.Message)
 		}
-		chans = append(chans, c)
+		if s.lanes != nil {
+			o = p.pushLane(s.lanes, event)
+		} else if s.conflate != nil && s.conflate.replace(event) {
+			o = delivered
+		} else {
+			select {
+			case s.c <- event:
+				o = delivered
+			default:
+				if s.conflate != nil {
+					s.conflate.keep(event)
+					o = delivered
+				} else if s.overflow == OverflowDropOldest {
+					o = p.replaceOldest(s, event)
+				}
+			}
+		}
+	}
+	if o == dropped && id != "" {
+		sub.seen.remove(id)
+	}
+	p.sent(s, event.Name, o)
+	return o
+}
+
+// collection returns the subscriptions of kind k, and locks the shard of name if k
+// is byName. Patterns and filters are guarded by the write lock.
+func (p *Pubsub) collection(k kind, name string) (map[string][]*subscriber, func()) {
+	switch k {
+	case byPattern:
+		return p.patterns, func() {}
+	case byFilter:
+		return p.filters, func() {}
 	}
-	collection[name] = chans
-	return true
+	sh := p.shard(name)
+	sh.mu.Lock()
+	return sh.channels, sh.mu.Unlock
 }
 
-func (p *Pubsub) unsubscribe(collection map[string][]chan Event, name string, i int) {
-	chans := collection[name]
-	chans = append(chans[:i], chans[i+1:]...)
-	if len(chans) == 0 {
+// add subscribes c to name and updates the routing snapshots. It returns
+// ErrMaxSubscribe if name has max subscriptions, or the error of another limit of
+// the Pubsub.
+func (p *Pubsub) add(k kind, name string, c chan Event) (*subscriber, error) {
+	return p.addWith(k, name, c, nil)
+}
+
+// addWith is add, which calls init with a new subscriber, or with a copy of the
+// subscriber replacing it if c is already subscribed to name.
+func (p *Pubsub) addWith(k kind, name string, c chan Event, init func(s *subscriber)) (*subscriber, error) {
+	collection, unlock := p.collection(k, name)
+	defer unlock()
+
+	subs := collection[name]
+	if i := p.findChan(subs, c); i >= 0 {
+		if init == nil {
+			return subs[i], nil
+		}
+		// copy, the routing snapshots may share the subscriber and the slice
+		s := *subs[i]
+		init(&s)
+		subs = append(subs[:i:i], subs[i:]...)
+		subs[i] = &s
+		collection[name] = subs
+		p.changed(k, name)
+		return &s, nil
+	}
+	if err := p.admitSubscriber(k, name, c, len(subs)); err != nil {
+		return nil, err
+	}
+	s := p.newSubscriber(c)
+	if init != nil {
+		init(s)
+	}
+	collection[name] = append(subs, s)
+	p.changed(k, name)
+	p.audit(AuditRecord{Action: AuditSubscribe, Name: name, Channel: c})
+	p.logSubscription("pubsub subscribe", RouteKind(k), name, "", c)
+	if len(subs) == 0 && (k != byName || len(p.queues[name]) == 0) {
+		p.emit(k, true, name)
+	}
+	if k == byName {
+		p.notifyKeyspace(KeyspaceSubscribe, name, c, "")
+	}
+	return s, nil
+}
+
+// remove unsubscribes c from name and updates the routing snapshots.
+func (p *Pubsub) remove(k kind, name string, c chan Event) {
+	collection, unlock := p.collection(k, name)
+	defer unlock()
+
+	if i := p.findChan(collection[name], c); i >= 0 {
+		p.removeAt(k, collection, name, i)
+		p.changed(k, name)
+	}
+}
+
+// removeAt unsubscribes the i-th subscriber of name, without updating the routing
+// snapshots.
+func (p *Pubsub) removeAt(k kind, collection map[string][]*subscriber, name string, i int) {
+	subs := collection[name]
+	if subs[i].unwatch != nil {
+		close(subs[i].unwatch)
+	}
+	subs[i].unwatchCtx()
+	if subs[i].conflate != nil {
+		subs[i].conflate.stop()
+	}
+	if subs[i].lanes != nil {
+		subs[i].lanes.stop()
+	}
+	p.audit(AuditRecord{Action: AuditUnsubscribe, Name: name, Channel: subs[i].c})
+	p.logSubscription("pubsub unsubscribe", RouteKind(k), name, "", subs[i].c)
+	p.unref(subs[i].c)
+	p.numSubs.Add(-1)
+	if k == byName {
+		p.notifyKeyspace(KeyspaceUnsubscribe, name, subs[i].c, "")
+	}
+	// copy, the routing snapshots may share the slice
+	subs = append(subs[:i:i], subs[i+1:]...)
+	if len(subs) == 0 {
+		if k == byName {
+			p.numTopics.Add(-1)
+			p.touch(name)
+		}
 		delete(collection, name)
+		if k != byName || len(p.queues[name]) == 0 {
+			p.emit(k, false, name)
+		}
 	} else {
-		collection[name] = chans
+		collection[name] = subs
+	}
+}
+
+// changed updates the routing snapshot after the subscribers of name changed. The
+// shard of name must be locked if k is byName.
+func (p *Pubsub) changed(k kind, name string) {
+	defer p.generation.Add(1)
+	switch k {
+	case byName:
+		if subs, ok := p.shard(name).channels[name]; ok {
+			p.routes.Store(name, subs)
+		} else {
+			p.routes.Delete(name)
+		}
+	case byPattern:
+		p.patternRoutes.Store(newPatternRoutes(p.patterns, p.matcher))
+	case byFilter:
+		p.filterRoutes.Store(newFilterRoutes(p.filters))
 	}
 }
 
-func (p *Pubsub) findChan(chans []chan Event, c chan Event) int {
-	for i, ch := range chans {
-		if ch == c {
+func (p *Pubsub) findChan(subs []*subscriber, c chan Event) int {
+	for i, s := range subs {
+		if s.c == c {
 			return i
 		}
 	}
@@ -1,6 +1,8 @@
 package pubsub
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,26 +31,26 @@ func TestUnsub(t *testing.T) {
 	c2 := make(chan Event)
 	ps := New(-1)
 
-	assert.Equal(t, len(ps.channels), 0)
+	assert.Equal(t, len(ps.Topics()), 0)
 	ps.Subscribe("sub", nil)
-	assert.Equal(t, len(ps.channels), 0)
+	assert.Equal(t, len(ps.Topics()), 0)
 
 	ps.Subscribe("sub", c1)
 	ps.Subscribe("sub", c2)
 	ps.Subscribe("sub", c2)
 
-	assert.Equal(t, len(ps.channels), 1)
-	assert.Equal(t, len(ps.channels["sub"]), 2)
+	assert.Equal(t, len(ps.Topics()), 1)
+	assert.Equal(t, ps.NumSubscribers("sub"), 2)
 
 	ps.Unsubscribe("sub", c1)
-	assert.Equal(t, len(ps.channels["sub"]), 1)
+	assert.Equal(t, ps.NumSubscribers("sub"), 1)
 	ps.Subscribe("sub1", c1)
-	assert.Equal(t, len(ps.channels["sub"]), 1)
-	assert.Equal(t, len(ps.channels["sub1"]), 1)
+	assert.Equal(t, ps.NumSubscribers("sub"), 1)
+	assert.Equal(t, ps.NumSubscribers("sub1"), 1)
 	ps.Unsubscribe("sub", c2)
 	ps.Unsubscribe("sub1", c1)
 
-	assert.Equal(t, len(ps.channels), 0)
+	assert.Equal(t, len(ps.Topics()), 0)
 
 	assert.Equal(t, len(ps.patterns), 0)
 	ps.PSubscribe("sub*", nil)
@@ -134,6 +136,140 @@ func TestPubsub(t *testing.T) {
 	}
 }
 
+func TestSubscribeMany(t *testing.T) {
+	ps := New(1)
+	c := make(chan Event, 2)
+	ps.Subscribe("e", c)
+	s, err := ps.SubscribeMany(c, "a", "b", "a", "e")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ps.Topics(), []string{"a", "b", "e"})
+
+	d := make(chan Event, 1)
+	ps.Subscribe("d", d)
+	_, err = ps.SubscribeMany(d, "c", "b")
+	assert.Equal(t, err, ErrMaxSubscribe)
+	assert.Equal(t, ps.Topics(), []string{"a", "b", "d", "e"})
+
+	ps.Publish("a", 1)
+	ps.Publish("b", 2)
+	assert.Equal(t, len(c), 2)
+	// the subscription c had before goes on
+	s.Unsubscribe()
+	assert.Equal(t, ps.Topics(), []string{"d", "e"})
+	assert.Equal(t, s.State(), Active)
+}
+
+func TestSubscribeFiltered(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	even := func(e Event) bool { return e.Message.(int)%2 == 0 }
+	assert.Equal(t, ps.SubscribeFiltered("n", c, even), nil)
+
+	assert.Equal(t, ps.Publish("n", 1), PublishResult{})
+	assert.Equal(t, ps.Publish("n", 2), PublishResult{Delivered: 1})
+	assert.Equal(t, (<-c).Message, 2)
+
+	odd := func(e Event) bool { return !even(e) }
+	ps.SubscribeFiltered("n", c, odd)
+	assert.Equal(t, ps.NumSubscribers("n"), 1)
+	assert.Equal(t, ps.Publish("n", 2), PublishResult{})
+	assert.Equal(t, ps.Publish("n", 3), PublishResult{Delivered: 1})
+}
+
+func TestPublishResult(t *testing.T) {
+	ps := New(-1)
+	assert.Equal(t, ps.Publish("nobody", 1), PublishResult{})
+
+	ready := make(chan Event, 1)
+	full := make(chan Event)
+	ps.Subscribe("topic", ready)
+	ps.Subscribe("topic", full)
+	ps.PSubscribe("top*", ready)
+	assert.Equal(t, ps.Publish("topic", 1), PublishResult{Delivered: 1, Dropped: 2})
+	assert.Equal(t, ps.PublishRetain("topic", 2), PublishResult{Dropped: 3})
+}
+
+func TestPublishMsg(t *testing.T) {
+	ps := New(-1, WithTimestamps())
+	c := make(chan Event, 1)
+	ps.PSubscribe("orders.*", c)
+
+	headers := map[string]string{"Tenant": "acme"}
+	ps.PublishMsg("orders.1", 1, headers)
+	e := <-c
+	assert.Equal(t, e.Name, "orders.1")
+	assert.Equal(t, e.Pattern, "orders.*")
+	assert.Equal(t, e.Headers, headers)
+	assert.Equal(t, e.Time.IsZero(), false)
+
+	ps = New(-1)
+	ps.Subscribe("orders.1", c)
+	ps.Publish("orders.1", 2)
+	e = <-c
+	assert.Equal(t, e.Pattern, "")
+	assert.Equal(t, e.Time.IsZero(), true)
+}
+
+func TestPublishPattern(t *testing.T) {
+	ps := New(-1)
+	w1 := make(chan Event, 1)
+	w2 := make(chan Event, 1)
+	other := make(chan Event, 1)
+	ps.Subscribe("worker.1", w1)
+	ps.Subscribe("worker.2", w2)
+	ps.Subscribe("other", other)
+
+	assert.Equal(t, ps.PublishPattern("worker.*", "shutdown"), PublishResult{Delivered: 2})
+	assert.Equal(t, <-w1, Event{Name: "worker.1", Message: "shutdown"})
+	assert.Equal(t, <-w2, Event{Name: "worker.2", Message: "shutdown"})
+	assert.Equal(t, len(other), 0)
+	assert.Equal(t, ps.PublishPattern("nothing.*", "shutdown"), PublishResult{})
+}
+
+func TestPublishConcurrent(t *testing.T) {
+	ps := New(-1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			ps.Publish("topic", i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		c := make(chan Event, 1)
+		ps.Subscribe("topic", c)
+		ps.PSubscribe("top*", c)
+		ps.UnsubscribeAll(c)
+		// no Publish sends to c after UnsubscribeAll returned
+		close(c)
+	}
+	<-done
+}
+
+func TestShards(t *testing.T) {
+	ps := New(-1, WithShards(4))
+	assert.Equal(t, len(ps.shards), 4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := make(chan Event, 1)
+			name := fmt.Sprintf("topic%d", i)
+			for j := 0; j < 100; j++ {
+				ps.Subscribe(name, c)
+				ps.Publish(name, j)
+				ps.Unsubscribe(name, c)
+			}
+			ps.Subscribe(name, c)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, len(ps.Topics()), 8)
+	assert.Equal(t, ps.Health().Subscriptions, 8)
+}
+
 /*
 func TestPubsubMax(t *testing.T) {
 	p := New(2)
@@ -260,3 +396,40 @@ func TestPubsubUnsubscribeAll(t *testing.T) {
 	assert.Equal(t, len(p.patterns), 0)
 }
 */
+
+func BenchmarkPublish(b *testing.B) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.Subscribe("orders", c)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish("orders", "message")
+		<-c
+	}
+}
+
+func BenchmarkPublishPatterns(b *testing.B) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.PSubscribe("orders/*", c)
+	for i := 0; i < 100; i++ {
+		ps.PSubscribe(fmt.Sprintf("service%d/*", i), make(chan Event))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish("orders/1", "message")
+		<-c
+	}
+}
+
+func BenchmarkPublishNoSubscribers(b *testing.B) {
+	ps := New(-1)
+	ps.PSubscribe("orders/*", make(chan Event))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ps.Publish("users/1", "message")
+	}
+}
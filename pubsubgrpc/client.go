@@ -0,0 +1,68 @@
+package pubsubgrpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// Client is a Go client of the Pubsub service.
+type Client struct {
+	c PubsubClient
+}
+
+// NewClient creates a Client calling the service with cc.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{c: NewPubsubClient(cc)}
+}
+
+// Publish publishes the data of req to its topic.
+func (c *Client) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	return c.c.Publish(ctx, req)
+}
+
+// Subscribe opens a stream of the messages of topics. The stream ends when ctx is
+// done.
+func (c *Client) Subscribe(ctx context.Context, topics ...string) (*Stream, error) {
+	return c.open(ctx, c.c.Subscribe, topics)
+}
+
+// PSubscribe opens a stream of the messages of patterns. The stream ends when ctx
+// is done.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*Stream, error) {
+	return c.open(ctx, c.c.PSubscribe, patterns)
+}
+
+func (c *Client) open(ctx context.Context, call func(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeRequest, Message], error), names []string) (*Stream, error) {
+	cs, err := call(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stream{cs}
+	if len(names) > 0 {
+		if err := s.Subscribe(names...); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Stream is a stream of messages of a Client.
+type Stream struct {
+	cs grpc.BidiStreamingClient[SubscribeRequest, Message]
+}
+
+// Subscribe adds names to the topics or patterns of the stream.
+func (s *Stream) Subscribe(names ...string) error {
+	return s.cs.Send(&SubscribeRequest{Subscribe: names})
+}
+
+// Unsubscribe removes names from the topics or patterns of the stream.
+func (s *Stream) Unsubscribe(names ...string) error {
+	return s.cs.Send(&SubscribeRequest{Unsubscribe: names})
+}
+
+// Recv receives the next message.
+func (s *Stream) Recv() (*Message, error) {
+	return s.cs.Recv()
+}
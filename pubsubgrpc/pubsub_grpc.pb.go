@@ -0,0 +1,198 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: pubsub.proto
+
+package pubsubgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Pubsub_Publish_FullMethodName    = "/pubsub.v1.Pubsub/Publish"
+	Pubsub_Subscribe_FullMethodName  = "/pubsub.v1.Pubsub/Subscribe"
+	Pubsub_PSubscribe_FullMethodName = "/pubsub.v1.Pubsub/PSubscribe"
+)
+
+// PubsubClient is the client API for Pubsub service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Pubsub exposes a topic space to publishers and subscribers in other processes.
+type PubsubClient interface {
+	// Publish publishes a message to a topic.
+	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error)
+	// Subscribe streams the messages of the topics the client subscribes to with
+	// the requests it sends, until it cancels the call.
+	Subscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeRequest, Message], error)
+	// PSubscribe is like Subscribe, with patterns instead of topics.
+	PSubscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeRequest, Message], error)
+}
+
+type pubsubClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPubsubClient(cc grpc.ClientConnInterface) PubsubClient {
+	return &pubsubClient{cc}
+}
+
+func (c *pubsubClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (*PublishResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishResponse)
+	err := c.cc.Invoke(ctx, Pubsub_Publish_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pubsubClient) Subscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeRequest, Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Pubsub_ServiceDesc.Streams[0], Pubsub_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Message]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pubsub_SubscribeClient = grpc.BidiStreamingClient[SubscribeRequest, Message]
+
+func (c *pubsubClient) PSubscribe(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SubscribeRequest, Message], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Pubsub_ServiceDesc.Streams[1], Pubsub_PSubscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, Message]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pubsub_PSubscribeClient = grpc.BidiStreamingClient[SubscribeRequest, Message]
+
+// PubsubServer is the server API for Pubsub service.
+// All implementations must embed UnimplementedPubsubServer
+// for forward compatibility.
+//
+// Pubsub exposes a topic space to publishers and subscribers in other processes.
+type PubsubServer interface {
+	// Publish publishes a message to a topic.
+	Publish(context.Context, *PublishRequest) (*PublishResponse, error)
+	// Subscribe streams the messages of the topics the client subscribes to with
+	// the requests it sends, until it cancels the call.
+	Subscribe(grpc.BidiStreamingServer[SubscribeRequest, Message]) error
+	// PSubscribe is like Subscribe, with patterns instead of topics.
+	PSubscribe(grpc.BidiStreamingServer[SubscribeRequest, Message]) error
+	mustEmbedUnimplementedPubsubServer()
+}
+
+// UnimplementedPubsubServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPubsubServer struct{}
+
+func (UnimplementedPubsubServer) Publish(context.Context, *PublishRequest) (*PublishResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedPubsubServer) Subscribe(grpc.BidiStreamingServer[SubscribeRequest, Message]) error {
+	return status.Error(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedPubsubServer) PSubscribe(grpc.BidiStreamingServer[SubscribeRequest, Message]) error {
+	return status.Error(codes.Unimplemented, "method PSubscribe not implemented")
+}
+func (UnimplementedPubsubServer) mustEmbedUnimplementedPubsubServer() {}
+func (UnimplementedPubsubServer) testEmbeddedByValue()                {}
+
+// UnsafePubsubServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PubsubServer will
+// result in compilation errors.
+type UnsafePubsubServer interface {
+	mustEmbedUnimplementedPubsubServer()
+}
+
+func RegisterPubsubServer(s grpc.ServiceRegistrar, srv PubsubServer) {
+	// If the following call panics, it indicates UnimplementedPubsubServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Pubsub_ServiceDesc, srv)
+}
+
+func _Pubsub_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PubsubServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Pubsub_Publish_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PubsubServer).Publish(ctx, req.(*PublishRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Pubsub_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PubsubServer).Subscribe(&grpc.GenericServerStream[SubscribeRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pubsub_SubscribeServer = grpc.BidiStreamingServer[SubscribeRequest, Message]
+
+func _Pubsub_PSubscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(PubsubServer).PSubscribe(&grpc.GenericServerStream[SubscribeRequest, Message]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Pubsub_PSubscribeServer = grpc.BidiStreamingServer[SubscribeRequest, Message]
+
+// Pubsub_ServiceDesc is the grpc.ServiceDesc for Pubsub service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Pubsub_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pubsub.v1.Pubsub",
+	HandlerType: (*PubsubServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Publish",
+			Handler:    _Pubsub_Publish_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Pubsub_Subscribe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "PSubscribe",
+			Handler:       _Pubsub_PSubscribe_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "pubsub.proto",
+}
@@ -0,0 +1,12 @@
+// Package pubsubgrpc serves a Pubsub over gRPC, so clients in other processes and
+// languages share its topic space with the in-process subscribers. The service is
+// defined in pubsub.proto, and its messages are exchanged with the default proto
+// codec of gRPC.
+//
+//	s := grpc.NewServer()
+//	pubsubgrpc.NewServer(ps).Register(s)
+//
+// Go clients call the service with NewClient, or with the generated PubsubClient.
+package pubsubgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pubsub.proto
@@ -0,0 +1,206 @@
+package pubsubgrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the Pubsub service of pubsub.proto with a Pubsub.
+type Server struct {
+	UnimplementedPubsubServer
+
+	ps     *pubsub.Pubsub
+	codec  pubsub.Codec
+	buffer int
+	quotas *pubsub.Quotas
+	batch  int
+	delay  time.Duration
+}
+
+// Option configures a Server.
+type Option func(s *Server)
+
+// WithBuffer buffers up to n messages per stream, 64 by default. Messages are
+// dropped when a client is slower.
+func WithBuffer(n int) Option {
+	return func(s *Server) {
+		s.buffer = n
+	}
+}
+
+// WithCodec encodes the streamed messages with c instead of the Codec of the
+// Pubsub. Clients decoding them with Event.Into register c with
+// pubsub.RegisterCodec.
+func WithCodec(c pubsub.Codec) Option {
+	return func(s *Server) {
+		s.codec = c
+	}
+}
+
+// WithQuotas enforces the quotas of q on the calls, for the principal of the
+// identity of the call context, see pubsub.ContextWithIdentity. Calls over the
+// quota fail with codes.ResourceExhausted.
+func WithQuotas(q *pubsub.Quotas) Option {
+	return func(s *Server) {
+		s.quotas = q
+	}
+}
+
+// WithBatch sends the messages of a stream in batches of up to maxSize messages,
+// sent when full or maxDelay after their first message, e.g. to stream many
+// small messages in fewer flushes.
+func WithBatch(maxSize int, maxDelay time.Duration) Option {
+	return func(s *Server) {
+		s.batch = maxSize
+		s.delay = maxDelay
+	}
+}
+
+// NewServer creates a Server of ps.
+func NewServer(ps *pubsub.Pubsub, opts ...Option) *Server {
+	s := &Server{
+		ps:     ps,
+		buffer: 64,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.codec == nil {
+		s.codec = ps.Codec()
+	}
+	return s
+}
+
+// Register registers the service with r, e.g. a *grpc.Server.
+func (s *Server) Register(r grpc.ServiceRegistrar) {
+	RegisterPubsubServer(r, s)
+}
+
+// Publish publishes the data of req to its topic.
+func (s *Server) Publish(ctx context.Context, req *PublishRequest) (*PublishResponse, error) {
+	if req.Topic == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing topic")
+	}
+	if err := s.quotas.Publish(pubsub.IdentityFromContext(ctx)); err != nil {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	result := s.ps.PublishMsg(req.Topic, req.Data, req.Headers)
+	return &PublishResponse{
+		Delivered: int32(result.Delivered),
+		Dropped:   int32(result.Dropped),
+	}, nil
+}
+
+// Subscribe streams the messages of the topics of the received requests.
+func (s *Server) Subscribe(stream Pubsub_SubscribeServer) error {
+	return s.stream(stream, false, s.ps.Subscribe, s.ps.Unsubscribe)
+}
+
+// PSubscribe streams the messages of the patterns of the received requests.
+func (s *Server) PSubscribe(stream Pubsub_PSubscribeServer) error {
+	return s.stream(stream, true, s.ps.PSubscribe, s.ps.PUnsubscribe)
+}
+
+func (s *Server) stream(stream grpc.BidiStreamingServer[SubscribeRequest, Message], pattern bool, subscribe func(string, chan pubsub.Event) error, unsubscribe func(string, chan pubsub.Event)) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	c := make(chan pubsub.Event, s.buffer)
+	quota := s.quotas.Session(pubsub.IdentityFromContext(ctx))
+	defer quota.Close()
+	defer s.ps.UnsubscribeAll(c)
+
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					// the client is done sending requests, not receiving
+					errs <- err
+				}
+				return
+			}
+			for _, name := range req.Subscribe {
+				if err := quota.Subscribe(name, pattern); err != nil {
+					errs <- status.Error(codes.ResourceExhausted, err.Error())
+					return
+				}
+				if err := subscribe(name, c); err != nil {
+					errs <- status.Error(codes.Unavailable, err.Error())
+					return
+				}
+			}
+			for _, name := range req.Unsubscribe {
+				unsubscribe(name, c)
+				quota.Unsubscribe(name, pattern)
+			}
+		}
+	}()
+
+	size, delay := s.batch, s.delay
+	if size <= 0 {
+		size, delay = 1, 0
+	}
+	batches := pubsub.Batch(ctx, c, size, delay)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case events := <-batches:
+			for _, event := range events {
+				msg, err := s.message(event)
+				if err != nil {
+					// skip the messages which can't be encoded
+					continue
+				}
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// message returns the Message of event. Messages which are already encoded are
+// streamed as is.
+func (s *Server) message(event pubsub.Event) (*Message, error) {
+	headers := event.Headers
+	var data []byte
+	switch m := event.Message.(type) {
+	case []byte:
+		data = m
+	case json.RawMessage:
+		data = m
+	default:
+		var err error
+		if data, err = s.codec.Encode(m); err != nil {
+			return nil, err
+		}
+		headers = make(map[string]string, len(event.Headers)+1)
+		for k, v := range event.Headers {
+			headers[k] = v
+		}
+		headers[pubsub.HeaderContentType] = s.codec.ContentType()
+	}
+	msg := &Message{
+		Id:      event.ID,
+		Topic:   event.Name,
+		Pattern: event.Pattern,
+		Data:    data,
+		Headers: headers,
+	}
+	if !event.Time.IsZero() {
+		msg.TimeUnixNano = event.Time.UnixNano()
+	}
+	return msg, nil
+}
@@ -0,0 +1,230 @@
+package pubsubgrpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// pipeConn calls the handlers of Pubsub_ServiceDesc in process, encoding the
+// messages with proto.
+type pipeConn struct {
+	srv interface{}
+}
+
+func roundTrip(from, to interface{}) error {
+	data, err := proto.Marshal(from.(proto.Message))
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, to.(proto.Message))
+}
+
+func (p pipeConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	for _, m := range Pubsub_ServiceDesc.Methods {
+		if "/"+Pubsub_ServiceDesc.ServiceName+"/"+m.MethodName != method {
+			continue
+		}
+		resp, err := m.Handler(p.srv, ctx, func(v interface{}) error { return roundTrip(args, v) }, nil)
+		if err != nil {
+			return err
+		}
+		return roundTrip(resp, reply)
+	}
+	return status.Error(codes.Unavailable, "unknown method")
+}
+
+func (p pipeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	toServer, toClient := make(chan interface{}, 16), make(chan interface{}, 16)
+	client := &pipeStream{ctx: ctx, in: toClient, out: toServer}
+	go func() {
+		client.err = desc.Handler(p.srv, &pipeStream{ctx: ctx, in: toServer, out: toClient})
+		close(toClient)
+	}()
+	return client, nil
+}
+
+type pipeStream struct {
+	ctx context.Context
+	in  chan interface{}
+	out chan interface{}
+	err error // returned by the handler, once in is closed
+}
+
+func (s *pipeStream) SetHeader(metadata.MD) error  { return nil }
+func (s *pipeStream) SendHeader(metadata.MD) error { return nil }
+func (s *pipeStream) SetTrailer(metadata.MD)       {}
+func (s *pipeStream) Header() (metadata.MD, error) { return nil, nil }
+func (s *pipeStream) Trailer() metadata.MD         { return nil }
+func (s *pipeStream) Context() context.Context     { return s.ctx }
+
+func (s *pipeStream) CloseSend() error {
+	close(s.out)
+	return nil
+}
+
+func (s *pipeStream) SendMsg(m interface{}) error {
+	select {
+	case s.out <- m:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func (s *pipeStream) RecvMsg(m interface{}) error {
+	select {
+	case v, ok := <-s.in:
+		if !ok {
+			if s.err != nil {
+				return s.err
+			}
+			return io.EOF
+		}
+		return roundTrip(v, m)
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+func TestPublish(t *testing.T) {
+	ps := pubsub.New(-1)
+	client := NewClient(pipeConn{NewServer(ps)})
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("orders", c)
+
+	resp, err := client.Publish(context.Background(), &PublishRequest{Topic: "orders", Data: []byte(`{"id":1}`)})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.Delivered, int32(1))
+	assert.Equal(t, resp.Dropped, int32(0))
+	var order map[string]int
+	assert.Equal(t, (<-c).Into(&order), nil)
+	assert.Equal(t, order, map[string]int{"id": 1})
+
+	_, err = client.Publish(context.Background(), &PublishRequest{})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
+
+func TestSubscribeCodec(t *testing.T) {
+	ps := pubsub.New(-1)
+	client := NewClient(pipeConn{NewServer(ps, WithCodec(pubsub.Gob))})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	topics, _ := client.Subscribe(ctx, "orders")
+	for ps.NumSubscribers("orders") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("orders", 1)
+	msg, err := topics.Recv()
+	assert.Equal(t, err, nil)
+	var n int
+	e := pubsub.Event{Message: msg.Data, Headers: msg.Headers}
+	assert.Equal(t, e.Into(&n), nil)
+	assert.Equal(t, n, 1)
+}
+
+func TestSubscribe(t *testing.T) {
+	ps := pubsub.New(-1)
+	client := NewClient(pipeConn{NewServer(ps)})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topics, err := client.Subscribe(ctx, "orders")
+	assert.Equal(t, err, nil)
+	patterns, err := client.PSubscribe(ctx, "users/*")
+	assert.Equal(t, err, nil)
+	for ps.NumSubscribers("orders") == 0 || len(ps.Patterns()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("orders", map[string]int{"id": 1})
+	msg, err := topics.Recv()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, proto.Equal(msg, &Message{
+		Topic:   "orders",
+		Data:    []byte(`{"id":1}`),
+		Headers: map[string]string{pubsub.HeaderContentType: "application/json"},
+	}), true)
+
+	ps.Publish("users/1", []byte("ann"))
+	msg, err = patterns.Recv()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, proto.Equal(msg, &Message{Topic: "users/1", Pattern: "users/*", Data: []byte("ann")}), true)
+
+	topics.Unsubscribe("orders")
+	for ps.NumSubscribers("orders") > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	for len(ps.Patterns()) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeBatch(t *testing.T) {
+	ps := pubsub.New(-1)
+	client := NewClient(pipeConn{NewServer(ps, WithBatch(2, 50*time.Millisecond))})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	topics, _ := client.Subscribe(ctx, "orders")
+	for ps.NumSubscribers("orders") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ps.Publish("orders", []byte("1"))
+	ps.Publish("orders", []byte("2"))
+	for _, want := range []string{"1", "2"} {
+		msg, err := topics.Recv()
+		assert.Equal(t, err, nil)
+		assert.Equal(t, string(msg.Data), want)
+	}
+
+	// sent after the delay
+	start := time.Now()
+	ps.Publish("orders", []byte("3"))
+	msg, err := topics.Recv()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(msg.Data), "3")
+	assert.Equal(t, time.Since(start) >= 50*time.Millisecond, true)
+}
+
+func TestQuotas(t *testing.T) {
+	ps := pubsub.New(-1)
+	quotas := pubsub.NewQuotas(func(principal string) pubsub.Quota {
+		return pubsub.Quota{MaxSubscriptions: 1, PublishRate: 0.001}
+	})
+	client := NewClient(pipeConn{NewServer(ps, WithQuotas(quotas))})
+	ctx, cancel := context.WithCancel(pubsub.ContextWithIdentity(context.Background(), "alice"))
+	defer cancel()
+
+	_, err := client.Publish(ctx, &PublishRequest{Topic: "orders"})
+	assert.Equal(t, err, nil)
+	_, err = client.Publish(ctx, &PublishRequest{Topic: "orders"})
+	assert.Equal(t, status.Code(err), codes.ResourceExhausted)
+
+	topics, err := client.Subscribe(ctx, "orders", "users")
+	assert.Equal(t, err, nil)
+	_, err = topics.Recv()
+	assert.Equal(t, status.Code(err), codes.ResourceExhausted)
+
+	// the subscriptions of the failed stream are released
+	topics, err = client.Subscribe(ctx, "users")
+	assert.Equal(t, err, nil)
+	for ps.NumSubscribers("users") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ps.Publish("users", []byte("ann"))
+	msg, err := topics.Recv()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, msg.Topic, "users")
+}
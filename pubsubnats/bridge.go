@@ -0,0 +1,282 @@
+package pubsubnats
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	// Error of running a Bridge which already ran.
+	ErrStarted = errors.New("bridge already started")
+	// Error of dropping a message because the bridge is behind.
+	ErrBufferFull = errors.New("bridge buffer is full")
+)
+
+const (
+	headerPrefix = "Pubsub-"
+	headerOrigin = "Pubsub-Origin" // the bridge which published the message
+	headerSeq    = "Pubsub-Seq"
+)
+
+// sysPrefix is the prefix of the topics which stay local.
+const sysPrefix = "$SYS/"
+
+// seen is the number of received messages remembered to drop their duplicates.
+const seen = 1024
+
+// Bridge federates a local Pubsub with NATS. It only subscribes to the subjects of
+// the names and patterns which have local subscribers, and publishes every local
+// message to NATS.
+//
+//	b := pubsubnats.NewBridge(nc)
+//	ps := pubsub.New(-1, b.Options()...)
+//	go b.Run(ctx, ps)
+type Bridge struct {
+	nc       *nats.Conn
+	config   *config
+	origin   string
+	seq      atomic.Uint64
+	outbound chan *nats.Msg
+	inbound  chan *nats.Msg
+	started  atomic.Bool
+	running  atomic.Bool
+	ps       atomic.Pointer[pubsub.Pubsub] // set by Run
+
+	mu       sync.Mutex
+	subjects map[string]*natsSubject // subject -> local names and patterns
+	active   bool                    // whether subscribed to NATS, guarded by mu
+}
+
+type natsSubject struct {
+	refs int
+	sub  *nats.Subscription
+}
+
+// NewBridge creates a Bridge with nc.
+func NewBridge(nc *nats.Conn, opts ...Option) *Bridge {
+	config := newConfig(opts)
+	return &Bridge{
+		nc:       nc,
+		config:   config,
+		origin:   pubsub.UUIDv7().NewID(),
+		outbound: make(chan *nats.Msg, config.buffer),
+		inbound:  make(chan *nats.Msg, config.buffer),
+		subjects: map[string]*natsSubject{},
+	}
+}
+
+// Options returns the options to create the Pubsub with, which tell the bridge the
+// names and patterns with local subscribers.
+func (b *Bridge) Options() []pubsub.Option {
+	return []pubsub.Option{
+		pubsub.OnTopicCreated(func(name string) { b.track(b.config.subject, name, true) }),
+		pubsub.OnTopicDeleted(func(name string) { b.track(b.config.subject, name, false) }),
+		pubsub.OnPatternCreated(func(pattern string) { b.track(b.config.patternSubject, pattern, true) }),
+		pubsub.OnPatternDeleted(func(pattern string) { b.track(b.config.patternSubject, pattern, false) }),
+	}
+}
+
+// Run federates ps, created with the Options of the bridge, until ctx is done. It
+// can only be called once.
+func (b *Bridge) Run(ctx context.Context, ps *pubsub.Pubsub) error {
+	if !b.started.CompareAndSwap(false, true) {
+		return ErrStarted
+	}
+	if b.config.codec == nil {
+		b.config.codec = ps.Codec()
+	}
+	b.ps.Store(ps)
+	ps.Use(b.intercept)
+	ps.OnShutdownPhase(pubsub.ShutdownBridges, b.flush)
+
+	b.mu.Lock()
+	b.active = true
+	for subject, s := range b.subjects {
+		if err := b.subscribe(subject, s); err != nil {
+			b.mu.Unlock()
+			b.close()
+			return err
+		}
+	}
+	b.mu.Unlock()
+	defer b.close()
+
+	b.running.Store(true)
+	defer b.running.Store(false)
+	go b.publish(ctx)
+
+	var (
+		ids   = map[string]bool{}
+		order []string
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-b.inbound:
+			origin := msg.Header.Get(headerOrigin)
+			if origin == b.origin {
+				continue
+			}
+			// NATS sends a message once for every matching subscription
+			id := origin + "/" + msg.Header.Get(headerSeq)
+			if origin != "" {
+				if ids[id] {
+					continue
+				}
+				ids[id] = true
+				order = append(order, id)
+				if len(order) > seen {
+					delete(ids, order[0])
+					order = order[1:]
+				}
+			}
+			event, ok := b.config.event(msg)
+			if !ok || strings.HasPrefix(event.Name, sysPrefix) {
+				continue
+			}
+			ps.PublishMsg(event.Name, inbound{msg.Data}, event.Headers)
+		}
+	}
+}
+
+// close unsubscribes from NATS.
+func (b *Bridge) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.active = false
+	for _, s := range b.subjects {
+		if s.sub != nil {
+			s.sub.Unsubscribe()
+			s.sub = nil
+		}
+	}
+}
+
+// track counts the local names or patterns of a subject after they got their first
+// or lost their last subscriber, and subscribes to it while it's counted.
+func (b *Bridge) track(subject func(string) string, key string, subscribed bool) {
+	if strings.HasPrefix(key, sysPrefix) {
+		return
+	}
+	name := subject(key)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.subjects[name]
+	if !ok {
+		s = &natsSubject{}
+		b.subjects[name] = s
+	}
+	if subscribed {
+		s.refs++
+		if s.refs == 1 && b.active {
+			if err := b.subscribe(name, s); err != nil {
+				b.report(err)
+			}
+		}
+		return
+	}
+	s.refs--
+	if s.refs > 0 {
+		return
+	}
+	delete(b.subjects, name)
+	if s.sub != nil {
+		if err := s.sub.Unsubscribe(); err != nil {
+			b.report(err)
+		}
+	}
+}
+
+// subscribe subscribes to subject, with mu held.
+func (b *Bridge) subscribe(subject string, s *natsSubject) error {
+	sub, err := b.nc.Subscribe(subject, func(msg *nats.Msg) {
+		select {
+		case b.inbound <- msg:
+		default:
+			b.report(ErrBufferFull)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	s.sub = sub
+	return nil
+}
+
+// inbound is a message received from NATS, published to the Pubsub without
+// publishing it to NATS again.
+type inbound struct {
+	data []byte
+}
+
+// intercept is the middleware publishing the local messages to NATS.
+func (b *Bridge) intercept(topic string, message interface{}, next func(topic string, message interface{})) {
+	if in, ok := message.(inbound); ok {
+		next(topic, in.data)
+		return
+	}
+	next(topic, message)
+	if !b.running.Load() || strings.HasPrefix(topic, sysPrefix) {
+		return
+	}
+	msg, err := b.config.encode(topic, message)
+	if err != nil {
+		b.report(err)
+		return
+	}
+	msg.Header.Set(headerOrigin, b.origin)
+	msg.Header.Set(headerSeq, strconv.FormatUint(b.seq.Add(1), 10))
+	select {
+	case b.outbound <- msg:
+	default:
+		b.report(ErrBufferFull)
+	}
+}
+
+// publish publishes the queued messages to NATS until ctx is done.
+func (b *Bridge) publish(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-b.outbound:
+			if err := b.nc.PublishMsg(msg); err != nil {
+				b.report(err)
+			}
+		}
+	}
+}
+
+// flush publishes the queued messages to NATS when the Pubsub is shut down.
+func (b *Bridge) flush(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg := <-b.outbound:
+			if err := b.nc.PublishMsg(msg); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// report reports err to the OnError handler, and as a notice of the Pubsub once
+// running.
+func (b *Bridge) report(err error) {
+	b.config.onError(err)
+	if ps := b.ps.Load(); ps != nil {
+		ps.Notify(pubsub.Notice{Kind: pubsub.NoticeBridgeError, Err: err})
+	}
+}
@@ -0,0 +1,45 @@
+package pubsubnats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestBridge(t *testing.T) {
+	nc := connect(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge := func() *pubsub.Pubsub {
+		b := NewBridge(nc, WithPrefix(t.Name()+"."))
+		ps := pubsub.New(-1, b.Options()...)
+		go b.Run(ctx, ps)
+		return ps
+	}
+	a, b := bridge(), bridge()
+
+	c := make(chan pubsub.Event, 10)
+	b.Subscribe("orders/1", c)
+	b.PSubscribe("orders/*", c)
+	local := make(chan pubsub.Event, 10)
+	a.Subscribe("orders/1", local)
+	time.Sleep(50 * time.Millisecond)
+
+	a.Publish("orders/1", 1)
+	e := <-c
+	var n int
+	assert.Equal(t, e.Into(&n), nil)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, e.Headers, map[string]string{pubsub.HeaderContentType: "application/json"})
+	assert.Equal(t, (<-c).Pattern, "orders/*")
+	assert.Equal(t, (<-local).Message, 1)
+
+	// neither echoed back nor delivered twice
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, len(local), 0)
+	assert.Equal(t, len(c), 0)
+}
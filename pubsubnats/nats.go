@@ -0,0 +1,282 @@
+// Package pubsubnats provides the Subscribe/PSubscribe/Publish API of a Pubsub
+// backed by a NATS connection, and a Bridge federating a local Pubsub with NATS.
+//
+// Topics are mapped to NATS subjects by replacing the level separator / with the
+// token separator ., after the subject prefix. Patterns are mapped to NATS
+// wildcards: a level of only * or + is the * wildcard, a trailing # is the >
+// wildcard, and other levels with wildcards are mapped to * and matched locally.
+// Messages are encoded with the Codec of the Conn, or of the Pubsub for a Bridge,
+// JSON by default, and received as []byte with the HeaderContentType header, which
+// Event.Into decodes.
+package pubsubnats
+
+import (
+	"strings"
+	"sync"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/nats-io/nats.go"
+)
+
+type config struct {
+	prefix    string
+	separator string
+	translate func(pattern string) string
+	matcher   pubsub.Matcher
+	codec     pubsub.Codec
+	onError   func(err error)
+	buffer    int
+}
+
+// Option configures a Conn or a Bridge.
+type Option func(c *config)
+
+// WithPrefix prefixes the subjects of the topics with prefix, e.g. "app.".
+func WithPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithSeparator separates the levels of the topics with sep instead of /.
+func WithSeparator(sep string) Option {
+	return func(c *config) {
+		c.separator = sep
+	}
+}
+
+// WithPatterns maps patterns to NATS subjects with translate, which must match at
+// least the topics of the pattern. The prefix is added to the returned subject.
+func WithPatterns(translate func(pattern string) string) Option {
+	return func(c *config) {
+		c.translate = translate
+	}
+}
+
+// WithMatcher matches the topics of the received messages against the patterns of
+// a Conn with m instead of GlobMatcher. The Bridge matches with the Pubsub.
+func WithMatcher(m pubsub.Matcher) Option {
+	return func(c *config) {
+		c.matcher = m
+	}
+}
+
+// WithCodec encodes the messages with c instead of JSON, or of the Codec of the
+// Pubsub of a Bridge. Register c with pubsub.RegisterCodec for Into to decode the
+// messages received.
+func WithCodec(c pubsub.Codec) Option {
+	return func(cfg *config) {
+		cfg.codec = c
+	}
+}
+
+// OnError calls fn with the errors of publishing in the background and of decoding
+// received messages, which are ignored by default.
+func OnError(fn func(err error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// WithBuffer buffers up to n messages of a Bridge in each direction, 1024 by
+// default. Messages are dropped with ErrBufferFull when the buffer is full.
+func WithBuffer(n int) Option {
+	return func(c *config) {
+		c.buffer = n
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		separator: "/",
+		matcher:   pubsub.GlobMatcher{},
+		onError:   func(error) {},
+		buffer:    1024,
+	}
+	c.translate = c.wildcards
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// subject returns the subject of topic.
+func (c *config) subject(topic string) string {
+	if c.separator == "." {
+		return c.prefix + topic
+	}
+	return c.prefix + strings.ReplaceAll(topic, c.separator, ".")
+}
+
+// topic returns the topic of subject, or false if subject doesn't have the prefix.
+func (c *config) topic(subject string) (string, bool) {
+	subject, ok := strings.CutPrefix(subject, c.prefix)
+	if c.separator == "." {
+		return subject, ok
+	}
+	return strings.ReplaceAll(subject, ".", c.separator), ok
+}
+
+// patternSubject returns the wildcard subject of pattern.
+func (c *config) patternSubject(pattern string) string {
+	return c.prefix + c.translate(pattern)
+}
+
+// wildcards is the default translation of patterns.
+func (c *config) wildcards(pattern string) string {
+	levels := strings.Split(pattern, c.separator)
+	for i, level := range levels {
+		switch {
+		case level == "#" && i == len(levels)-1:
+			levels[i] = ">"
+		case strings.ContainsAny(level, `*?[\+#`):
+			levels[i] = "*"
+		}
+	}
+	return strings.Join(levels, ".")
+}
+
+// encode returns the NATS message of a message published to topic.
+func (c *config) encode(topic string, message interface{}) (*nats.Msg, error) {
+	data, err := c.codec.Encode(message)
+	if err != nil {
+		return nil, err
+	}
+	msg := nats.NewMsg(c.subject(topic))
+	msg.Data = data
+	msg.Header.Set(pubsub.HeaderContentType, c.codec.ContentType())
+	return msg, nil
+}
+
+// event returns the event of a received NATS message, or false if the subject
+// doesn't have the prefix.
+func (c *config) event(msg *nats.Msg) (pubsub.Event, bool) {
+	topic, ok := c.topic(msg.Subject)
+	if !ok {
+		return pubsub.Event{}, false
+	}
+	event := pubsub.Event{
+		Name:    topic,
+		Message: msg.Data,
+	}
+	if len(msg.Header) > 0 {
+		event.Headers = make(map[string]string, len(msg.Header))
+		for k := range msg.Header {
+			if !strings.HasPrefix(k, headerPrefix) {
+				event.Headers[k] = msg.Header.Get(k)
+			}
+		}
+	}
+	return event, true
+}
+
+// Conn subscribes channels to and publishes messages with NATS, like a Pubsub.
+type Conn struct {
+	nc     *nats.Conn
+	config *config
+
+	mu   sync.Mutex
+	subs map[subscription]*nats.Subscription
+}
+
+type subscription struct {
+	pattern bool
+	name    string
+	c       chan pubsub.Event
+}
+
+// New creates a Conn with nc.
+func New(nc *nats.Conn, opts ...Option) *Conn {
+	config := newConfig(opts)
+	if config.codec == nil {
+		config.codec = pubsub.JSON
+	}
+	return &Conn{
+		nc:     nc,
+		config: config,
+		subs:   map[subscription]*nats.Subscription{},
+	}
+}
+
+// Subscribe subscribes channel c to name. Messages are dropped when c is full.
+func (conn *Conn) Subscribe(name string, c chan pubsub.Event) error {
+	return conn.subscribe(subscription{false, name, c}, conn.config.subject(name))
+}
+
+// PSubscribe subscribes channel c to pattern.
+func (conn *Conn) PSubscribe(pattern string, c chan pubsub.Event) error {
+	return conn.subscribe(subscription{true, pattern, c}, conn.config.patternSubject(pattern))
+}
+
+func (conn *Conn) subscribe(s subscription, subject string) error {
+	if s.c == nil {
+		return nil
+	}
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	if _, ok := conn.subs[s]; ok {
+		return nil
+	}
+	sub, err := conn.nc.Subscribe(subject, func(msg *nats.Msg) {
+		event, ok := conn.config.event(msg)
+		if !ok {
+			return
+		}
+		if s.pattern {
+			if !conn.config.matcher.Match(s.name, event.Name) {
+				return
+			}
+			event.Pattern = s.name
+		}
+		select {
+		case s.c <- event:
+		default:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	conn.subs[s] = sub
+	return nil
+}
+
+// Unsubscribe unsubscribes channel c from name.
+func (conn *Conn) Unsubscribe(name string, c chan pubsub.Event) {
+	conn.unsubscribe(func(s subscription) bool {
+		return !s.pattern && s.name == name && s.c == c
+	})
+}
+
+// PUnsubscribe unsubscribes channel c from pattern.
+func (conn *Conn) PUnsubscribe(pattern string, c chan pubsub.Event) {
+	conn.unsubscribe(func(s subscription) bool {
+		return s.pattern && s.name == pattern && s.c == c
+	})
+}
+
+// UnsubscribeAll unsubscribes channel c from all names and patterns.
+func (conn *Conn) UnsubscribeAll(c chan pubsub.Event) {
+	conn.unsubscribe(func(s subscription) bool {
+		return s.c == c
+	})
+}
+
+func (conn *Conn) unsubscribe(match func(s subscription) bool) {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	for s, sub := range conn.subs {
+		if match(s) {
+			sub.Unsubscribe()
+			delete(conn.subs, s)
+		}
+	}
+}
+
+// Publish publishes message to name.
+func (conn *Conn) Publish(name string, message interface{}) error {
+	msg, err := conn.config.encode(name, message)
+	if err != nil {
+		return err
+	}
+	return conn.nc.PublishMsg(msg)
+}
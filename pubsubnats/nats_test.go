@@ -0,0 +1,69 @@
+package pubsubnats
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/nats-io/nats.go"
+)
+
+func TestSubjects(t *testing.T) {
+	c := newConfig([]Option{WithPrefix("app.")})
+	assert.Equal(t, c.subject("orders/1"), "app.orders.1")
+	topic, ok := c.topic("app.orders.1")
+	assert.Equal(t, topic, "orders/1")
+	assert.Equal(t, ok, true)
+	_, ok = c.topic("other.orders")
+	assert.Equal(t, ok, false)
+
+	assert.Equal(t, c.patternSubject("orders/*"), "app.orders.*")
+	assert.Equal(t, c.patternSubject("orders/+/items"), "app.orders.*.items")
+	assert.Equal(t, c.patternSubject("orders/#"), "app.orders.>")
+	assert.Equal(t, c.patternSubject("orders/1?"), "app.orders.*")
+}
+
+// connect connects to the NATS server at $NATS_URL.
+func connect(t *testing.T) *nats.Conn {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		t.Skip("NATS_URL isn't set")
+	}
+	nc, err := nats.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nc
+}
+
+func TestConn(t *testing.T) {
+	conn := New(connect(t), WithPrefix(t.Name()+"."))
+	c := make(chan pubsub.Event, 10)
+	p := make(chan pubsub.Event, 10)
+	assert.Equal(t, conn.Subscribe("orders/1", c), nil)
+	assert.Equal(t, conn.PSubscribe("orders/1?", p), nil)
+
+	assert.Equal(t, conn.Publish("orders/1", 1), nil)
+	assert.Equal(t, conn.Publish("orders/12", 12), nil)
+	assert.Equal(t, conn.Publish("orders/2", 2), nil)
+
+	var n int
+	e := <-c
+	assert.Equal(t, e.Name, "orders/1")
+	assert.Equal(t, e.Into(&n), nil)
+	assert.Equal(t, n, 1)
+	e = <-p
+	assert.Equal(t, e.Name, "orders/12")
+	assert.Equal(t, e.Pattern, "orders/1?")
+
+	conn.UnsubscribeAll(c)
+	conn.Unsubscribe("orders/1", p)
+	conn.PUnsubscribe("orders/1?", p)
+	conn.Publish("orders/1", 1)
+	conn.Publish("orders/12", 12)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, len(c), 0)
+	assert.Equal(t, len(p), 0)
+}
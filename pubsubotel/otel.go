@@ -0,0 +1,117 @@
+// Package pubsubotel traces the messages of a Pubsub with OpenTelemetry.
+//
+//	tracer := pubsubotel.New()
+//	ps := pubsub.New(-1, pubsub.WithTracer(tracer))
+//
+// Every published message gets a producer span, and carries its trace context in
+// its headers. Subscribers continue the trace with Extract or StartReceive.
+package pubsubotel
+
+import (
+	"context"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentation = "github.com/kildevaeld/go-pubsub/pubsubotel"
+
+// Tracer is a pubsub.Tracer creating OpenTelemetry spans.
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// Option configures a Tracer.
+type Option func(t *Tracer)
+
+// WithTracerProvider creates the spans with tp instead of the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(t *Tracer) {
+		t.tracer = tp.Tracer(instrumentation)
+	}
+}
+
+// WithPropagator carries the trace context in the headers with p instead of the
+// global propagator.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		t.propagator = p
+	}
+}
+
+// New creates a Tracer.
+func New(opts ...Option) *Tracer {
+	t := &Tracer{
+		tracer:     otel.GetTracerProvider().Tracer(instrumentation),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// StartPublish starts the producer span of event, and injects its trace context
+// into the headers of event.
+func (t *Tracer) StartPublish(ctx context.Context, event *pubsub.Event) func(pubsub.PublishResult) {
+	ctx, span := t.tracer.Start(ctx, event.Name+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "pubsub"),
+			attribute.String("messaging.operation", "publish"),
+			attribute.String("messaging.destination.name", event.Name),
+			attribute.String("messaging.message.id", event.ID),
+		),
+	)
+
+	headers := make(map[string]string, len(event.Headers)+2)
+	for k, v := range event.Headers {
+		headers[k] = v
+	}
+	t.propagator.Inject(ctx, propagation.MapCarrier(headers))
+	event.Headers = headers
+
+	return func(result pubsub.PublishResult) {
+		span.SetAttributes(
+			attribute.Int("pubsub.delivered", result.Delivered),
+			attribute.Int("pubsub.dropped", result.Dropped),
+		)
+		if result.Dropped > 0 {
+			span.SetStatus(codes.Error, "message dropped")
+		}
+		span.End()
+	}
+}
+
+// Inject sets the headers carrying the trace context of ctx.
+func (t *Tracer) Inject(ctx context.Context, headers map[string]string) {
+	t.propagator.Inject(ctx, propagation.MapCarrier(headers))
+}
+
+// Extract returns ctx with the trace context carried by event.
+func (t *Tracer) Extract(ctx context.Context, event pubsub.Event) context.Context {
+	return t.propagator.Extract(ctx, propagation.MapCarrier(event.Headers))
+}
+
+// StartReceive starts the consumer span of event, a child of the producer span
+// of event. The caller ends the span once event is processed.
+func (t *Tracer) StartReceive(ctx context.Context, event pubsub.Event) (context.Context, trace.Span) {
+	name := event.Name
+	if event.Pattern != "" {
+		name = event.Pattern
+	}
+	return t.tracer.Start(t.Extract(ctx, event), name+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "pubsub"),
+			attribute.String("messaging.operation", "receive"),
+			attribute.String("messaging.destination.name", event.Name),
+			attribute.String("messaging.message.id", event.ID),
+		),
+	)
+}
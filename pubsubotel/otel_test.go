@@ -0,0 +1,60 @@
+package pubsubotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(
+		WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+		WithPropagator(propagation.TraceContext{}),
+	)
+	ps := pubsub.New(-1, pubsub.WithTracer(tracer))
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("orders", c)
+	ps.Publish("orders", 1)
+
+	event := <-c
+	_, span := tracer.StartReceive(context.Background(), event)
+	span.End()
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 2)
+	publish, receive := spans[0], spans[1]
+	assert.Equal(t, publish.Name(), "orders publish")
+	assert.Equal(t, publish.SpanKind(), trace.SpanKindProducer)
+	assert.Equal(t, receive.Name(), "orders receive")
+	assert.Equal(t, receive.Parent().SpanID(), publish.SpanContext().SpanID())
+	assert.Equal(t, receive.SpanContext().TraceID(), publish.SpanContext().TraceID())
+}
+
+func TestTracerPublishWithContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := New(WithTracerProvider(provider), WithPropagator(propagation.TraceContext{}))
+	ps := pubsub.New(-1, pubsub.WithTracer(tracer))
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("orders", c)
+
+	ctx, request := provider.Tracer("test").Start(context.Background(), "request")
+	ps.PublishWithContext(ctx, "orders", 1)
+	request.End()
+
+	ctx, cancel := ps.Context(context.Background(), <-c)
+	defer cancel()
+	assert.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), request.SpanContext().TraceID())
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 2)
+	assert.Equal(t, spans[0].Name(), "orders publish")
+	assert.Equal(t, spans[0].Parent().SpanID(), request.SpanContext().SpanID())
+}
@@ -0,0 +1,158 @@
+package pubsubtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// FakeClock is a pubsub.Clock whose time only changes with Advance and Set, to test
+// PublishAfter, PublishAt and PublishEvery without waiting.
+//
+//	clock := pubsubtest.NewFakeClock(time.Now())
+//	ps := pubsub.New(-1, pubsub.WithClock(clock))
+//	ps.PublishAfter("reminder", "ping", time.Hour)
+//	clock.Advance(time.Hour)
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{} // closed when the timers change
+}
+
+// NewFakeClock returns a FakeClock at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) pubsub.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.resetLocked(d)
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set sets the time to now, firing the timers due if it's later.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(now)
+}
+
+// WaitTimers waits until n timers are waiting to fire, or timeout, and returns
+// whether they are, e.g. to advance the time once the scheduler of a Pubsub waits
+// for its next message.
+func (c *FakeClock) WaitTimers(n int, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		c.mu.Lock()
+		waiting, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if waiting >= n {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return false
+		}
+	}
+}
+
+// changedLocked wakes WaitTimers up.
+func (c *FakeClock) changedLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *FakeClock) setLocked(now time.Time) {
+	c.now = now
+	sort.Slice(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	fired := false
+	for len(c.timers) > 0 && !c.timers[0].at.After(now) {
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		t.active = false
+		fired = true
+		select {
+		case t.c <- now:
+		default:
+		}
+	}
+	if fired {
+		c.changedLocked()
+	}
+}
+
+type fakeTimer struct {
+	clock  *FakeClock
+	c      chan time.Time
+	at     time.Time
+	active bool // guarded by clock.mu
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.stopLocked()
+}
+
+func (t *fakeTimer) stopLocked() bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i:i], t.clock.timers[i+1:]...)
+			break
+		}
+	}
+	t.clock.changedLocked()
+	return true
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.resetLocked(d)
+}
+
+func (t *fakeTimer) resetLocked(d time.Duration) bool {
+	active := t.stopLocked()
+	// like time.Timer since Go 1.23, no stale value is received after Reset
+	select {
+	case <-t.c:
+	default:
+	}
+	t.at = t.clock.now.Add(d)
+	if d <= 0 {
+		t.c <- t.clock.now
+		return active
+	}
+	t.active = true
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.changedLocked()
+	return active
+}
@@ -0,0 +1,172 @@
+// Package pubsubtest provides utilities to test code using a Pubsub: a Recorder of
+// the messages of a topic with assertions, a FakeClock for the scheduled messages,
+// and Drain.
+//
+//	rec := pubsubtest.NewRecorder(ps, "orders/*")
+//	defer rec.Close()
+//	placeOrder(ps)
+//	rec.ExpectMessage(t, "orders/placed", Order{ID: 1}, time.Second)
+package pubsubtest
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+// Record is a message received by a Recorder.
+type Record struct {
+	Event    pubsub.Event
+	Received time.Time
+}
+
+// Recorder records the messages of the topics matching a pattern.
+type Recorder struct {
+	ps *pubsub.Pubsub
+	c  chan pubsub.Event
+
+	mu      sync.Mutex
+	records []Record
+	matched map[int]bool  // records matched by an expectation
+	changed chan struct{} // closed when a message is recorded
+	done    chan struct{}
+}
+
+// NewRecorder returns a Recorder of the messages of the topics matching pattern, matched
+// by the Matcher of ps, until Close. It buffers 1024 messages, which are dropped
+// while the Recorder is behind.
+func NewRecorder(ps *pubsub.Pubsub, pattern string) *Recorder {
+	r := &Recorder{
+		ps:      ps,
+		c:       make(chan pubsub.Event, 1024),
+		matched: map[int]bool{},
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	ps.MustPSubscribe(pattern, r.c)
+	go r.run()
+	return r
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+	for event := range r.c {
+		r.mu.Lock()
+		r.records = append(r.records, Record{Event: event, Received: time.Now()})
+		close(r.changed)
+		r.changed = make(chan struct{})
+		r.mu.Unlock()
+	}
+}
+
+// Close unsubscribes the Recorder. The recorded messages are kept.
+func (r *Recorder) Close() {
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	r.ps.UnsubscribeAll(r.c)
+	close(r.c)
+	<-r.done
+}
+
+// Records returns the recorded messages, oldest first.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Record(nil), r.records...)
+}
+
+// Messages returns the recorded messages of topic, oldest first.
+func (r *Recorder) Messages(topic string) []interface{} {
+	var ret []interface{}
+	for _, record := range r.Records() {
+		if record.Event.Name == topic {
+			ret = append(ret, record.Event.Message)
+		}
+	}
+	return ret
+}
+
+// Topics returns the sorted topics of the recorded messages.
+func (r *Recorder) Topics() []string {
+	seen := map[string]bool{}
+	for _, record := range r.Records() {
+		seen[record.Event.Name] = true
+	}
+	ret := make([]string, 0, len(seen))
+	for topic := range seen {
+		ret = append(ret, topic)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Wait waits for a message of topic for which match returns true, not matched by a
+// previous Wait, and returns it. It returns false after timeout.
+func (r *Recorder) Wait(topic string, match func(event pubsub.Event) bool, timeout time.Duration) (pubsub.Event, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		for i, record := range r.records {
+			if !r.matched[i] && record.Event.Name == topic && match(record.Event) {
+				r.matched[i] = true
+				r.mu.Unlock()
+				return record.Event, true
+			}
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			return pubsub.Event{}, false
+		}
+	}
+}
+
+// ExpectMessage waits for a message of topic deeply equal to want, not matched by a
+// previous expectation, and returns it. It fails t after timeout.
+func (r *Recorder) ExpectMessage(t testing.TB, topic string, want interface{}, timeout time.Duration) pubsub.Event {
+	t.Helper()
+	event, ok := r.Wait(topic, func(event pubsub.Event) bool {
+		return reflect.DeepEqual(event.Message, want)
+	}, timeout)
+	if !ok {
+		t.Fatalf("pubsubtest: no message %#v of %q after %v, got %#v", want, topic, timeout, r.Messages(topic))
+	}
+	return event
+}
+
+// ExpectNoMessage fails t if a message of topic, not matched by an expectation, is
+// recorded within d.
+func (r *Recorder) ExpectNoMessage(t testing.TB, topic string, d time.Duration) {
+	t.Helper()
+	event, ok := r.Wait(topic, func(pubsub.Event) bool { return true }, d)
+	if ok {
+		t.Fatalf("pubsubtest: unexpected message %#v of %q", event.Message, topic)
+	}
+}
+
+// Drain returns the messages buffered in c, without waiting for more.
+func Drain(c <-chan pubsub.Event) []pubsub.Event {
+	var ret []pubsub.Event
+	for {
+		select {
+		case event, ok := <-c:
+			if !ok {
+				return ret
+			}
+			ret = append(ret, event)
+		default:
+			return ret
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package pubsubtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+)
+
+func TestRecorder(t *testing.T) {
+	ps := pubsub.New(-1)
+	rec := NewRecorder(ps, "orders/*")
+	defer rec.Close()
+
+	go func() {
+		ps.Publish("orders/placed", 1)
+		ps.Publish("orders/placed", 2)
+		ps.Publish("orders/shipped", 1)
+		ps.Publish("other", 1)
+	}()
+	rec.ExpectMessage(t, "orders/placed", 2, time.Second)
+	rec.ExpectMessage(t, "orders/placed", 1, time.Second)
+	rec.ExpectMessage(t, "orders/shipped", 1, time.Second)
+	rec.ExpectNoMessage(t, "orders/placed", 10*time.Millisecond)
+
+	assert.Equal(t, rec.Messages("orders/placed"), []interface{}{1, 2})
+	assert.Equal(t, rec.Topics(), []string{"orders/placed", "orders/shipped"})
+	records := rec.Records()
+	assert.Equal(t, len(records), 3)
+	assert.Equal(t, records[0].Received.IsZero(), false)
+
+	_, ok := rec.Wait("orders/placed", func(pubsub.Event) bool { return true }, 10*time.Millisecond)
+	assert.Equal(t, ok, false)
+
+	rec.Close()
+	rec.Close()
+	ps.Publish("orders/placed", 3)
+	assert.Equal(t, len(rec.Records()), 3)
+}
+
+func TestDrain(t *testing.T) {
+	c := make(chan pubsub.Event, 3)
+	c <- pubsub.Event{Name: "a"}
+	c <- pubsub.Event{Name: "b"}
+	assert.Equal(t, len(Drain(c)), 2)
+	assert.Equal(t, len(Drain(c)), 0)
+	c <- pubsub.Event{Name: "a"}
+	close(c)
+	assert.Equal(t, len(Drain(c)), 1)
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	ps := pubsub.New(-1, pubsub.WithClock(clock), pubsub.WithTimestamps())
+	defer ps.Close()
+	rec := NewRecorder(ps, "*")
+	defer rec.Close()
+
+	ps.PublishAfter("reminder", "ping", time.Hour)
+	assert.Equal(t, clock.WaitTimers(1, time.Second), true)
+	clock.Advance(59 * time.Minute)
+	rec.ExpectNoMessage(t, "reminder", 10*time.Millisecond)
+	clock.Advance(time.Minute)
+	event := rec.ExpectMessage(t, "reminder", "ping", time.Second)
+	assert.Equal(t, event.Time, start.Add(time.Hour))
+
+	every := ps.PublishEvery("tick", "tock", time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, clock.WaitTimers(1, time.Second), true)
+		clock.Advance(time.Minute)
+		rec.ExpectMessage(t, "tick", "tock", time.Second)
+	}
+	assert.Equal(t, every.Cancel(), true)
+}
+
+func TestFakeTimer(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+	assert.Equal(t, timer.Stop(), true)
+	assert.Equal(t, timer.Stop(), false)
+	assert.Equal(t, timer.Reset(time.Second), false)
+	clock.Set(time.Unix(1, 0))
+	assert.Equal(t, <-timer.C(), time.Unix(1, 0))
+	assert.Equal(t, clock.WaitTimers(0, 0), true)
+}
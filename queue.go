@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// QueueSubscribe subscribes channel c to name as a member of the queue group. Each
+// message published to name is delivered to one member of every group, round-robin,
+// skipping the members which aren't ready to receive, while ordinary subscribers of
+// name still receive every message. A message published with PublishKeyed is only
+// delivered to the member of the partition of its key. A group of a name can only
+// have max members, or the max set by SetMaxFor.
+func (p *Pubsub) QueueSubscribe(name, group string, c chan Event) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	g := p.queues[name][group]
+	var members []*subscriber
+	if g != nil {
+		members = g.members
+	}
+	if p.findChan(members, c) >= 0 {
+		return nil
+	}
+	if max := p.maxFor(byName, name); max > 0 && len(members) >= max {
+		p.Notify(Notice{Kind: NoticeMaxSubscribe, Name: name, Channel: c, Err: ErrMaxSubscribe})
+		return ErrMaxSubscribe
+	}
+	if !reserve(&p.numSubs, p.maxSubs) {
+		p.Notify(Notice{Kind: NoticeMaxSubscribe, Name: name, Channel: c, Err: ErrMaxSubscriptions})
+		return ErrMaxSubscriptions
+	}
+	if g == nil {
+		groups := p.queues[name]
+		if groups == nil {
+			groups = make(map[string]*queueGroup)
+			p.queues[name] = groups
+			if len(p.shard(name).channels[name]) == 0 {
+				p.emit(byName, true, name)
+			}
+		}
+		g = &queueGroup{name: group, next: new(atomic.Uint64)}
+		groups[group] = g
+	}
+	g.members = append(g.members, p.newSubscriber(c))
+	p.audit(AuditRecord{Action: AuditSubscribe, Name: name, Channel: c})
+	p.logSubscription("pubsub subscribe", RouteQueue, name, group, c)
+	p.notifyKeyspace(KeyspaceSubscribe, name, c, "")
+	p.changedQueue(name)
+	return nil
+}
+
+// QueueUnsubscribe unsubscribes channel c from the queue group of name.
+func (p *Pubsub) QueueUnsubscribe(name, group string, c chan Event) {
+	name = p.normalize(name)
+	if c == nil {
+		return
+	}
+
+	defer p.awaitSends(p.Subscription(c))
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if g := p.queues[name][group]; g != nil {
+		if i := p.findChan(g.members, c); i >= 0 {
+			p.removeMember(name, g, i)
+			p.changedQueue(name)
+		}
+	}
+}
+
+// QueueGroups returns the sorted queue groups of name.
+func (p *Pubsub) QueueGroups(name string) []string {
+	name = p.normalize(name)
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	ret := make([]string, 0, len(p.queues[name]))
+	for group := range p.queues[name] {
+		ret = append(ret, group)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// queueGroup is a queue group of a name. The members are copied on write, so a
+// copy of the group is a routing snapshot sharing the round-robin counter.
+type queueGroup struct {
+	name    string
+	members []*subscriber
+	next    *atomic.Uint64
+}
+
+// pick returns the members in the order to try for the next message, only the
+// member of the partition of key if any.
+func (g queueGroup) pick(key string, fn func(s *subscriber) bool) {
+	n := uint64(len(g.members))
+	if key != "" {
+		fn(g.members[uint64(fnv32(key))%n])
+		return
+	}
+	start := g.next.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		if fn(g.members[(start+i)%n]) {
+			return
+		}
+	}
+}
+
+func (p *Pubsub) removeMember(name string, g *queueGroup, i int) {
+	p.audit(AuditRecord{Action: AuditUnsubscribe, Name: name, Channel: g.members[i].c})
+	p.logSubscription("pubsub unsubscribe", RouteQueue, name, g.name, g.members[i].c)
+	p.unref(g.members[i].c)
+	p.numSubs.Add(-1)
+	p.notifyKeyspace(KeyspaceUnsubscribe, name, g.members[i].c, "")
+	g.members = append(g.members[:i:i], g.members[i+1:]...)
+	if len(g.members) == 0 {
+		delete(p.queues[name], g.name)
+		if len(p.queues[name]) == 0 {
+			delete(p.queues, name)
+			p.touch(name)
+			if len(p.shard(name).channels[name]) == 0 {
+				p.emit(byName, false, name)
+			}
+		}
+	}
+}
+
+// removeQueues unsubscribes c from all queue groups. It must be called with the
+// write lock held.
+func (p *Pubsub) removeQueues(c chan Event) {
+	for name, groups := range p.queues {
+		changed := false
+		for _, g := range groups {
+			if i := p.findChan(g.members, c); i >= 0 {
+				p.removeMember(name, g, i)
+				changed = true
+			}
+		}
+		if changed {
+			p.changedQueue(name)
+		}
+	}
+}
+
+// changedQueue updates the routing snapshot of the queue groups of name.
+func (p *Pubsub) changedQueue(name string) {
+	groups, ok := p.queues[name]
+	if !ok {
+		p.queueRoutes.Delete(name)
+		return
+	}
+	snapshot := make([]queueGroup, 0, len(groups))
+	for _, g := range groups {
+		snapshot = append(snapshot, *g)
+	}
+	p.queueRoutes.Store(name, snapshot)
+}
+
+// routeQueues calls fn with the queue groups of name.
+func (p *Pubsub) routeQueues(name string, fn func(g queueGroup)) {
+	if v, ok := p.queueRoutes.Load(name); ok {
+		for _, g := range v.([]queueGroup) {
+			fn(g)
+		}
+	}
+}
+
+// sendQueues sends event to one member of every queue group of its name. The
+// message is reported dropped once if no member is ready.
+func (p *Pubsub) sendQueues(event Event, result *PublishResult) {
+	p.routeQueues(event.Name, func(g queueGroup) {
+		var (
+			o    outcome
+			last *subscriber
+		)
+		g.pick(event.Key(), func(s *subscriber) bool {
+			if tried := p.trySend(s, event, ""); tried != skipped {
+				o, last = tried, s
+			}
+			return o == delivered
+		})
+		if o == dropped {
+			p.drop(last.c, event, "")
+		}
+		result.add(o)
+	})
+}
@@ -0,0 +1,60 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestQueueSubscribe(t *testing.T) {
+	ps := New(-1)
+	w1 := make(chan Event, 10)
+	w2 := make(chan Event, 10)
+	all := make(chan Event, 10)
+	assert.Equal(t, ps.QueueSubscribe("jobs", "workers", w1), nil)
+	assert.Equal(t, ps.QueueSubscribe("jobs", "workers", w2), nil)
+	ps.Subscribe("jobs", all)
+	assert.Equal(t, ps.QueueGroups("jobs"), []string{"workers"})
+	assert.Equal(t, ps.Topics(), []string{"jobs"})
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, ps.Publish("jobs", i), PublishResult{Delivered: 2})
+	}
+	assert.Equal(t, len(w1), 2)
+	assert.Equal(t, len(w2), 2)
+	assert.Equal(t, len(all), 4)
+
+	ps.QueueUnsubscribe("jobs", "workers", w1)
+	ps.Publish("jobs", 4)
+	assert.Equal(t, len(w2), 3)
+
+	ps.UnsubscribeAll(w2)
+	assert.Equal(t, ps.QueueGroups("jobs"), []string{})
+	assert.Equal(t, ps.Publish("jobs", 5), PublishResult{Delivered: 1})
+}
+
+func TestQueueSkipsBusyMembers(t *testing.T) {
+	ps := New(-1)
+	busy := make(chan Event)
+	idle := make(chan Event, 2)
+	ps.QueueSubscribe("jobs", "workers", busy)
+	ps.QueueSubscribe("jobs", "workers", idle)
+
+	assert.Equal(t, ps.Publish("jobs", 1), PublishResult{Delivered: 1})
+	assert.Equal(t, ps.Publish("jobs", 2), PublishResult{Delivered: 1})
+	assert.Equal(t, len(idle), 2)
+	assert.Equal(t, ps.Publish("jobs", 3), PublishResult{Dropped: 1})
+	assert.Equal(t, ps.Health().Dropped, uint64(1))
+}
+
+func TestQueuePublishCtx(t *testing.T) {
+	ps := New(-1)
+	w := make(chan Event, 1)
+	ps.QueueSubscribe("jobs", "workers", w)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Equal(t, ps.PublishCtx(ctx, "jobs", 1), nil)
+	assert.Equal(t, (<-w).Message, 1)
+}
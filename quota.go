@@ -0,0 +1,225 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrQuotaExceeded is the error of an operation over the Quota of its principal.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota is the limits of a principal, e.g. the identity of an authenticated user,
+// across all its connections to the gateways, like httpws and mqtt. Zero values
+// are unlimited.
+type Quota struct {
+	MaxSubscriptions        int        // concurrent subscriptions to names
+	MaxPatternSubscriptions int        // concurrent subscriptions to patterns and filters
+	PublishRate             rate.Limit // messages per second
+	PublishBurst            int        // messages published at once, at least 1
+}
+
+// QuotaLimit is a limit of a Quota.
+type QuotaLimit int
+
+const (
+	// QuotaSubscriptions is the limit of the MaxSubscriptions.
+	QuotaSubscriptions QuotaLimit = iota
+	// QuotaPatternSubscriptions is the limit of the MaxPatternSubscriptions.
+	QuotaPatternSubscriptions
+	// QuotaPublishRate is the limit of the PublishRate.
+	QuotaPublishRate
+)
+
+func (l QuotaLimit) String() string {
+	switch l {
+	case QuotaSubscriptions:
+		return "subscriptions"
+	case QuotaPatternSubscriptions:
+		return "pattern subscriptions"
+	case QuotaPublishRate:
+		return "publish rate"
+	}
+	return "unknown"
+}
+
+// QuotaError is the error of an operation of Principal over a limit of its Quota.
+type QuotaError struct {
+	Principal string
+	Limit     QuotaLimit
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("pubsub: %v of %q: %v", ErrQuotaExceeded, e.Principal, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// Quotas enforces the Quotas of principals, shared by the gateways serving them so
+// a principal can't get around its quota by opening more connections.
+type Quotas struct {
+	quota func(principal string) Quota
+
+	mu     sync.Mutex
+	usages map[string]*quotaUsage
+}
+
+// quotaUsage is the usage of a principal, by its open sessions.
+type quotaUsage struct {
+	quota    Quota
+	sessions int
+	subs     int
+	patterns int
+	limiter  *rate.Limiter
+}
+
+// NewQuotas returns Quotas enforcing the Quota returned by fn for each principal.
+// fn is called when a principal without open sessions opens one.
+func NewQuotas(fn func(principal string) Quota) *Quotas {
+	return &Quotas{quota: fn, usages: map[string]*quotaUsage{}}
+}
+
+// Session opens a session of principal, e.g. for a connection, which counts its
+// subscriptions until it's closed. The usage of a principal without a PublishRate
+// is forgotten once its last session is closed. Session of nil Quotas returns nil,
+// whose methods do nothing, so gateways without Quotas can use one.
+func (q *Quotas) Session(principal string) *QuotaSession {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := q.usage(principal)
+	u.sessions++
+	return &QuotaSession{q: q, principal: principal, u: u, subs: map[quotaKey]struct{}{}}
+}
+
+// Publish counts a message published by principal outside of a session, e.g. by a
+// unary request, and returns a *QuotaError if it's over the publish rate of the
+// principal. Publish of nil Quotas returns nil.
+func (q *Quotas) Publish(principal string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	u := q.usage(principal)
+	q.mu.Unlock()
+	return u.publish(principal)
+}
+
+// usage returns the usage of principal, with q.mu held.
+func (q *Quotas) usage(principal string) *quotaUsage {
+	u, ok := q.usages[principal]
+	if !ok {
+		u = &quotaUsage{quota: q.quota(principal)}
+		if u.quota.PublishRate > 0 {
+			u.limiter = rate.NewLimiter(u.quota.PublishRate, max(u.quota.PublishBurst, 1))
+		}
+		q.usages[principal] = u
+	}
+	return u
+}
+
+func (u *quotaUsage) publish(principal string) error {
+	if u.limiter == nil || u.limiter.Allow() {
+		return nil
+	}
+	return &QuotaError{Principal: principal, Limit: QuotaPublishRate}
+}
+
+// QuotaSession is a session of a principal opened by Quotas.Session.
+type QuotaSession struct {
+	q         *Quotas
+	principal string
+	u         *quotaUsage
+	subs      map[quotaKey]struct{} // guarded by q.mu
+	closed    bool
+}
+
+type quotaKey struct {
+	name    string
+	pattern bool
+}
+
+// Subscribe counts a subscription of the session to name, or to a pattern or filter
+// if pattern is true, and returns a *QuotaError if it's over the quota. A
+// subscription the session already has isn't counted again.
+func (s *QuotaSession) Subscribe(name string, pattern bool) error {
+	if s == nil {
+		return nil
+	}
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	key := quotaKey{name, pattern}
+	if _, ok := s.subs[key]; ok || s.closed {
+		return nil
+	}
+	count, limit, n := &s.u.subs, QuotaSubscriptions, s.u.quota.MaxSubscriptions
+	if pattern {
+		count, limit, n = &s.u.patterns, QuotaPatternSubscriptions, s.u.quota.MaxPatternSubscriptions
+	}
+	if n > 0 && *count >= n {
+		return &QuotaError{Principal: s.principal, Limit: limit}
+	}
+	*count++
+	s.subs[key] = struct{}{}
+	return nil
+}
+
+// Unsubscribe releases the subscription of the session to name counted by
+// Subscribe.
+func (s *QuotaSession) Unsubscribe(name string, pattern bool) {
+	if s == nil {
+		return
+	}
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	s.release(quotaKey{name, pattern})
+}
+
+// release releases the subscription of key, with q.mu held.
+func (s *QuotaSession) release(key quotaKey) {
+	if _, ok := s.subs[key]; !ok {
+		return
+	}
+	delete(s.subs, key)
+	if key.pattern {
+		s.u.patterns--
+	} else {
+		s.u.subs--
+	}
+}
+
+// Publish counts a message published by the session, and returns a *QuotaError if
+// it's over the publish rate of the principal.
+func (s *QuotaSession) Publish() error {
+	if s == nil {
+		return nil
+	}
+	return s.u.publish(s.principal)
+}
+
+// Close releases the subscriptions of the session.
+func (s *QuotaSession) Close() {
+	if s == nil {
+		return
+	}
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for key := range s.subs {
+		s.release(key)
+	}
+	s.u.sessions--
+	if s.u.sessions == 0 && s.u.limiter == nil {
+		// the publish rate outlives the sessions, so it can't be reset by reconnecting
+		delete(s.q.usages, s.principal)
+	}
+}
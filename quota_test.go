@@ -0,0 +1,38 @@
+package pubsub
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestQuotas(t *testing.T) {
+	q := NewQuotas(func(principal string) Quota {
+		return Quota{MaxSubscriptions: 1, MaxPatternSubscriptions: 1, PublishRate: 0.001}
+	})
+	first, second := q.Session("alice"), q.Session("alice")
+
+	assert.Equal(t, first.Subscribe("orders", false), nil)
+	assert.Equal(t, first.Subscribe("orders", false), nil)
+	err := second.Subscribe("users", false)
+	assert.Equal(t, err, error(&QuotaError{Principal: "alice", Limit: QuotaSubscriptions}))
+	assert.Equal(t, errors.Is(err, ErrQuotaExceeded), true)
+	assert.Equal(t, second.Subscribe("users/*", true), nil)
+	assert.Equal(t, q.Session("bob").Subscribe("users", false), nil)
+
+	first.Unsubscribe("orders", false)
+	assert.Equal(t, second.Subscribe("users", false), nil)
+	second.Close()
+	assert.Equal(t, first.Subscribe("orders/*", true), nil)
+
+	// the publish rate is shared by the sessions and the requests of a principal
+	assert.Equal(t, first.Publish(), nil)
+	assert.Equal(t, q.Publish("alice"), error(&QuotaError{Principal: "alice", Limit: QuotaPublishRate}))
+	first.Close()
+	assert.Equal(t, q.Session("alice").Publish() != nil, true)
+
+	var none *Quotas
+	assert.Equal(t, none.Session("alice").Subscribe("orders", false), nil)
+	assert.Equal(t, none.Publish("alice"), nil)
+}
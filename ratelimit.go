@@ -0,0 +1,106 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateMode is the behavior of a rate limited topic on messages published over the
+// limit.
+type RateMode int
+
+const (
+	// RateDrop discards the messages over the limit.
+	RateDrop RateMode = iota
+	// RateCoalesce keeps the latest message over the limit of each topic, which is
+	// published as soon as the limit allows, replacing the messages before it.
+	RateCoalesce
+	// RateBlock blocks the publisher until the limit allows the message.
+	RateBlock
+)
+
+// WithRateLimit limits the rate of the messages published to the topics matching
+// pattern, matched by the Matcher of the Pubsub, with limiter, which is shared by
+// all of them. mode selects what happens to the messages over the limit. The first
+// matching pattern wins.
+func WithRateLimit(pattern string, limiter *rate.Limiter, mode RateMode) Option {
+	return func(p *Pubsub) {
+		if p.limits == nil {
+			p.limits = &rateLimits{}
+		}
+		p.limits.rules = append(p.limits.rules, &rateRule{
+			pattern: pattern,
+			limiter: limiter,
+			mode:    mode,
+			pending: map[string]*Event{},
+		})
+	}
+}
+
+type rateRule struct {
+	pattern string
+	limiter *rate.Limiter
+	mode    RateMode
+
+	mu      sync.Mutex
+	pending map[string]*Event // latest coalesced message of each topic
+}
+
+type rateLimits struct {
+	rules []*rateRule
+	cache sync.Map // name -> *rateRule, nil if not limited
+}
+
+// rateRule returns the rate limit of name, or nil.
+func (p *Pubsub) rateRule(name string) *rateRule {
+	if v, ok := p.limits.cache.Load(name); ok {
+		return v.(*rateRule)
+	}
+	var rule *rateRule
+	for _, r := range p.limits.rules {
+		if p.matcher.Match(r.pattern, name) {
+			rule = r
+			break
+		}
+	}
+	p.limits.cache.Store(name, rule)
+	return rule
+}
+
+// admit returns whether event can be published now under the rate limit of its
+// topic, blocking with RateBlock until ctx is done, and coalescing it with
+// RateCoalesce.
+func (p *Pubsub) admit(ctx context.Context, event Event) bool {
+	rule := p.rateRule(event.Name)
+	if rule == nil {
+		return true
+	}
+	switch rule.mode {
+	case RateBlock:
+		return rule.limiter.Wait(ctx) == nil
+	case RateCoalesce:
+		rule.mu.Lock()
+		defer rule.mu.Unlock()
+		if pending, ok := rule.pending[event.Name]; ok {
+			*pending = event
+			return false
+		}
+		if rule.limiter.Allow() {
+			return true
+		}
+		rule.pending[event.Name] = &event
+		time.AfterFunc(rule.limiter.Reserve().Delay(), func() {
+			rule.mu.Lock()
+			pending := rule.pending[event.Name]
+			delete(rule.pending, event.Name)
+			rule.mu.Unlock()
+			p.publishTo(*pending, nil)
+		})
+		return false
+	default:
+		return rule.limiter.Allow()
+	}
+}
@@ -0,0 +1,50 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimit(t *testing.T) {
+	ps := New(-1,
+		WithRateLimit("drop.*", rate.NewLimiter(rate.Every(time.Hour), 2), RateDrop),
+		WithRateLimit("latest.*", rate.NewLimiter(rate.Every(20*time.Millisecond), 1), RateCoalesce),
+		WithRateLimit("block.*", rate.NewLimiter(rate.Every(20*time.Millisecond), 1), RateBlock),
+	)
+	c := make(chan Event, 10)
+	ps.Subscribe("drop.a", c)
+	ps.Subscribe("latest.a", c)
+	ps.Subscribe("block.a", c)
+	ps.Subscribe("other", c)
+
+	for i := 0; i < 5; i++ {
+		ps.Publish("drop.a", i)
+	}
+	assert.Equal(t, len(c), 2)
+	receiveN(t, c, 2)
+
+	for i := 0; i < 5; i++ {
+		ps.Publish("latest.a", i)
+	}
+	events := receiveN(t, c, 2)
+	assert.Equal(t, events[0].Message, 0)
+	assert.Equal(t, events[1].Message, 4)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		ps.Publish("block.a", i)
+	}
+	assert.Equal(t, time.Since(start) >= 30*time.Millisecond, true)
+	receiveN(t, c, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, ps.PublishCtx(ctx, "block.a", 3), context.Canceled)
+
+	ps.Publish("other", 1)
+	assert.Equal(t, len(c), 1)
+}
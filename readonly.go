@@ -0,0 +1,95 @@
+package pubsub
+
+import "context"
+
+// SubscriberOnly is the subscribe side of a Pubsub, which can't publish or change
+// the Pubsub otherwise.
+type SubscriberOnly interface {
+	Subscribe(name string, c chan Event) error
+	Unsubscribe(name string, c chan Event)
+	PSubscribe(pattern string, c chan Event) error
+	PUnsubscribe(pattern string, c chan Event)
+	HSubscribe(filter string, c chan Event) error
+	HUnsubscribe(filter string, c chan Event)
+	UnsubscribeAll(c chan Event)
+
+	SubscribeCtx(ctx context.Context, name string, c chan Event) error
+	PSubscribeCtx(ctx context.Context, pattern string, c chan Event) error
+	SubscribeFunc(ctx context.Context, name string, fn Handler) (*Subscription, error)
+	PSubscribeFunc(ctx context.Context, pattern string, fn Handler) (*Subscription, error)
+	SubscribeRetained(name string, c chan Event) error
+	Retained(name string) (interface{}, bool)
+
+	Topics() []string
+	Patterns() []string
+}
+
+// ReadOnly returns the subscribe side of the Pubsub, e.g. to give to untrusted
+// modules. The returned value can't be converted back to the Pubsub.
+func (p *Pubsub) ReadOnly() SubscriberOnly {
+	return readOnly{p: p}
+}
+
+type readOnly struct {
+	p *Pubsub
+}
+
+func (r readOnly) Subscribe(name string, c chan Event) error {
+	return r.p.Subscribe(name, c)
+}
+
+func (r readOnly) Unsubscribe(name string, c chan Event) {
+	r.p.Unsubscribe(name, c)
+}
+
+func (r readOnly) PSubscribe(pattern string, c chan Event) error {
+	return r.p.PSubscribe(pattern, c)
+}
+
+func (r readOnly) PUnsubscribe(pattern string, c chan Event) {
+	r.p.PUnsubscribe(pattern, c)
+}
+
+func (r readOnly) HSubscribe(filter string, c chan Event) error {
+	return r.p.HSubscribe(filter, c)
+}
+
+func (r readOnly) HUnsubscribe(filter string, c chan Event) {
+	r.p.HUnsubscribe(filter, c)
+}
+
+func (r readOnly) UnsubscribeAll(c chan Event) {
+	r.p.UnsubscribeAll(c)
+}
+
+func (r readOnly) SubscribeCtx(ctx context.Context, name string, c chan Event) error {
+	return r.p.SubscribeCtx(ctx, name, c)
+}
+
+func (r readOnly) PSubscribeCtx(ctx context.Context, pattern string, c chan Event) error {
+	return r.p.PSubscribeCtx(ctx, pattern, c)
+}
+
+func (r readOnly) SubscribeFunc(ctx context.Context, name string, fn Handler) (*Subscription, error) {
+	return r.p.SubscribeFunc(ctx, name, fn)
+}
+
+func (r readOnly) PSubscribeFunc(ctx context.Context, pattern string, fn Handler) (*Subscription, error) {
+	return r.p.PSubscribeFunc(ctx, pattern, fn)
+}
+
+func (r readOnly) SubscribeRetained(name string, c chan Event) error {
+	return r.p.SubscribeRetained(name, c)
+}
+
+func (r readOnly) Retained(name string) (interface{}, bool) {
+	return r.p.Retained(name)
+}
+
+func (r readOnly) Topics() []string {
+	return r.p.Topics()
+}
+
+func (r readOnly) Patterns() []string {
+	return r.p.Patterns()
+}
@@ -0,0 +1,24 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	ps := New(-1)
+	ro := ps.ReadOnly()
+	_, ok := ro.(interface {
+		Publish(string, interface{}) PublishResult
+	})
+	assert.Equal(t, ok, false)
+
+	c := make(chan Event, 1)
+	assert.Equal(t, ro.Subscribe("a", c), nil)
+	assert.Equal(t, ro.Topics(), []string{"a"})
+	ps.Publish("a", 1)
+	assert.Equal(t, (<-c).Message, 1)
+	ro.UnsubscribeAll(c)
+	assert.Equal(t, ro.Topics(), []string{})
+}
@@ -0,0 +1,363 @@
+// Package redisbridge mirrors the topics of a Pubsub with Redis Pub/Sub, so the
+// Pubsubs of multiple processes share the same topic space.
+//
+//	b := redisbridge.New(redis.NewClient(&redis.Options{Addr: addr}))
+//	ps := pubsub.New(-1, b.Options()...)
+//	go b.Run(ctx, ps)
+//
+// The bridge only subscribes to the Redis channels of the names and patterns which
+// have local subscribers or queue groups, and publishes every local message to
+// Redis while running. Messages are
+// encoded with the Codec of the bridge, or of the Pubsub, and received as []byte
+// with the HeaderContentType header, which Event.Into decodes.
+package redisbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// Error of running a Bridge which already ran.
+	ErrStarted = errors.New("bridge already started")
+	// Error of dropping a local message because the publishing to Redis is behind.
+	ErrBufferFull = errors.New("bridge buffer is full")
+)
+
+// sysPrefix is the prefix of the topics which stay local.
+const sysPrefix = "$SYS/"
+
+// Bridge mirrors the topics of a Pubsub with Redis.
+type Bridge struct {
+	client    redis.UniversalClient
+	prefix    string
+	codec     pubsub.Codec
+	translate func(pattern string) string
+	onError   func(err error)
+	origin    string
+	seq       atomic.Uint64
+	outbound  chan outbound
+	started   atomic.Bool
+	running   atomic.Bool
+	ps        atomic.Pointer[pubsub.Pubsub] // set by Run
+
+	mu       sync.Mutex
+	names    map[string]int // Redis channel -> local names
+	patterns map[string]int // Redis pattern -> local patterns
+	upstream *redis.PubSub  // set while running, guarded by mu
+}
+
+// Option configures a Bridge.
+type Option func(b *Bridge)
+
+// WithPrefix prefixes the Redis channels of the topics with prefix, to share a
+// Redis between multiple topic spaces.
+func WithPrefix(prefix string) Option {
+	return func(b *Bridge) {
+		b.prefix = prefix
+	}
+}
+
+// WithCodec encodes the messages with c instead of the Codec of the Pubsub.
+// Register c with pubsub.RegisterCodec for Into to decode the messages received.
+func WithCodec(c pubsub.Codec) Option {
+	return func(b *Bridge) {
+		b.codec = c
+	}
+}
+
+// WithPatterns translates the patterns of the Pubsub to Redis glob patterns with
+// translate, e.g. MQTTPattern for a Pubsub created WithMatcher(MQTTMatcher{}). A
+// Redis pattern may match more topics than the pattern, the Pubsub only delivers
+// the received messages to the matching subscribers. Patterns are passed as is by
+// default, which suits the GlobMatcher.
+func WithPatterns(translate func(pattern string) string) Option {
+	return func(b *Bridge) {
+		b.translate = translate
+	}
+}
+
+// WithBuffer buffers up to n local messages while publishing to Redis, 1024 by
+// default. Messages are dropped with ErrBufferFull when the buffer is full.
+func WithBuffer(n int) Option {
+	return func(b *Bridge) {
+		b.outbound = make(chan outbound, n)
+	}
+}
+
+// OnError calls fn with the errors of the bridge, which are ignored by default.
+func OnError(fn func(err error)) Option {
+	return func(b *Bridge) {
+		b.onError = fn
+	}
+}
+
+// MQTTPattern translates an MQTT topic filter to a Redis pattern.
+func MQTTPattern(pattern string) string {
+	pattern = strings.ReplaceAll(pattern, "+", "*")
+	if strings.HasSuffix(pattern, "/#") {
+		// # includes the parent level
+		return pattern[:len(pattern)-2] + "*"
+	}
+	return strings.ReplaceAll(pattern, "#", "*")
+}
+
+// New creates a Bridge publishing and subscribing with client.
+func New(client redis.UniversalClient, opts ...Option) *Bridge {
+	b := &Bridge{
+		client:    client,
+		translate: func(pattern string) string { return pattern },
+		onError:   func(error) {},
+		origin:    pubsub.UUIDv7().NewID(),
+		outbound:  make(chan outbound, 1024),
+		names:     map[string]int{},
+		patterns:  map[string]int{},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Options returns the options to create the Pubsub with, which tell the bridge the
+// names and patterns with local subscribers.
+func (b *Bridge) Options() []pubsub.Option {
+	return []pubsub.Option{
+		pubsub.OnTopicCreated(func(name string) { b.track(false, name, true) }),
+		pubsub.OnTopicDeleted(func(name string) { b.track(false, name, false) }),
+		pubsub.OnPatternCreated(func(pattern string) { b.track(true, pattern, true) }),
+		pubsub.OnPatternDeleted(func(pattern string) { b.track(true, pattern, false) }),
+	}
+}
+
+// Run mirrors the topics of ps, created with the Options of the bridge, until ctx
+// is done or Redis fails. It can only be called once.
+func (b *Bridge) Run(ctx context.Context, ps *pubsub.Pubsub) error {
+	if !b.started.CompareAndSwap(false, true) {
+		return ErrStarted
+	}
+	if b.codec == nil {
+		b.codec = ps.Codec()
+	}
+	b.ps.Store(ps)
+	remove := ps.Use(b.intercept)
+	defer remove()
+	ps.OnShutdownPhase(pubsub.ShutdownBridges, b.flush)
+
+	b.mu.Lock()
+	b.upstream = b.client.Subscribe(ctx)
+	upstream := b.upstream
+	var names, patterns []string
+	for name := range b.names {
+		names = append(names, name)
+	}
+	for pattern := range b.patterns {
+		patterns = append(patterns, pattern)
+	}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.upstream = nil
+		b.mu.Unlock()
+		upstream.Close()
+	}()
+	if len(names) > 0 {
+		if err := upstream.Subscribe(ctx, names...); err != nil {
+			return err
+		}
+	}
+	if len(patterns) > 0 {
+		if err := upstream.PSubscribe(ctx, patterns...); err != nil {
+			return err
+		}
+	}
+
+	b.running.Store(true)
+	defer b.running.Store(false)
+	go b.publish(ctx)
+
+	var last envelope
+	messages := upstream.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			topic, ok := strings.CutPrefix(msg.Channel, b.prefix)
+			if !ok {
+				continue
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				b.report(err)
+				continue
+			}
+			// Redis sends a message once for every matching subscription, in a row
+			if env.Origin == b.origin || (env.Origin == last.Origin && env.Seq == last.Seq) {
+				continue
+			}
+			last = env
+			ps.PublishMsg(topic, inbound{env.Data}, map[string]string{
+				pubsub.HeaderContentType: env.ContentType,
+			})
+		}
+	}
+}
+
+// track counts the local names or patterns of a Redis channel or pattern after
+// they got their first or lost their last subscriber, and subscribes to it while
+// it's counted.
+func (b *Bridge) track(pattern bool, key string, subscribed bool) {
+	if strings.HasPrefix(key, sysPrefix) {
+		return
+	}
+	set := b.names
+	if pattern {
+		set = b.patterns
+		key = b.translate(key)
+	}
+	key = b.channel(key)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if subscribed {
+		set[key]++
+		if set[key] > 1 {
+			return
+		}
+	} else {
+		set[key]--
+		if set[key] > 0 {
+			return
+		}
+		delete(set, key)
+	}
+	if b.upstream == nil {
+		return
+	}
+
+	ctx := context.Background()
+	var err error
+	switch {
+	case !pattern && subscribed:
+		err = b.upstream.Subscribe(ctx, key)
+	case !pattern:
+		err = b.upstream.Unsubscribe(ctx, key)
+	case subscribed:
+		err = b.upstream.PSubscribe(ctx, key)
+	default:
+		err = b.upstream.PUnsubscribe(ctx, key)
+	}
+	if err != nil {
+		b.report(err)
+	}
+}
+
+// channel returns the Redis channel or pattern of a topic or a pattern.
+func (b *Bridge) channel(topic string) string {
+	return b.prefix + topic
+}
+
+// envelope is the Redis message of a local message.
+type envelope struct {
+	Origin      string `json:"origin"` // the bridge which published the message
+	Seq         uint64 `json:"seq"`
+	ContentType string `json:"contentType"`
+	Data        []byte `json:"data"`
+}
+
+// inbound is a message received from Redis, published to the Pubsub without
+// publishing it to Redis again.
+type inbound struct {
+	data []byte
+}
+
+type outbound struct {
+	channel string
+	payload []byte
+}
+
+// intercept is the middleware publishing the local messages to Redis.
+func (b *Bridge) intercept(topic string, message interface{}, next func(topic string, message interface{})) {
+	if in, ok := message.(inbound); ok {
+		next(topic, in.data)
+		return
+	}
+	next(topic, message)
+	if b.running.Load() && !strings.HasPrefix(topic, sysPrefix) {
+		b.forward(topic, message)
+	}
+}
+
+// forward queues message for publishing to Redis.
+func (b *Bridge) forward(topic string, message interface{}) {
+	data, err := b.codec.Encode(message)
+	if err != nil {
+		b.report(err)
+		return
+	}
+	payload, err := json.Marshal(envelope{
+		Origin:      b.origin,
+		Seq:         b.seq.Add(1),
+		ContentType: b.codec.ContentType(),
+		Data:        data,
+	})
+	if err != nil {
+		b.report(err)
+		return
+	}
+	select {
+	case b.outbound <- outbound{b.channel(topic), payload}:
+	default:
+		b.report(ErrBufferFull)
+	}
+}
+
+// publish publishes the queued messages to Redis until ctx is done.
+func (b *Bridge) publish(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case o := <-b.outbound:
+			if err := b.client.Publish(ctx, o.channel, o.payload).Err(); err != nil {
+				b.report(err)
+			}
+		}
+	}
+}
+
+// flush publishes the queued messages to Redis when the Pubsub is shut down.
+func (b *Bridge) flush(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case o := <-b.outbound:
+			if err := b.client.Publish(ctx, o.channel, o.payload).Err(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// report reports err to the OnError handler, and as a notice of the Pubsub once
+// running.
+func (b *Bridge) report(err error) {
+	b.onError(err)
+	if ps := b.ps.Load(); ps != nil {
+		ps.Notify(pubsub.Notice{Kind: pubsub.NoticeBridgeError, Err: err})
+	}
+}
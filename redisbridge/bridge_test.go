@@ -0,0 +1,122 @@
+package redisbridge
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+	pubsub "github.com/kildevaeld/go-pubsub"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMQTTPattern(t *testing.T) {
+	assert.Equal(t, MQTTPattern("a/+/c"), "a/*/c")
+	assert.Equal(t, MQTTPattern("a/#"), "a*")
+	assert.Equal(t, MQTTPattern("#"), "*")
+}
+
+func TestTrack(t *testing.T) {
+	b := New(nil, WithPrefix("app:"), WithPatterns(MQTTPattern))
+	b.track(true, "a/+", true)
+	b.track(true, "a/#", true)
+	b.track(true, "a/#", false)
+	b.track(false, "b", true)
+	b.track(false, "$SYS/shed", true)
+	assert.Equal(t, b.patterns, map[string]int{"app:a/*": 1})
+	assert.Equal(t, b.names, map[string]int{"app:b": 1})
+}
+
+// TestBridge needs a Redis server at $REDIS_ADDR.
+func TestBridge(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR isn't set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge := func() *pubsub.Pubsub {
+		b := New(redis.NewClient(&redis.Options{Addr: addr}), WithPrefix(t.Name()+":"))
+		ps := pubsub.New(-1, b.Options()...)
+		go b.Run(ctx, ps)
+		return ps
+	}
+	a, b := bridge(), bridge()
+
+	c := make(chan pubsub.Event, 10)
+	b.Subscribe("orders", c)
+	p := make(chan pubsub.Event, 10)
+	b.PSubscribe("orders*", p)
+	local := make(chan pubsub.Event, 10)
+	a.Subscribe("orders", local)
+	time.Sleep(100 * time.Millisecond)
+
+	a.Publish("orders", map[string]int{"id": 1})
+	var got map[string]int
+	assert.Equal(t, (<-c).Into(&got), nil)
+	assert.Equal(t, got, map[string]int{"id": 1})
+	assert.Equal(t, (<-p).Into(&got), nil)
+	assert.Equal(t, (<-local).Message, map[string]int{"id": 1})
+
+	// neither echoed back nor delivered twice
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, len(local), 0)
+	assert.Equal(t, len(c), 0)
+}
+
+// TestBridgeQueue needs a Redis server at $REDIS_ADDR.
+func TestBridgeQueue(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR isn't set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bridge := func() *pubsub.Pubsub {
+		b := New(redis.NewClient(&redis.Options{Addr: addr}), WithPrefix(t.Name()+":"))
+		ps := pubsub.New(-1, b.Options()...)
+		go b.Run(ctx, ps)
+		return ps
+	}
+	a, b := bridge(), bridge()
+
+	// queue groups subscribe the bridge to their topic
+	c := make(chan pubsub.Event, 10)
+	b.QueueSubscribe("jobs", "workers", c)
+	time.Sleep(100 * time.Millisecond)
+
+	a.Publish("jobs", 1)
+	var got int
+	assert.Equal(t, (<-c).Into(&got), nil)
+	assert.Equal(t, got, 1)
+}
+
+func TestBridgeCodec(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR isn't set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := New(redis.NewClient(&redis.Options{Addr: addr}), WithPrefix(t.Name()+":"))
+	local := pubsub.New(-1, append(a.Options(), pubsub.WithCodec(pubsub.Gob))...)
+	go a.Run(ctx, local)
+	b := New(redis.NewClient(&redis.Options{Addr: addr}), WithPrefix(t.Name()+":"))
+	ps := pubsub.New(-1, b.Options()...)
+	go b.Run(ctx, ps)
+
+	c := make(chan pubsub.Event, 1)
+	ps.Subscribe("orders", c)
+	time.Sleep(100 * time.Millisecond)
+	local.Publish("orders", 1)
+
+	e := <-c
+	assert.Equal(t, e.Headers[pubsub.HeaderContentType], pubsub.Gob.ContentType())
+	var n int
+	assert.Equal(t, e.Into(&n), nil)
+	assert.Equal(t, n, 1)
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strconv"
+)
+
+// InboxPrefix is the prefix of the reply topics of Request.
+const InboxPrefix = "$INBOX/"
+
+// Error of a request which no channel received.
+var ErrNoResponders = errors.New("no responders")
+
+// Request publishes a message to name with a unique reply topic, and waits for the
+// first reply to it, sent with Reply. The request is delivered before Request
+// waits, without the dispatch workers. It returns ErrNoResponders if no channel
+// received the request, the error of the publish, e.g. a *SchemaError, or
+// ctx.Err() if ctx is done before a reply.
+func (p *Pubsub) Request(ctx context.Context, name string, message interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	reply := InboxPrefix + strconv.FormatUint(p.inboxes.Add(1), 10)
+	c := make(chan Event, 1)
+	if err := p.Subscribe(reply, c); err != nil {
+		return nil, err
+	}
+	defer p.Unsubscribe(reply, c)
+
+	event := p.newEvent(name, message)
+	event.Reply = reply
+	var result PublishResult
+	p.intercept(event, func(e Event) {
+		if err := p.validate(e); err != nil {
+			result.merge(PublishResult{Err: err})
+			return
+		}
+		// not queued for the dispatch workers, to know whether it was delivered
+		result.merge(p.publishNow(e, nil))
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	if result.Delivered == 0 {
+		return nil, ErrNoResponders
+	}
+	select {
+	case e := <-c:
+		return e.Message, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reply publishes a message to the reply topic of request. It does nothing if
+// request wasn't published by Request.
+func (p *Pubsub) Reply(request Event, message interface{}) PublishResult {
+	if request.Reply == "" {
+		return PublishResult{}
+	}
+	return p.Publish(request.Reply, message)
+}
@@ -0,0 +1,62 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestRequest(t *testing.T) {
+	ps := New(-1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := ps.Request(ctx, "double", 1)
+	assert.Equal(t, err, ErrNoResponders)
+
+	c := make(chan Event, 1)
+	ps.Subscribe("double", c)
+	go func() {
+		for e := range c {
+			ps.Reply(e, e.Message.(int)*2)
+		}
+	}()
+	resp, err := ps.Request(ctx, "double", 21)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp, 42)
+	assert.Equal(t, ps.Topics(), []string{"double"})
+
+	assert.Equal(t, ps.Reply(Event{Name: "double"}, 1), PublishResult{})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.Subscribe("silent", c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := ps.Request(ctx, "silent", 1)
+	assert.Equal(t, err, context.DeadlineExceeded)
+	assert.Equal(t, (<-c).Reply != "", true)
+}
+
+func TestRequestDispatched(t *testing.T) {
+	ps := New(-1, WithDispatchWorkers(2))
+	defer ps.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	c := make(chan Event, 1)
+	ps.Subscribe("double", c)
+	go func() {
+		for e := range c {
+			ps.Reply(e, e.Message.(int)*2)
+		}
+	}()
+	resp, err := ps.Request(ctx, "double", 21)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp, 42)
+}
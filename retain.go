@@ -0,0 +1,182 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PublishRetain publishes a message like Publish and keeps it as the retained
+// message of name, which replaces any previous one. Channels subscribed with
+// SubscribeRetained receive the retained message immediately, which they may
+// receive twice if they subscribe while it's published. A nil message clears the
+// retained message of name without publishing anything.
+func (p *Pubsub) PublishRetain(name string, message interface{}) PublishResult {
+	if message == nil {
+		name = p.normalize(name)
+		p.locker.Lock()
+		defer p.locker.Unlock()
+		delete(p.retained, name)
+		return PublishResult{}
+	}
+	var result PublishResult
+	p.intercept(p.newEvent(name, message), func(event Event) {
+		result.merge(p.publishRetain(event))
+	})
+	return result
+}
+
+func (p *Pubsub) publishRetain(event Event) PublishResult {
+	if err := p.validate(event); err != nil {
+		return PublishResult{Err: err}
+	}
+	p.locker.Lock()
+	name := event.Name
+	old, ok := p.retained[name]
+	p.retained[name] = event
+	if p.differ == nil {
+		// delivered without the lock, which the dispatch workers need
+		p.locker.Unlock()
+		return p.publish(event)
+	}
+	// the diffs are sent in the order of the retained messages
+	p.diffs.Lock()
+	p.locker.Unlock()
+	defer p.diffs.Unlock()
+	end := p.trace(context.Background(), &event)
+	result := p.publishDiff(event, old, ok)
+	if end != nil {
+		end(result)
+	}
+	return result
+}
+
+// Retained returns the retained message of name.
+func (p *Pubsub) Retained(name string) (interface{}, bool) {
+	name = p.normalize(name)
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	event, ok := p.retained[name]
+	if ok && p.expired(event) {
+		return nil, false
+	}
+	return event.Message, ok
+}
+
+// SubscribeRetained subscribes channel c like Subscribe, and sends the retained
+// message of name to c if there is one. The retained message is dropped if c isn't
+// ready to receive.
+func (p *Pubsub) SubscribeRetained(name string, c chan Event) error {
+	name = p.normalize(name)
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	backfill, err := p.subscribeRetained(name, c)
+	p.locker.Unlock()
+	backfill()
+	return err
+}
+
+// subscribeRetained subscribes c to name with the write lock held, and returns the
+// function sending the retained message of name to c, to call once the lock is
+// released so the drop hooks can use the Pubsub.
+func (p *Pubsub) subscribeRetained(name string, c chan Event) (func(), error) {
+	s, err := p.add(byName, name, c)
+	if err != nil {
+		return noop, err
+	}
+	event, ok := p.retained[name]
+	if !ok || p.expired(event) {
+		return noop, nil
+	}
+	return func() {
+		p.send(s, event, "")
+	}, nil
+}
+
+// RetainedMatching returns the retained messages of the names matching the
+// hierarchical topic filter, like HSubscribe, sorted by name. It returns
+// ErrBadFilter if filter is malformed.
+func (p *Pubsub) RetainedMatching(filter string) ([]Event, error) {
+	filter = p.normalize(filter)
+	if !validFilter(filter) {
+		return nil, ErrBadFilter
+	}
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+
+	var ret []Event
+	for name, event := range p.retained {
+		if !p.expired(event) && (MQTTMatcher{}).Match(filter, name) {
+			ret = append(ret, event)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+type retainedRecord struct {
+	ID      string          `json:"id,omitempty"`
+	Name    string          `json:"name"`
+	Message json.RawMessage `json:"message"`
+}
+
+// ExportRetained writes the retained messages to w as JSON, one object per line.
+// Messages must be encodable with encoding/json.
+func (p *Pubsub) ExportRetained(w io.Writer) error {
+	p.locker.RLock()
+	names := make([]string, 0, len(p.retained))
+	for name := range p.retained {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	events := make([]Event, len(names))
+	for i, name := range names {
+		events[i] = p.retained[name]
+	}
+	p.locker.RUnlock()
+
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		msg, err := json.Marshal(event.Message)
+		if err != nil {
+			return fmt.Errorf("pubsub: export retained %q: %w", event.Name, err)
+		}
+		if err := enc.Encode(retainedRecord{ID: event.ID, Name: event.Name, Message: msg}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportRetained reads retained messages written by ExportRetained from r and keeps
+// them as the retained messages of their topics, without publishing them. Imported
+// messages are json.RawMessage, which can be decoded with Event.Into.
+func (p *Pubsub) ImportRetained(r io.Reader) error {
+	var events []Event
+	dec := json.NewDecoder(r)
+	for {
+		var record retainedRecord
+		if err := dec.Decode(&record); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("pubsub: import retained: %w", err)
+		}
+		events = append(events, Event{ID: record.ID, Name: p.normalize(record.Name), Message: record.Message})
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	for _, event := range events {
+		p.retained[event.Name] = event
+		p.touch(event.Name)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestRetain(t *testing.T) {
+	ps := New(-1)
+	live := make(chan Event, 1)
+	ps.Subscribe("config", live)
+
+	ps.PublishRetain("config", "v1")
+	assert.Equal(t, (<-live).Message, "v1")
+	ps.PublishRetain("config", "v2")
+	assert.Equal(t, (<-live).Message, "v2")
+
+	late := make(chan Event, 1)
+	assert.Equal(t, ps.SubscribeRetained("config", late), nil)
+	assert.Equal(t, <-late, Event{Name: "config", Message: "v2"})
+
+	msg, ok := ps.Retained("config")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, msg, "v2")
+
+	ps.PublishRetain("config", nil)
+	_, ok = ps.Retained("config")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(live), 0)
+
+	empty := make(chan Event, 1)
+	ps.SubscribeRetained("config", empty)
+	assert.Equal(t, len(empty), 0)
+}
+
+func TestRetainNormalized(t *testing.T) {
+	ps := New(-1, WithTopicNormalizer(CaseInsensitive))
+	ps.PublishRetain("Config", "v1")
+	ps.PublishRetain("CONFIG", nil)
+	_, ok := ps.Retained("config")
+	assert.Equal(t, ok, false)
+
+	assert.Equal(t, ps.ImportRetained(strings.NewReader(`{"name":"State","message":1}`)), nil)
+	msg, ok := ps.Retained("state")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, string(msg.(json.RawMessage)), "1")
+}
+
+func TestRetainDispatched(t *testing.T) {
+	var ps *Pubsub
+	ps = New(-1, WithDispatchWorkers(1), WithDispatchQueue(1), OnDrop(func(d DroppedMessage) {
+		// while the next messages fill the queue
+		time.Sleep(10 * time.Millisecond)
+		ps.Retained(d.Name)
+	}))
+	defer ps.Close()
+	ps.Subscribe("a", make(chan Event))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			ps.PublishRetain("a", i)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestSubscribeRetainedDropHandler(t *testing.T) {
+	var ps *Pubsub
+	ps = New(-1, WithDiffer(DifferFunc(func(string, interface{}, interface{}) (interface{}, bool) {
+		return nil, false
+	})), OnDrop(func(d DroppedMessage) {
+		ps.Retained(d.Name)
+	}))
+	ps.PublishRetain("a", 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ps.SubscribeRetained("a", make(chan Event))
+		ps.SubscribeLate("a", make(chan Event), BackfillRetained)
+		ps.SubscribeDiff("a", make(chan Event))
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+	assert.Equal(t, ps.Stats().Dropped, uint64(3))
+}
+
+func TestExportImportRetained(t *testing.T) {
+	src := New(-1)
+	src.PublishRetain("b", point{1, 2})
+	src.PublishRetain("a", "state")
+
+	var buf bytes.Buffer
+	assert.Equal(t, src.ExportRetained(&buf), nil)
+	assert.Equal(t, buf.String(), `{"name":"a","message":"state"}
+{"name":"b","message":{"X":1,"Y":2}}
+`)
+
+	dst := New(-1)
+	assert.Equal(t, dst.ImportRetained(&buf), nil)
+	c := make(chan Event, 1)
+	dst.SubscribeRetained("b", c)
+	var p point
+	assert.Equal(t, (<-c).Into(&p), nil)
+	assert.Equal(t, p, point{1, 2})
+
+	assert.Equal(t, dst.ImportRetained(strings.NewReader("{")) != nil, true)
+	src.PublishRetain("c", make(chan int))
+	assert.Equal(t, src.ExportRetained(&buf) != nil, true)
+}
+
+func TestRetainedMatching(t *testing.T) {
+	ps := New(-1)
+	ps.PublishRetain("devices/2/status", "off")
+	ps.PublishRetain("devices/1/status", "on")
+	ps.PublishRetain("devices/1/config", "cfg")
+	ps.PublishRetain("$SYS/status", "up")
+
+	events, err := ps.RetainedMatching("devices/+/status")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[0].Name, "devices/1/status")
+	assert.Equal(t, events[1].Message, "off")
+
+	events, err = ps.RetainedMatching("#")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(events), 3)
+
+	_, err = ps.RetainedMatching("devices/#/status")
+	assert.Equal(t, err, ErrBadFilter)
+}
@@ -0,0 +1,194 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Error of reading from a ring which is stopped and fully consumed.
+var ErrRingClosed = errors.New("ring is closed")
+
+// Broadcast returns the ring buffer of name, creating it with size slots if it
+// doesn't exist. Every message published to name is written to the ring once, and
+// every RingReader of the ring reads it with its own cursor, instead of Publish
+// sending it to a channel per subscriber. Size is rounded up to a power of two.
+func (p *Pubsub) Broadcast(name string, size int) *Ring {
+	name = p.normalize(name)
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	if r, ok := p.rings[name]; ok {
+		return r
+	}
+	r := newRing(size, p.clock)
+	p.rings[name] = r
+	p.changedRings()
+	return r
+}
+
+// StopBroadcast removes the ring buffer of name. Readers of the ring get
+// ErrRingClosed after they consumed the messages left in the ring.
+func (p *Pubsub) StopBroadcast(name string) {
+	name = p.normalize(name)
+	p.locker.Lock()
+	r, ok := p.rings[name]
+	delete(p.rings, name)
+	p.changedRings()
+	p.locker.Unlock()
+
+	if ok {
+		r.close()
+	}
+}
+
+func (p *Pubsub) changedRings() {
+	rings := make(map[string]*Ring, len(p.rings))
+	for name, r := range p.rings {
+		rings[name] = r
+	}
+	p.ringRoutes.Store(&rings)
+}
+
+// Ring is a lock free ring buffer shared by all readers of a topic. A slow reader
+// never blocks the publisher, it's lapped and skips the overwritten messages.
+type Ring struct {
+	slots  []ringSlot
+	mask   uint64
+	head   atomic.Uint64
+	closed atomic.Bool
+
+	waiting atomic.Int32
+	mu      sync.Mutex
+	signal  chan struct{}
+	clock   Clock // of the expiry of the messages
+}
+
+type ringSlot struct {
+	seq   atomic.Uint64 // sequence+1 of the event in the slot, 0 while writing
+	event atomic.Pointer[Event]
+}
+
+func newRing(size int, clock Clock) *Ring {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	return &Ring{
+		slots:  make([]ringSlot, n),
+		mask:   uint64(n - 1),
+		signal: make(chan struct{}),
+		clock:  clock,
+	}
+}
+
+// Reader returns a new reader of the ring, which starts reading at the next
+// published message.
+func (r *Ring) Reader() *RingReader {
+	return &RingReader{
+		ring: r,
+		next: r.head.Load(),
+	}
+}
+
+func (r *Ring) publish(event Event) {
+	n := r.head.Add(1) - 1
+	slot := &r.slots[n&r.mask]
+	slot.seq.Store(0)
+	slot.event.Store(&event)
+	slot.seq.Store(n + 1)
+	r.wake()
+}
+
+func (r *Ring) close() {
+	r.closed.Store(true)
+	r.wake()
+}
+
+func (r *Ring) wake() {
+	if r.waiting.Load() == 0 {
+		return
+	}
+	r.mu.Lock()
+	close(r.signal)
+	r.signal = make(chan struct{})
+	r.mu.Unlock()
+}
+
+// RingReader reads a Ring with its own cursor. A RingReader must not be used by
+// multiple goroutines at the same time.
+type RingReader struct {
+	ring *Ring
+	next uint64
+	lost uint64
+}
+
+// Lost returns the number of messages the reader skipped because it was lapped.
+func (rr *RingReader) Lost() uint64 {
+	return rr.lost
+}
+
+// TryNext returns the next message of the ring, or false if there is no new message.
+func (rr *RingReader) TryNext() (Event, bool) {
+	r := rr.ring
+	size := uint64(len(r.slots))
+	for {
+		slot := &r.slots[rr.next&r.mask]
+		want := rr.next + 1
+		seq := slot.seq.Load()
+		if seq == want {
+			event := slot.event.Load()
+			if slot.seq.Load() == want {
+				rr.next++
+				if event.expiredAt(r.clock.Now()) {
+					continue
+				}
+				return *event, true
+			}
+		} else if seq < want && r.head.Load()-rr.next <= size {
+			return Event{}, false
+		}
+		// lapped by the publisher, skip to the oldest message kept in the ring
+		if head := r.head.Load(); head > size && head-size > rr.next {
+			rr.lost += head - size - rr.next
+			rr.next = head - size
+		} else {
+			return Event{}, false
+		}
+	}
+}
+
+// Next returns the next message of the ring, waiting until one is published, ctx is
+// done or the ring is stopped.
+func (rr *RingReader) Next(ctx context.Context) (Event, error) {
+	r := rr.ring
+	for {
+		if event, ok := rr.TryNext(); ok {
+			return event, nil
+		}
+		if r.closed.Load() {
+			return Event{}, ErrRingClosed
+		}
+
+		r.waiting.Add(1)
+		r.mu.Lock()
+		signal := r.signal
+		r.mu.Unlock()
+		if event, ok := rr.TryNext(); ok {
+			r.waiting.Add(-1)
+			return event, nil
+		}
+		if r.closed.Load() {
+			r.waiting.Add(-1)
+			return Event{}, ErrRingClosed
+		}
+		select {
+		case <-signal:
+			r.waiting.Add(-1)
+		case <-ctx.Done():
+			r.waiting.Add(-1)
+			return Event{}, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestRing(t *testing.T) {
+	ps := New(-1)
+	r := ps.Broadcast("hot", 3)
+	assert.Equal(t, len(r.slots), 4)
+	assert.Equal(t, ps.Broadcast("hot", 8), r)
+
+	r1 := r.Reader()
+	_, ok := r1.TryNext()
+	assert.Equal(t, ok, false)
+
+	ps.Publish("hot", 1)
+	r2 := r.Reader()
+	ps.Publish("hot", 2)
+
+	e, ok := r1.TryNext()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, e, Event{Name: "hot", Message: 1})
+	e, _ = r1.TryNext()
+	assert.Equal(t, e.Message, 2)
+	e, _ = r2.TryNext()
+	assert.Equal(t, e.Message, 2)
+}
+
+func TestRingLapped(t *testing.T) {
+	ps := New(-1)
+	rd := ps.Broadcast("hot", 4).Reader()
+	for i := 0; i < 10; i++ {
+		ps.Publish("hot", i)
+	}
+	e, ok := rd.TryNext()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, e.Message, 6)
+	assert.Equal(t, rd.Lost(), uint64(6))
+}
+
+func TestRingNext(t *testing.T) {
+	ps := New(-1)
+	r := ps.Broadcast("hot", 1024)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		rd := r.Reader()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				e, err := rd.Next(context.Background())
+				assert.Equal(t, err, nil)
+				assert.Equal(t, e.Message, i)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		ps.Publish("hot", i)
+	}
+	wg.Wait()
+
+	rd := r.Reader()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := rd.Next(ctx)
+	assert.Equal(t, err, context.DeadlineExceeded)
+
+	ps.StopBroadcast("hot")
+	_, err = rd.Next(context.Background())
+	assert.Equal(t, err, ErrRingClosed)
+}
@@ -0,0 +1,99 @@
+package pubsub
+
+// RouteKind is how the channels of a Route are subscribed.
+type RouteKind int
+
+const (
+	// RouteName is the channels subscribed by name.
+	RouteName RouteKind = iota
+	// RoutePattern is the channels subscribed to a pattern matching the name.
+	RoutePattern
+	// RouteFilter is the channels subscribed to a filter matching the name.
+	RouteFilter
+	// RouteQueue is the members of a queue group of the name, one of which
+	// receives each message.
+	RouteQueue
+)
+
+func (k RouteKind) String() string {
+	switch k {
+	case RouteName:
+		return "name"
+	case RoutePattern:
+		return "pattern"
+	case RouteFilter:
+		return "filter"
+	case RouteQueue:
+		return "queue"
+	}
+	return "unknown"
+}
+
+// Route is a step of the delivery of the messages of a name: the channels
+// subscribed by name, to a matching pattern or filter, or in a queue group.
+type Route struct {
+	Kind     RouteKind
+	Pattern  string       // the name, pattern, filter or queue group
+	Channels []chan Event // in the order they are sent to, or tried in turn by a queue group
+
+	// The messages delivered to and dropped by the channels of the route, of every
+	// name it routes, only kept if the Pubsub is created WithTopicStats.
+	Delivered uint64
+	Dropped   uint64
+}
+
+// ExplainRoute returns the routes a message published to name would be sent
+// through, in the order of delivery. The order of the patterns isn't fixed unless
+// FlagPatternIndex is enabled.
+func (p *Pubsub) ExplainRoute(name string) []Route {
+	name = p.normalize(name)
+	var ret []Route
+	p.routeGroups(name, func(k kind, pattern string, subs []*subscriber) {
+		route := Route{Kind: RouteName, Pattern: name}
+		switch k {
+		case byPattern:
+			route = Route{Kind: RoutePattern, Pattern: pattern}
+		case byFilter:
+			route = Route{Kind: RouteFilter, Pattern: pattern}
+		}
+		ret = append(ret, newRoute(route, subs))
+	})
+	p.routeQueues(name, func(g queueGroup) {
+		ret = append(ret, newRoute(Route{Kind: RouteQueue, Pattern: g.name}, g.members))
+	})
+	return ret
+}
+
+func newRoute(route Route, subs []*subscriber) Route {
+	route.Channels = make([]chan Event, len(subs))
+	for i, s := range subs {
+		route.Channels[i] = s.c
+		if s.counters != nil {
+			route.Delivered += s.counters.delivered.Load()
+			route.Dropped += s.counters.dropped.Load()
+		}
+	}
+	return route
+}
+
+// newSubscriber returns a subscriber of c, counting its messages WithTopicStats.
+func (p *Pubsub) newSubscriber(c chan Event) *subscriber {
+	s := &subscriber{c: c, sub: p.ref(c)}
+	if p.stats != nil {
+		s.counters = &topicCounters{}
+	}
+	return s
+}
+
+// countRoute counts a message sent to the route of s.
+func countRoute(s *subscriber, o outcome) {
+	if s.counters == nil {
+		return
+	}
+	switch o {
+	case delivered:
+		s.counters.delivered.Add(1)
+	case dropped:
+		s.counters.dropped.Add(1)
+	}
+}
@@ -0,0 +1,48 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestExplainRoute(t *testing.T) {
+	ps := New(-1, WithTopicStats())
+	byName, byPattern, member := make(chan Event, 1), make(chan Event, 1), make(chan Event, 1)
+	assert.Equal(t, ps.Subscribe("a/b", byName), nil)
+	assert.Equal(t, ps.PSubscribe("a/*", byPattern), nil)
+	assert.Equal(t, ps.PSubscribe("x/*", make(chan Event)), nil)
+	assert.Equal(t, ps.QueueSubscribe("a/b", "workers", member), nil)
+
+	ps.Publish("a/b", 1)
+	ps.Publish("a/b", 2)
+
+	routes := ps.ExplainRoute("a/b")
+	assert.Equal(t, len(routes), 3)
+	assert.Equal(t, routes[0].Kind, RouteName)
+	assert.Equal(t, routes[0].Pattern, "a/b")
+	assert.Equal(t, routes[0].Channels, []chan Event{byName})
+	assert.Equal(t, routes[0].Delivered, uint64(1))
+	assert.Equal(t, routes[0].Dropped, uint64(1))
+	assert.Equal(t, routes[1].Kind, RoutePattern)
+	assert.Equal(t, routes[1].Pattern, "a/*")
+	assert.Equal(t, routes[1].Channels, []chan Event{byPattern})
+	assert.Equal(t, routes[2].Kind, RouteQueue)
+	assert.Equal(t, routes[2].Pattern, "workers")
+	assert.Equal(t, routes[2].Channels, []chan Event{member})
+	assert.Equal(t, routes[2].Delivered, uint64(1))
+
+	assert.Equal(t, len(ps.ExplainRoute("other")), 0)
+}
+
+func TestExplainRouteWithoutStats(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	assert.Equal(t, ps.PSubscribe("a/*", c), nil)
+	ps.Publish("a/b", 1)
+
+	routes := ps.ExplainRoute("a/b")
+	assert.Equal(t, len(routes), 1)
+	assert.Equal(t, routes[0].Kind.String(), "pattern")
+	assert.Equal(t, routes[0].Delivered, uint64(0))
+}
@@ -0,0 +1,117 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// routeCacheShards is the number of shards of the route cache.
+	routeCacheShards = 16
+	// routeCacheSize is the number of names kept by each shard of the route cache.
+	routeCacheSize = 64
+)
+
+// routeCache is a small cache of the patterns and filters matching the recently
+// published names, so publishing to the same names again doesn't match every
+// pattern again. Each shard is an immutable snapshot of a generation of the
+// routing snapshots, so a hit doesn't take a lock.
+type routeCache struct {
+	shards   [routeCacheShards]routeCacheShard
+	disabled bool // set by the benchmarks of the matchers
+}
+
+type routeCacheShard struct {
+	mu       sync.Mutex // serializes the updates of the snapshot
+	snapshot atomic.Pointer[routeSnapshot]
+}
+
+type routeSnapshot struct {
+	generation uint64
+	entries    map[string][]target
+}
+
+// matches returns the subscribers of the patterns and filters matching name, with
+// the pattern or filter, in the order of route.
+func (p *Pubsub) matches(name string) []target {
+	if len(p.patternRoutes.Load().subs) == 0 && len(p.filterRoutes.Load().subs) == 0 {
+		return nil
+	}
+	// load the generation first, so changes while matching refresh the entry again
+	generation := p.generation.Load()
+	sh := &p.routeCache.shards[fnv32(name)%routeCacheShards]
+	if snap := sh.snapshot.Load(); snap != nil && snap.generation == generation {
+		if targets, ok := snap.entries[name]; ok {
+			return targets
+		}
+	}
+
+	// a new slice, the previous targets may still be sent to
+	var targets []target
+	p.matchRoutes(name, func(s *subscriber, pattern string) {
+		targets = append(targets, target{s, pattern})
+	})
+	if !p.routeCache.disabled {
+		sh.store(generation, name, targets)
+	}
+	return targets
+}
+
+// store adds the targets of name to a copy of the snapshot of generation, replacing
+// an arbitrary entry if the shard is full.
+func (sh *routeCacheShard) store(generation uint64, name string, targets []target) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	old := sh.snapshot.Load()
+	if old != nil && old.generation > generation {
+		// matched with outdated routes
+		return
+	}
+	entries := make(map[string][]target, routeCacheSize)
+	if old != nil && old.generation == generation {
+		skip := len(old.entries) >= routeCacheSize
+		for k, v := range old.entries {
+			if skip && k != name {
+				skip = false
+				continue
+			}
+			entries[k] = v
+		}
+	}
+	entries[name] = targets
+	sh.snapshot.Store(&routeSnapshot{generation: generation, entries: entries})
+}
+
+// forget drops the cached matches of names.
+func (c *routeCache) forget(names map[string]bool) {
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.mu.Lock()
+		if old := sh.snapshot.Load(); old != nil {
+			entries := make(map[string][]target, len(old.entries))
+			for k, v := range old.entries {
+				if !names[k] {
+					entries[k] = v
+				}
+			}
+			if len(entries) < len(old.entries) {
+				sh.snapshot.Store(&routeSnapshot{generation: old.generation, entries: entries})
+			}
+		}
+		sh.mu.Unlock()
+	}
+}
+
+// matchRoutes calls fn with the subscribers of the patterns and filters matching
+// name, like route.
+func (p *Pubsub) matchRoutes(name string, fn func(s *subscriber, pattern string)) {
+	p.routeGroups(name, func(k kind, pattern string, subs []*subscriber) {
+		if k == byName {
+			return
+		}
+		for _, s := range subs {
+			fn(s, pattern)
+		}
+	})
+}
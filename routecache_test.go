@@ -0,0 +1,63 @@
+package pubsub
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestRouteCache(t *testing.T) {
+	ps := New(-1)
+	a, b := make(chan Event, 8), make(chan Event, 8)
+	assert.Equal(t, ps.PSubscribe("orders/*", a), nil)
+
+	assert.Equal(t, ps.Publish("orders/1", 1).Delivered, 1)
+	assert.Equal(t, ps.Publish("orders/1", 2).Delivered, 1)
+	// subscription changes refresh the cached matches
+	assert.Equal(t, ps.PSubscribe("*/1", b), nil)
+	assert.Equal(t, ps.Publish("orders/1", 3).Delivered, 2)
+	ps.PUnsubscribe("orders/*", a)
+	assert.Equal(t, ps.Publish("orders/1", 4).Delivered, 1)
+	assert.Equal(t, (<-b).Pattern, "*/1")
+}
+
+func TestRouteCacheEviction(t *testing.T) {
+	ps := New(-1)
+	assert.Equal(t, ps.PSubscribe("*", make(chan Event)), nil)
+	for i := 0; i < routeCacheShards*routeCacheSize*2; i++ {
+		ps.Publish(fmt.Sprint(i), i)
+	}
+	n := 0
+	for i := range ps.routeCache.shards {
+		snap := ps.routeCache.shards[i].snapshot.Load()
+		assert.Equal(t, len(snap.entries) <= routeCacheSize, true)
+		n += len(snap.entries)
+	}
+	assert.Equal(t, n > routeCacheSize, true)
+
+	// the last published name is cached
+	ps.Publish("0", 0)
+	_, ok := ps.routeCache.shards[fnv32("0")%routeCacheShards].snapshot.Load().entries["0"]
+	assert.Equal(t, ok, true)
+
+	// a subscription change drops the cached matches on the next publish
+	assert.Equal(t, ps.PSubscribe("1", make(chan Event)), nil)
+	ps.Publish("0", 0)
+	assert.Equal(t, len(ps.routeCache.shards[fnv32("0")%routeCacheShards].snapshot.Load().entries), 1)
+}
+
+func BenchmarkPublishPatternsCached(b *testing.B) {
+	ps := New(-1)
+	c := make(chan Event)
+	for i := 0; i < 1000; i++ {
+		ps.PSubscribe(fmt.Sprintf("service%d.*.event", i), c)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			ps.Publish("service42.node.event", nil)
+		}
+	})
+}
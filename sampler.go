@@ -0,0 +1,139 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Sampler decides which published messages are traced.
+type Sampler interface {
+	Sample(name string) bool
+}
+
+// SamplerFunc is an adapter to use an ordinary function as a Sampler.
+type SamplerFunc func(name string) bool
+
+// Sample calls fn(name).
+func (fn SamplerFunc) Sample(name string) bool {
+	return fn(name)
+}
+
+// WithSampler traces the published messages sampled by s. All messages are traced
+// without a Sampler.
+func WithSampler(s Sampler) Option {
+	return func(p *Pubsub) {
+		p.sampler = s
+	}
+}
+
+// sampled returns whether the message published to name is traced.
+func (p *Pubsub) sampled(name string) bool {
+	return p.sampler == nil || p.sampler.Sample(name)
+}
+
+// PSubscribeSampled subscribes channel c to pattern like PSubscribe, but only
+// delivers a fraction rate, from 0 to 1, of the matching messages to c, e.g. 0.01
+// for a debug tap receiving every hundredth message. The other messages are
+// skipped, they aren't reported as dropped. PSubscribeSampled of c to pattern
+// again replaces the rate.
+func (p *Pubsub) PSubscribeSampled(pattern string, c chan Event, rate float64) error {
+	pattern = p.normalize(pattern)
+	if c == nil {
+		return nil
+	}
+	if err := p.validatePattern(pattern); err != nil {
+		return err
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	sample := newSamplerRate(rate)
+	_, err := p.addWith(byPattern, pattern, c, func(s *subscriber) { s.sample = &sample })
+	return err
+}
+
+// TopicSampler samples a fraction of the messages of the topics matching a
+// pattern, e.g. 1% of clicks/* and all of payments/*. The first matching pattern
+// wins, other topics are sampled at the default rate. Sampling is deterministic,
+// a rate of 0.25 samples every fourth message of the pattern.
+type TopicSampler struct {
+	matcher Matcher
+	rate    samplerRate
+
+	mu    sync.RWMutex
+	rules []*samplerRule
+}
+
+type samplerRule struct {
+	pattern string
+	rate    samplerRate
+}
+
+type samplerRate struct {
+	rate  float64
+	count *atomic.Uint64
+}
+
+// NewTopicSampler returns a TopicSampler matching patterns with m, or GlobMatcher
+// if m is nil, which samples topics without a rate at defaultRate.
+func NewTopicSampler(m Matcher, defaultRate float64) *TopicSampler {
+	if m == nil {
+		m = GlobMatcher{}
+	}
+	return &TopicSampler{
+		matcher: m,
+		rate:    newSamplerRate(defaultRate),
+	}
+}
+
+func newSamplerRate(rate float64) samplerRate {
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	return samplerRate{rate: rate, count: new(atomic.Uint64)}
+}
+
+// SetRate samples the topics matching pattern at rate, from 0 to 1. It replaces the
+// rate of pattern if it's already set.
+func (s *TopicSampler) SetRate(pattern string, rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rule := range s.rules {
+		if rule.pattern == pattern {
+			rule.rate = newSamplerRate(rate)
+			return
+		}
+	}
+	s.rules = append(s.rules, &samplerRule{pattern: pattern, rate: newSamplerRate(rate)})
+}
+
+// Sample returns whether to trace the next message of name.
+func (s *TopicSampler) Sample(name string) bool {
+	s.mu.RLock()
+	rate := s.rate
+	for _, rule := range s.rules {
+		if s.matcher.Match(rule.pattern, name) {
+			rate = rule.rate
+			break
+		}
+	}
+	s.mu.RUnlock()
+	return rate.sample()
+}
+
+// sample returns true when the n-th message crosses an integer multiple of 1/rate.
+func (r samplerRate) sample() bool {
+	switch r.rate {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	n := r.count.Add(1)
+	return uint64(float64(n)*r.rate) > uint64(float64(n-1)*r.rate)
+}
@@ -0,0 +1,57 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestTopicSampler(t *testing.T) {
+	s := NewTopicSampler(nil, 0)
+	s.SetRate("clicks/*", 0.25)
+	s.SetRate("payments/*", 1)
+
+	count := func(name string, n int) int {
+		sampled := 0
+		for i := 0; i < n; i++ {
+			if s.Sample(name) {
+				sampled++
+			}
+		}
+		return sampled
+	}
+	assert.Equal(t, count("clicks/home", 100), 25)
+	assert.Equal(t, count("payments/1", 100), 100)
+	assert.Equal(t, count("orders/1", 100), 0)
+
+	s.SetRate("clicks/*", 0.01)
+	assert.Equal(t, count("clicks/home", 1000), 10)
+}
+
+func TestSampled(t *testing.T) {
+	assert.Equal(t, New(-1).sampled("a"), true)
+	ps := New(-1, WithSampler(SamplerFunc(func(name string) bool { return name == "a" })))
+	assert.Equal(t, ps.sampled("a"), true)
+	assert.Equal(t, ps.sampled("b"), false)
+}
+
+func TestPSubscribeSampled(t *testing.T) {
+	ps := New(-1)
+	tap := make(chan Event, 100)
+	all := make(chan Event, 100)
+	ps.PSubscribeSampled("clicks/*", tap, 0.1)
+	ps.PSubscribe("clicks/*", all)
+
+	delivered := 0
+	for i := 0; i < 50; i++ {
+		delivered += ps.Publish("clicks/home", i).Delivered
+	}
+	assert.Equal(t, delivered, 55)
+	assert.Equal(t, len(tap), 5)
+	assert.Equal(t, len(all), 50)
+	assert.Equal(t, (<-tap).Message, 9)
+
+	ps.PSubscribeSampled("clicks/*", tap, 0)
+	ps.Publish("clicks/home", 50)
+	assert.Equal(t, len(tap), 4)
+}
@@ -0,0 +1,175 @@
+package pubsub
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Scheduled is a message scheduled by PublishAfter, PublishAt or PublishEvery.
+type Scheduled struct {
+	s       *scheduler
+	name    string
+	message interface{}
+	at      time.Time
+	every   time.Duration // 0 unless periodic
+	index   int           // index in the heap, -1 if not scheduled
+}
+
+// Cancel cancels the publication of the message. It returns false if the message
+// was already published, or the publication cancelled.
+func (t *Scheduled) Cancel() bool {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&t.s.queue, t.index)
+	return true
+}
+
+// PublishAfter publishes message to name after d.
+func (p *Pubsub) PublishAfter(name string, message interface{}, d time.Duration) *Scheduled {
+	return p.schedule(name, message, p.clock.Now().Add(d), 0)
+}
+
+// PublishAt publishes message to name at t, or right away if t is past.
+func (p *Pubsub) PublishAt(name string, message interface{}, t time.Time) *Scheduled {
+	return p.schedule(name, message, t, 0)
+}
+
+// PublishEvery publishes message to name every d until cancelled, skipping the
+// ticks missed when publishing is late. It panics if d <= 0.
+func (p *Pubsub) PublishEvery(name string, message interface{}, d time.Duration) *Scheduled {
+	if d <= 0 {
+		panic("pubsub: non-positive interval for PublishEvery")
+	}
+	return p.schedule(name, message, p.clock.Now().Add(d), d)
+}
+
+// schedule adds a message to the scheduler, started on first use and stopped by
+// Close. Messages scheduled after Close are never published.
+func (p *Pubsub) schedule(name string, message interface{}, at time.Time, every time.Duration) *Scheduled {
+	s := &p.scheduler
+	s.once.Do(func() {
+		s.wake = make(chan struct{}, 1)
+		go p.runScheduler()
+	})
+	t := &Scheduled{s: s, name: name, message: message, at: at, every: every, index: -1}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-p.done:
+		return t
+	default:
+	}
+	heap.Push(&s.queue, t)
+	if t.index == 0 {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return t
+}
+
+// scheduler publishes the scheduled messages from a single goroutine, waiting for
+// the earliest one with a single timer.
+type scheduler struct {
+	once  sync.Once
+	mu    sync.Mutex
+	queue scheduleQueue
+	wake  chan struct{}
+}
+
+func (p *Pubsub) runScheduler() {
+	s := &p.scheduler
+	var (
+		timer Timer // created for the first message
+		due   []*Scheduled
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		if timer != nil {
+			timer.Stop()
+		}
+		s.mu.Lock()
+		var fire <-chan time.Time
+		if len(s.queue) > 0 {
+			d := s.queue[0].at.Sub(p.clock.Now())
+			if timer == nil {
+				timer = p.clock.NewTimer(d)
+			} else {
+				timer.Reset(d)
+			}
+			fire = timer.C()
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.wake:
+			continue
+		case <-p.done:
+			s.mu.Lock()
+			for _, t := range s.queue {
+				t.index = -1
+			}
+			s.queue = nil
+			s.mu.Unlock()
+			return
+		case <-fire:
+		}
+
+		now := p.clock.Now()
+		due = due[:0]
+		s.mu.Lock()
+		for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+			t := s.queue[0]
+			due = append(due, t)
+			if t.every > 0 {
+				for !t.at.After(now) {
+					t.at = t.at.Add(t.every)
+				}
+				heap.Fix(&s.queue, 0)
+			} else {
+				heap.Pop(&s.queue)
+			}
+		}
+		s.mu.Unlock()
+		for _, t := range due {
+			p.Publish(t.name, t.message)
+		}
+	}
+}
+
+// scheduleQueue is a heap of the scheduled messages by time.
+type scheduleQueue []*Scheduled
+
+func (q scheduleQueue) Len() int           { return len(q) }
+func (q scheduleQueue) Less(i, j int) bool { return q[i].at.Before(q[j].at) }
+
+func (q scheduleQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *scheduleQueue) Push(x interface{}) {
+	t := x.(*Scheduled)
+	t.index = len(*q)
+	*q = append(*q, t)
+}
+
+func (q *scheduleQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*q = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishAfter(t *testing.T) {
+	ps := New(-1)
+	defer ps.Close()
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+
+	start := time.Now()
+	ps.PublishAfter("a", 2, 40*time.Millisecond)
+	ps.PublishAt("a", 1, start.Add(20*time.Millisecond))
+	cancelled := ps.PublishAfter("a", 3, 30*time.Millisecond)
+	assert.Equal(t, cancelled.Cancel(), true)
+	assert.Equal(t, cancelled.Cancel(), false)
+
+	events := receiveN(t, c, 2)
+	assert.Equal(t, events[0].Message, 1)
+	assert.Equal(t, events[1].Message, 2)
+	assert.Equal(t, time.Since(start) >= 40*time.Millisecond, true)
+
+	// published already
+	s := ps.PublishAt("a", 4, start)
+	receiveN(t, c, 1)
+	assert.Equal(t, s.Cancel(), false)
+}
+
+func TestPublishEvery(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 10)
+	ps.Subscribe("tick", c)
+
+	s := ps.PublishEvery("tick", "tick", 10*time.Millisecond)
+	receiveN(t, c, 3)
+	assert.Equal(t, s.Cancel(), true)
+
+	// Close discards the scheduled messages
+	ps.PublishAfter("tick", "late", 10*time.Millisecond)
+	ps.Close()
+	time.Sleep(30 * time.Millisecond)
+	for len(c) > 0 {
+		assert.Equal(t, (<-c).Message, "tick")
+	}
+	assert.Equal(t, ps.PublishAfter("tick", "closed", 0).Cancel(), false)
+}
@@ -0,0 +1,138 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Error of a message which hasn't the type registered for its topic.
+var ErrWrongType = errors.New("message has the wrong type")
+
+// SchemaError is the error of a message rejected by the schema of its topic, in the
+// PublishResult or returned by PublishCtx.
+type SchemaError struct {
+	Name    string
+	Pattern string // the pattern the schema is registered for
+	Err     error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("pubsub: message of %q rejected by the schema of %q: %v", e.Name, e.Pattern, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// RegisterTopicType rejects the messages of the topics matching pattern, matched by
+// the Matcher of the Pubsub, which don't have the type of prototype, or don't
+// implement the interface prototype points to, e.g. (*fmt.Stringer)(nil). Encoded
+// messages, e.g. received by a bridge, are accepted if they decode into the type
+// with Into. The first matching pattern wins, registering a pattern again replaces
+// its schema.
+func (p *Pubsub) RegisterTopicType(pattern string, prototype interface{}) {
+	t := reflect.TypeOf(prototype)
+	if t == nil {
+		panic("pubsub: nil prototype for RegisterTopicType")
+	}
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Interface {
+		t = t.Elem()
+	}
+	p.RegisterTopicValidator(pattern, func(event Event) error {
+		return checkType(event, t)
+	})
+}
+
+// RegisterTopicValidator rejects the messages of the topics matching pattern for
+// which fn returns an error, like RegisterTopicType.
+func (p *Pubsub) RegisterTopicValidator(pattern string, fn func(event Event) error) {
+	p.schemas.mu.Lock()
+	defer p.schemas.mu.Unlock()
+
+	var rules []schemaRule
+	if old := p.schemas.rules.Load(); old != nil {
+		rules = append(rules, *old...)
+	}
+	replaced := false
+	for i := range rules {
+		if rules[i].pattern == pattern {
+			rules[i].validate = fn
+			replaced = true
+		}
+	}
+	if !replaced {
+		rules = append(rules, schemaRule{pattern, fn})
+	}
+	p.schemas.rules.Store(&rules)
+	clearMap(&p.schemas.cache)
+}
+
+type schemaRule struct {
+	pattern  string
+	validate func(event Event) error
+}
+
+type schemas struct {
+	mu    sync.Mutex                   // serializes the registrations
+	rules atomic.Pointer[[]schemaRule] // copy-on-write snapshot
+	cache sync.Map                     // name -> schemaMatch
+}
+
+// schemaMatch is the rule of a name, nil if none, matched among rules.
+type schemaMatch struct {
+	rules *[]schemaRule
+	rule  *schemaRule
+}
+
+// validate returns the *SchemaError of event, or nil if the schema of its topic
+// accepts it. It doesn't lock, so it doesn't serialize Publish.
+func (p *Pubsub) validate(event Event) error {
+	rules := p.schemas.rules.Load()
+	if rules == nil {
+		return nil
+	}
+	var rule *schemaRule
+	if v, ok := p.schemas.cache.Load(event.Name); ok && v.(schemaMatch).rules == rules {
+		rule = v.(schemaMatch).rule
+	} else {
+		// matched among the snapshot, so a registration in between isn't missed
+		for i := range *rules {
+			if p.matcher.Match((*rules)[i].pattern, event.Name) {
+				rule = &(*rules)[i]
+				break
+			}
+		}
+		p.schemas.cache.Store(event.Name, schemaMatch{rules, rule})
+	}
+
+	if rule == nil {
+		return nil
+	}
+	if err := rule.validate(event); err != nil {
+		return &SchemaError{Name: event.Name, Pattern: rule.pattern, Err: err}
+	}
+	return nil
+}
+
+func checkType(event Event, t reflect.Type) error {
+	if event.Message != nil {
+		mt := reflect.TypeOf(event.Message)
+		if mt == t || (t.Kind() == reflect.Interface && mt.Implements(t)) {
+			return nil
+		}
+	}
+	switch event.Message.(type) {
+	case []byte, string, json.RawMessage:
+		if t.Kind() != reflect.Interface {
+			if err := event.Into(reflect.New(t).Interface()); err != nil {
+				return fmt.Errorf("%w: %T doesn't decode into %v: %v", ErrWrongType, event.Message, t, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: got %T, want %v", ErrWrongType, event.Message, t)
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+type order struct {
+	ID int `json:"id"`
+}
+
+type stringer int
+
+func (stringer) String() string { return "" }
+
+func TestRegisterTopicType(t *testing.T) {
+	ps := New(-1)
+	ps.RegisterTopicType("orders/*", order{})
+	ps.RegisterTopicType("names/*", (*fmt.Stringer)(nil))
+	c := make(chan Event, 10)
+	ps.PSubscribe("*/*", c)
+
+	assert.Equal(t, ps.Publish("orders/new", order{1}), PublishResult{Delivered: 1})
+	result := ps.Publish("orders/new", &order{1})
+	assert.Equal(t, result.Delivered, 0)
+	var schemaErr *SchemaError
+	assert.Equal(t, errors.As(result.Err, &schemaErr), true)
+	assert.Equal(t, schemaErr.Name, "orders/new")
+	assert.Equal(t, schemaErr.Pattern, "orders/*")
+	assert.Equal(t, errors.Is(result.Err, ErrWrongType), true)
+	assert.Equal(t, result.Err.Error(), `pubsub: message of "orders/new" rejected by the schema of "orders/*": message has the wrong type: got *pubsub.order, want pubsub.order`)
+
+	// encoded messages which decode into the type
+	assert.Equal(t, ps.Publish("orders/new", []byte(`{"id":2}`)).Err, nil)
+	assert.Equal(t, errors.Is(ps.Publish("orders/new", []byte(`[1]`)).Err, ErrWrongType), true)
+
+	assert.Equal(t, ps.Publish("names/a", stringer(1)).Err, nil)
+	assert.Equal(t, errors.Is(ps.Publish("names/a", 1).Err, ErrWrongType), true)
+	assert.Equal(t, ps.Publish("other/a", 1).Err, nil)
+
+	assert.Equal(t, errors.Is(ps.PublishCtx(context.Background(), "orders/new", 1), ErrWrongType), true)
+	assert.Equal(t, errors.Is(ps.PublishRetain("orders/new", 1).Err, ErrWrongType), true)
+	_, ok := ps.Retained("orders/new")
+	assert.Equal(t, ok, false)
+	assert.Equal(t, len(c), 4)
+
+	// registering a pattern again replaces its schema
+	ps.RegisterTopicType("orders/*", 0)
+	assert.Equal(t, ps.Publish("orders/new", 1).Err, nil)
+}
+
+func TestRegisterTopicValidator(t *testing.T) {
+	ps := New(-1)
+	errEmpty := errors.New("empty")
+	ps.RegisterTopicValidator("*", func(event Event) error {
+		if event.Message == "" {
+			return errEmpty
+		}
+		return nil
+	})
+	result := ps.PublishMulti(map[string]interface{}{"a": "", "b": "x", "c": ""})
+	assert.Equal(t, errors.Is(result.Err, errEmpty), true)
+	assert.Equal(t, ps.Publish("a", "x").Err, nil)
+}
+
+func TestRegisterTopicTypeWhilePublishing(t *testing.T) {
+	ps := New(-1)
+	// a name cached before a registration is matched again
+	assert.Equal(t, ps.Publish("orders/new", "x").Err, nil)
+	ps.RegisterTopicType("orders/*", order{})
+	assert.Equal(t, errors.Is(ps.Publish("orders/new", "x").Err, ErrWrongType), true)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ps.Publish(fmt.Sprint("names/", i%10), i)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		ps.RegisterTopicValidator(fmt.Sprint("names/", i), func(Event) error { return nil })
+	}
+	<-done
+	assert.Equal(t, errors.Is(ps.Publish("orders/new", "x").Err, ErrWrongType), true)
+}
@@ -0,0 +1,89 @@
+package pubsub
+
+import "sync"
+
+// droppedEvent is a drop of a message reported once its topic is released.
+type droppedEvent struct {
+	c       chan Event
+	event   Event
+	pattern string
+}
+
+// WithSequence stamps the messages of each topic with a sequence number, in Seq,
+// starting at 1. The messages of a topic are sent one at a time, in the order of
+// their numbers, so a subscriber receives them in order and can tell the messages
+// it missed, e.g. dropped, from a gap between the numbers. Only a message of
+// PublishCtx waiting for a full channel can be overtaken by the next ones. The
+// drops are reported once the message is sent, so the drop handlers can publish
+// to the topic.
+func WithSequence() Option {
+	return func(p *Pubsub) {
+		p.sequences = &sequences{}
+	}
+}
+
+type sequences struct {
+	topics sync.Map // name -> *topicSequence
+}
+
+type topicSequence struct {
+	mu   sync.Mutex // held while a message is sent
+	last uint64
+
+	dmu   sync.Mutex
+	held  bool
+	drops []droppedEvent
+}
+
+func noop() {}
+
+// sequence stamps event with the next number of its topic, and returns the function
+// to call once event is sent without blocking, which lets the next message of the
+// topic be sent and reports the drops deferred meanwhile.
+func (p *Pubsub) sequence(event *Event) func() {
+	if p.sequences == nil {
+		return noop
+	}
+	v, ok := p.sequences.topics.Load(event.Name)
+	if !ok {
+		v, _ = p.sequences.topics.LoadOrStore(event.Name, &topicSequence{})
+	}
+	seq := v.(*topicSequence)
+	seq.mu.Lock()
+	seq.last++
+	event.Seq = seq.last
+	seq.dmu.Lock()
+	seq.held = true
+	seq.dmu.Unlock()
+	return func() {
+		seq.dmu.Lock()
+		seq.held = false
+		drops := seq.drops
+		seq.drops = nil
+		seq.dmu.Unlock()
+		seq.mu.Unlock()
+		for _, d := range drops {
+			p.reportDrop(d.c, d.event, d.pattern)
+		}
+	}
+}
+
+// deferDrop defers the report of a drop of event while its topic is held, and
+// returns whether it did.
+func (p *Pubsub) deferDrop(c chan Event, event Event, pattern string) bool {
+	if p.sequences == nil {
+		return false
+	}
+	v, ok := p.sequences.topics.Load(event.Name)
+	if !ok {
+		return false
+	}
+	seq := v.(*topicSequence)
+	seq.dmu.Lock()
+	defer seq.dmu.Unlock()
+	if !seq.held {
+		return false
+	}
+	seq.drops = append(seq.drops, droppedEvent{c, event, pattern})
+	return true
+}
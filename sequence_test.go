@@ -0,0 +1,88 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSequence(t *testing.T) {
+	ps := New(-1, WithSequence())
+	c := make(chan Event, 1000)
+	ps.PSubscribe("*", c)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ps.Publish("a", j)
+				ps.Publish("b", j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := map[string]uint64{}
+	for len(c) > 0 {
+		e := <-c
+		assert.Equal(t, e.Seq, last[e.Name]+1)
+		last[e.Name] = e.Seq
+	}
+	assert.Equal(t, last, map[string]uint64{"a": 400, "b": 400})
+
+	// a dropped message leaves a gap
+	small := make(chan Event, 1)
+	ps.Subscribe("c", small)
+	ps.Publish("c", 1)
+	ps.Publish("c", 2)
+	<-small
+	ps.Publish("c", 3)
+	assert.Equal(t, (<-small).Seq, uint64(3))
+}
+
+func TestSequenceDropHandlerPublishes(t *testing.T) {
+	var ps *Pubsub
+	ps = New(-1, WithSequence(), OnDrop(func(d DroppedMessage) {
+		if d.Message == 1 {
+			ps.Publish(d.Name, 2)
+		}
+	}))
+	c := make(chan Event)
+	ps.Subscribe("a", c)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ps.Publish("a", 1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("deadlock")
+	}
+	assert.Equal(t, ps.Stats().Dropped, uint64(2))
+}
+
+func TestSequencePublishCtxBlocked(t *testing.T) {
+	ps := New(-1, WithSequence())
+	full := make(chan Event)
+	ps.Subscribe("a", full)
+	c := make(chan Event, 1)
+	ps.Subscribe("a", c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() { errs <- ps.PublishCtx(ctx, "a", 1) }()
+	assert.Equal(t, (<-c).Seq, uint64(1))
+
+	// the blocked PublishCtx doesn't stall the next messages
+	ps.Publish("a", 2)
+	assert.Equal(t, (<-c).Seq, uint64(2))
+	cancel()
+	assert.Equal(t, <-errs != nil, true)
+}
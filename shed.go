@@ -0,0 +1,155 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+// ShedTopic is the topic which the Pubsub publishes its ShedReport to when created
+// with WithLoadShedding.
+const ShedTopic = "$SYS/shed"
+
+// Priority is the priority of a topic when shedding load, or of a message published
+// with PublishPriority.
+type Priority int
+
+const (
+	// PriorityLow messages are shed when the channel is half full.
+	PriorityLow Priority = iota
+	// PriorityNormal messages are shed when the channel is three quarters full.
+	PriorityNormal
+	// PriorityHigh messages are only dropped when the channel is full.
+	PriorityHigh
+	// PriorityCritical messages are never shed, they are only dropped when the
+	// channel is full.
+	PriorityCritical
+)
+
+func (pr Priority) String() string {
+	switch pr {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// ShedReport is the number of messages shed per topic since the previous report.
+type ShedReport struct {
+	Time time.Time
+	Shed map[string]uint64
+}
+
+// WithPriority declares the priority of the topics matching pattern, matched by the
+// Matcher of the Pubsub. The first matching declaration wins, topics without one
+// are PriorityNormal. Priorities are only used WithLoadShedding.
+func WithPriority(pattern string, priority Priority) Option {
+	return func(p *Pubsub) {
+		if p.shed == nil {
+			p.shed = &shedder{}
+		}
+		p.shed.rules = append(p.shed.rules, priorityRule{pattern, priority})
+	}
+}
+
+// WithLoadShedding drops messages to channels filling up according to the priority
+// of the topic, keeping room for the messages of higher priority. Shed messages
+// are reported as dropped, and a ShedReport is published to ShedTopic every
+// interval if any message was shed, until the Pubsub is closed. Unbuffered
+// channels and the $SYS/ topics are never shed.
+func WithLoadShedding(interval time.Duration) Option {
+	return func(p *Pubsub) {
+		if p.shed == nil {
+			p.shed = &shedder{}
+		}
+		p.shed.enabled = true
+		p.shed.interval = interval
+	}
+}
+
+type priorityRule struct {
+	pattern  string
+	priority Priority
+}
+
+type shedder struct {
+	enabled  bool
+	interval time.Duration
+	rules    []priorityRule
+	cache    sync.Map // name -> Priority
+
+	mu   sync.Mutex
+	shed map[string]uint64
+}
+
+// priority returns the declared priority of the topic name.
+func (p *Pubsub) priority(name string) Priority {
+	if p.shed == nil {
+		return PriorityNormal
+	}
+	if v, ok := p.shed.cache.Load(name); ok {
+		return v.(Priority)
+	}
+	priority := PriorityNormal
+	for _, rule := range p.shed.rules {
+		if p.matcher.Match(rule.pattern, name) {
+			priority = rule.priority
+			break
+		}
+	}
+	p.shed.cache.Store(name, priority)
+	return priority
+}
+
+// shedding returns whether to shed event instead of sending it to c.
+func (p *Pubsub) shedding(c chan Event, event Event) bool {
+	// never shed the reports, or they would report themselves
+	if p.shed == nil || !p.shed.enabled || cap(c) == 0 || p.system(event.Name) {
+		return false
+	}
+	fill := len(c) * 4
+	switch p.priorityOf(event) {
+	case PriorityLow:
+		if fill < cap(c)*2 {
+			return false
+		}
+	case PriorityNormal:
+		if fill < cap(c)*3 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	p.shed.mu.Lock()
+	if p.shed.shed == nil {
+		p.shed.shed = make(map[string]uint64)
+	}
+	p.shed.shed[event.Name]++
+	p.shed.mu.Unlock()
+	return true
+}
+
+func (p *Pubsub) reportShed(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.shed.mu.Lock()
+			shed := p.shed.shed
+			p.shed.shed = nil
+			p.shed.mu.Unlock()
+			if len(shed) > 0 {
+				p.Publish(ShedTopic, ShedReport{Time: now, Shed: shed})
+			}
+		}
+	}
+}
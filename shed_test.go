@@ -0,0 +1,48 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestLoadShedding(t *testing.T) {
+	ps := New(-1,
+		WithPriority("clicks/*", PriorityLow),
+		WithPriority("payments/*", PriorityCritical),
+		WithLoadShedding(time.Millisecond),
+	)
+	defer ps.Close()
+	assert.Equal(t, ps.priority("clicks/home"), PriorityLow)
+	assert.Equal(t, ps.priority("orders/1"), PriorityNormal)
+
+	reports := make(chan Event, 1)
+	ps.Subscribe(ShedTopic, reports)
+	c := make(chan Event, 4)
+	ps.PSubscribe("*/*", c)
+	assert.Equal(t, ps.Publish("clicks/home", 1), PublishResult{Delivered: 1})
+	assert.Equal(t, ps.Publish("clicks/home", 2), PublishResult{Delivered: 1})
+	// half full, low priority is shed
+	assert.Equal(t, ps.Publish("clicks/home", 3), PublishResult{Dropped: 1})
+	assert.Equal(t, ps.Publish("orders/1", 4), PublishResult{Delivered: 1})
+	// three quarters full, normal priority is shed
+	assert.Equal(t, ps.Publish("orders/1", 5), PublishResult{Dropped: 1})
+	assert.Equal(t, ps.Publish("payments/1", 6), PublishResult{Delivered: 1})
+	assert.Equal(t, ps.Publish("payments/1", 7), PublishResult{Dropped: 1})
+
+	select {
+	case e := <-reports:
+		assert.Equal(t, e.Message.(ShedReport).Shed, map[string]uint64{"clicks/home": 1, "orders/1": 1})
+	case <-time.After(time.Second):
+		t.Fatal("no shed report")
+	}
+}
+
+func TestWithoutLoadShedding(t *testing.T) {
+	ps := New(-1, WithPriority("*", PriorityLow))
+	c := make(chan Event, 2)
+	ps.Subscribe("a", c)
+	assert.Equal(t, ps.Publish("a", 1), PublishResult{Delivered: 1})
+	assert.Equal(t, ps.Publish("a", 2), PublishResult{Delivered: 1})
+}
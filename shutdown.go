@@ -0,0 +1,96 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ShutdownPhase orders the shutdown hooks. Shutdown runs the phases in order, after
+// the subscriptions are drained.
+type ShutdownPhase int
+
+const (
+	// ShutdownComponents is the phase of the components consuming messages, the
+	// phase of OnShutdown.
+	ShutdownComponents ShutdownPhase = iota
+	// ShutdownBridges is the phase of the bridges flushing the messages they
+	// forward.
+	ShutdownBridges
+	// ShutdownStores is the phase of the stores syncing what they keep.
+	ShutdownStores
+	shutdownPhases
+)
+
+type shutdownHooks struct {
+	mu     sync.Mutex
+	phases [shutdownPhases][]func(ctx context.Context) error
+	once   sync.Once
+	err    error
+}
+
+// OnShutdown calls fn when the Pubsub is shut down, in the ShutdownComponents phase.
+func (p *Pubsub) OnShutdown(fn func(ctx context.Context) error) {
+	p.OnShutdownPhase(ShutdownComponents, fn)
+}
+
+// OnShutdownPhase calls fn when the Pubsub is shut down, in phase. The hooks of a
+// phase are called in the order they were registered.
+func (p *Pubsub) OnShutdownPhase(phase ShutdownPhase, fn func(ctx context.Context) error) {
+	if phase < 0 || phase >= shutdownPhases {
+		phase = ShutdownComponents
+	}
+	p.shutdown.mu.Lock()
+	defer p.shutdown.mu.Unlock()
+	p.shutdown.phases[phase] = append(p.shutdown.phases[phase], fn)
+}
+
+// Shutdown waits for the dispatched messages like Flush, drains all subscriptions
+// like Drain, calls the shutdown hooks phase by
+// phase, and closes the Pubsub. It returns ctx.Err() if ctx is done before the
+// subscriptions are drained, joined with the errors of the hooks, which are called
+// anyway. Later calls return the result of the first one.
+func (p *Pubsub) Shutdown(ctx context.Context) error {
+	p.shutdown.once.Do(func() {
+		defer p.Close()
+		errs := []error{p.Flush(ctx), p.drainAll(ctx)}
+
+		p.shutdown.mu.Lock()
+		phases := p.shutdown.phases
+		p.shutdown.mu.Unlock()
+		for _, hooks := range phases {
+			for _, fn := range hooks {
+				errs = append(errs, fn(ctx))
+			}
+		}
+		p.shutdown.err = errors.Join(errs...)
+	})
+	return p.shutdown.err
+}
+
+// drainAll drains all subscriptions concurrently.
+func (p *Pubsub) drainAll(ctx context.Context) error {
+	p.registry.Lock()
+	subs := make([]*Subscription, 0, len(p.subs))
+	for _, sub := range p.subs {
+		subs = append(subs, sub)
+	}
+	p.registry.Unlock()
+
+	var (
+		wg  sync.WaitGroup
+		err = make([]error, len(subs))
+	)
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub *Subscription) {
+			defer wg.Done()
+			err[i] = sub.Drain(ctx)
+		}(i, sub)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Join(err...)
+}
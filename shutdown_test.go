@@ -0,0 +1,65 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestShutdown(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.Publish("a", 1)
+
+	var order []string
+	hook := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	errStore := errors.New("store")
+	ps.OnShutdownPhase(ShutdownStores, hook("store", errStore))
+	ps.OnShutdownPhase(ShutdownBridges, hook("bridge", nil))
+	ps.OnShutdown(func(ctx context.Context) error {
+		// subscribers are drained before the hooks
+		order = append(order, "component")
+		assert.Equal(t, ps.NumSubscribers("a"), 0)
+		return nil
+	})
+	ps.OnShutdown(hook("component 2", nil))
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		<-c
+	}()
+	err := ps.Shutdown(context.Background())
+	assert.Equal(t, errors.Is(err, errStore), true)
+	assert.Equal(t, order, []string{"component", "component 2", "bridge", "store"})
+
+	// only once
+	assert.Equal(t, ps.Shutdown(context.Background()), err)
+	assert.Equal(t, len(order), 4)
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+	ps.Publish("a", 1)
+	called := false
+	ps.OnShutdown(func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, errors.Is(ps.Shutdown(ctx), context.DeadlineExceeded), true)
+	assert.Equal(t, called, true)
+	assert.Equal(t, ps.NumSubscribers("a"), 0)
+}
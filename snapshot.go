@@ -0,0 +1,215 @@
+package pubsub
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BrokerState is the state of a Pubsub taken by Snapshot, to be applied by Restore,
+// e.g. to restart a broker warm or to move it to another process. It doesn't keep
+// subscriptions, which are live channels. It can be encoded with encoding/json and
+// encoding/gob.
+type BrokerState struct {
+	Retained   []RetainedState
+	Offsets    []OffsetState
+	MaxFor     []MaxState     // set by SetMaxFor, in order
+	Broadcasts map[string]int // name -> size of the ring
+	Flags      map[string]bool
+}
+
+// RetainedState is a retained message, encoded with the Codec of the Pubsub unless
+// it's already encoded, and encrypted WithEncryption.
+type RetainedState struct {
+	ID          string
+	Name        string
+	Time        time.Time
+	Expires     time.Time
+	Headers     map[string]string
+	ContentType string
+	Data        []byte
+	Key         string // ID of the key encrypting Data, if any
+}
+
+// OffsetState is the offset acknowledged by a consumer of a durable topic.
+type OffsetState struct {
+	Name     string
+	Consumer string
+	Offset   uint64
+}
+
+// MaxState is the max subscriptions of the names matching a pattern.
+type MaxState struct {
+	Pattern string
+	Max     int
+}
+
+// Snapshot returns the state of the Pubsub: its retained messages, the offsets
+// acknowledged by the consumers of durable topics, whose Store is an OffsetLister,
+// and its topic configuration. It returns an error if a retained message can't be
+// encoded or the offsets can't be read.
+func (p *Pubsub) Snapshot() (BrokerState, error) {
+	state := BrokerState{
+		Broadcasts: map[string]int{},
+		Flags:      map[string]bool{},
+	}
+
+	p.locker.RLock()
+	retained := make([]Event, 0, len(p.retained))
+	for _, event := range p.retained {
+		if !p.expired(event) {
+			retained = append(retained, event)
+		}
+	}
+	for name, r := range p.rings {
+		state.Broadcasts[name] = len(r.slots)
+	}
+	p.locker.RUnlock()
+
+	sort.Slice(retained, func(i, j int) bool { return retained[i].Name < retained[j].Name })
+	for _, event := range retained {
+		contentType, data, err := encodeMessage(event, p.Codec())
+		if err != nil {
+			return BrokerState{}, fmt.Errorf("pubsub: snapshot retained %q: %w", event.Name, err)
+		}
+		var key string
+		if p.keys != nil {
+			if key, data, err = seal(p.keys, data, []byte(event.Name)); err != nil {
+				return BrokerState{}, fmt.Errorf("pubsub: snapshot retained %q: %w", event.Name, err)
+			}
+		}
+		headers := make(map[string]string, len(event.Headers))
+		for k, v := range event.Headers {
+			if k != HeaderContentType {
+				headers[k] = v
+			}
+		}
+		state.Retained = append(state.Retained, RetainedState{
+			ID:          event.ID,
+			Name:        event.Name,
+			Time:        event.Time,
+			Expires:     event.Expires,
+			Headers:     headers,
+			ContentType: contentType,
+			Data:        data,
+			Key:         key,
+		})
+	}
+
+	offsets, err := p.durableOffsets()
+	if err != nil {
+		return BrokerState{}, fmt.Errorf("pubsub: snapshot offsets: %w", err)
+	}
+	state.Offsets = offsets
+
+	p.maxes.mu.Lock()
+	for _, rule := range p.maxes.rules {
+		state.MaxFor = append(state.MaxFor, MaxState{rule.pattern, rule.max})
+	}
+	p.maxes.mu.Unlock()
+
+	for _, name := range Flags() {
+		state.Flags[name] = p.Flag(name)
+	}
+	return state, nil
+}
+
+// durableOffsets returns the offsets of the durable topics, sorted by name and
+// consumer.
+func (p *Pubsub) durableOffsets() ([]OffsetState, error) {
+	if p.durable == nil {
+		return nil, nil
+	}
+	var ret []OffsetState
+	seen := map[Store]bool{}
+	for _, rule := range p.durable.rules {
+		lister, ok := rule.store.(OffsetLister)
+		if !ok || seen[rule.store] {
+			continue
+		}
+		seen[rule.store] = true
+		offsets, err := lister.Offsets()
+		if err != nil {
+			return nil, err
+		}
+		for name, consumers := range offsets {
+			// the topics of the store, which other rules may share
+			if topic := p.durableTopic(name); topic == nil || topic.store != rule.store {
+				continue
+			}
+			for consumer, offset := range consumers {
+				ret = append(ret, OffsetState{name, consumer, offset})
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Name != ret[j].Name {
+			return ret[i].Name < ret[j].Name
+		}
+		return ret[i].Consumer < ret[j].Consumer
+	})
+	return ret, nil
+}
+
+// Restore applies state taken by Snapshot. The retained messages replace the
+// retained messages of their names, without being published, and are []byte with
+// the HeaderContentType header, to be decoded with Into. Encrypted ones are
+// decrypted WithEncryption. The offsets are committed to the Stores of their
+// durable topics, unless the Stores keep higher ones. It applies as much of state
+// as it can, and returns the errors of the rest, e.g. ErrNotDurable for the offsets
+// of topics which aren't durable.
+func (p *Pubsub) Restore(state BrokerState) error {
+	var errs []error
+
+	p.locker.Lock()
+	for _, rec := range state.Retained {
+		if rec.Key != "" {
+			data, err := unseal(p.keys, rec.Key, rec.Data, []byte(rec.Name))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pubsub: restore retained %q: %w", rec.Name, err))
+				continue
+			}
+			rec.Data = data
+		}
+		headers := make(map[string]string, len(rec.Headers)+1)
+		for k, v := range rec.Headers {
+			headers[k] = v
+		}
+		headers[HeaderContentType] = rec.ContentType
+		name := p.normalize(rec.Name)
+		p.retained[name] = Event{
+			ID:      rec.ID,
+			Name:    name,
+			Message: rec.Data,
+			Time:    rec.Time,
+			Headers: headers,
+			Expires: rec.Expires,
+		}
+		p.touch(name)
+	}
+	p.locker.Unlock()
+
+	for _, o := range state.Offsets {
+		topic := p.durableTopic(p.normalize(o.Name))
+		if topic == nil {
+			errs = append(errs, fmt.Errorf("pubsub: restore offsets of %q: %w", o.Name, ErrNotDurable))
+			continue
+		}
+		if err := topic.store.Commit(p.normalize(o.Name), o.Consumer, o.Offset); err != nil {
+			errs = append(errs, fmt.Errorf("pubsub: restore offsets of %q: %w", o.Name, err))
+		}
+	}
+	for _, m := range state.MaxFor {
+		p.SetMaxFor(m.Pattern, m.Max)
+	}
+	for name, size := range state.Broadcasts {
+		p.Broadcast(name, size)
+	}
+	for name, on := range state.Flags {
+		if err := p.SetFlag(name, on); err != nil {
+			errs = append(errs, fmt.Errorf("pubsub: restore flag %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	store := NewMemoryStore()
+	ps := New(-1, WithDurable("orders/*", store), WithIDGenerator(UUIDv7()))
+	ps.PublishRetain("config", map[string]int{"n": 1})
+	ps.PublishTTL("gone", 1, -1)
+	ps.PublishRetain("gone", 1)
+	ps.PublishRetain("gone", nil)
+	ps.Publish("orders/1", "a")
+	store.Commit("orders/1", "billing", 1)
+	ps.SetMaxFor("busy/*", 2)
+	ps.Broadcast("ticks", 8)
+	ps.SetFlag(FlagParallelFanout, true)
+
+	state, err := ps.Snapshot()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(state.Retained), 1)
+	assert.Equal(t, state.Retained[0].Name, "config")
+	assert.Equal(t, state.Retained[0].ContentType, JSON.ContentType())
+	assert.Equal(t, state.Offsets, []OffsetState{{"orders/1", "billing", 1}})
+	assert.Equal(t, state.MaxFor, []MaxState{{"busy/*", 2}})
+	assert.Equal(t, state.Broadcasts, map[string]int{"ticks": 8})
+
+	for _, codec := range []string{"json", "gob"} {
+		var decoded BrokerState
+		switch codec {
+		case "json":
+			data, err := json.Marshal(state)
+			assert.Equal(t, err, nil)
+			assert.Equal(t, json.Unmarshal(data, &decoded), nil)
+		case "gob":
+			var buf bytes.Buffer
+			assert.Equal(t, gob.NewEncoder(&buf).Encode(state), nil)
+			assert.Equal(t, gob.NewDecoder(&buf).Decode(&decoded), nil)
+		}
+
+		restoredStore := NewMemoryStore()
+		restored := New(-1, WithDurable("orders/*", restoredStore))
+		assert.Equal(t, restored.Restore(decoded), nil)
+
+		c := make(chan Event, 1)
+		restored.SubscribeRetained("config", c)
+		event := <-c
+		assert.Equal(t, event.ID, state.Retained[0].ID)
+		var config map[string]int
+		assert.Equal(t, event.Into(&config), nil)
+		assert.Equal(t, config, map[string]int{"n": 1})
+
+		offset, _ := restoredStore.Committed("orders/1", "billing")
+		assert.Equal(t, offset, uint64(1))
+		restored.Subscribe("busy/a", make(chan Event))
+		restored.Subscribe("busy/a", make(chan Event))
+		assert.Equal(t, restored.Subscribe("busy/a", make(chan Event)), ErrMaxSubscribe)
+		assert.Equal(t, restored.Flag(FlagParallelFanout), true)
+		assert.Equal(t, len(restored.Broadcast("ticks", 1).slots), 8)
+	}
+
+	err = New(-1).Restore(state)
+	assert.Equal(t, errors.Is(err, ErrNotDurable), true)
+}
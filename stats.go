@@ -0,0 +1,135 @@
+package pubsub
+
+import (
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a Pubsub since it was created.
+type Stats struct {
+	Published uint64
+	Delivered uint64
+	Dropped   uint64
+	Topics    map[string]TopicStats // the names which have subscribers or counters
+
+	// SystemDropped counts the Notices and keyspace notifications dropped because
+	// too many were waiting to be published.
+	SystemDropped uint64
+}
+
+// TopicStats are the counters of a topic. The message counters are only kept if
+// the Pubsub is created WithTopicStats.
+type TopicStats struct {
+	Published   uint64
+	Delivered   uint64
+	Dropped     uint64
+	Subscribers int // number of channels subscribed by name
+}
+
+// StatsCollector receives the Stats of a Pubsub, e.g. to export them to a metrics
+// system like Prometheus.
+type StatsCollector interface {
+	CollectStats(stats Stats)
+}
+
+// StatsCollectorFunc is an adapter to use an ordinary function as a StatsCollector.
+type StatsCollectorFunc func(stats Stats)
+
+// CollectStats calls fn(stats).
+func (fn StatsCollectorFunc) CollectStats(stats Stats) {
+	fn(stats)
+}
+
+// WithTopicStats keeps the message counters of every topic, which can be read with
+// Stats.
+func WithTopicStats() Option {
+	return func(p *Pubsub) {
+		p.stats = &topicStats{}
+	}
+}
+
+// WithStatsCollector sends the Stats of the Pubsub to c every interval, until the
+// Pubsub is closed.
+func WithStatsCollector(c StatsCollector, interval time.Duration) Option {
+	return func(p *Pubsub) {
+		p.collector = c
+		p.collectInterval = interval
+	}
+}
+
+// Stats returns a snapshot of the counters of the Pubsub.
+func (p *Pubsub) Stats() Stats {
+	stats := Stats{
+		Published: p.published.Load(),
+		Delivered: p.delivered.Load(),
+		Dropped:   p.dropped.Load(),
+		Topics:    make(map[string]TopicStats),
+
+		SystemDropped: p.notices.dropped.Load() + p.keyspace.dropped.Load(),
+	}
+	if p.stats != nil {
+		p.stats.topics.Range(func(k, v interface{}) bool {
+			c := v.(*topicCounters)
+			stats.Topics[k.(string)] = TopicStats{
+				Published: c.published.Load(),
+				Delivered: c.delivered.Load(),
+				Dropped:   c.dropped.Load(),
+			}
+			return true
+		})
+	}
+
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	for _, sh := range p.shards {
+		sh.mu.Lock()
+		for name, subs := range sh.channels {
+			t := stats.Topics[name]
+			t.Subscribers = len(subs)
+			stats.Topics[name] = t
+		}
+		sh.mu.Unlock()
+	}
+	return stats
+}
+
+// Expvar returns an expvar.Var of the Stats, which can be exported with
+// expvar.Publish.
+func (p *Pubsub) Expvar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		return p.Stats()
+	})
+}
+
+func (p *Pubsub) collectStats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.collector.CollectStats(p.Stats())
+		}
+	}
+}
+
+type topicStats struct {
+	topics sync.Map // name -> *topicCounters
+}
+
+type topicCounters struct {
+	published atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func (s *topicStats) topic(name string) *topicCounters {
+	if v, ok := s.topics.Load(name); ok {
+		return v.(*topicCounters)
+	}
+	v, _ := s.topics.LoadOrStore(name, &topicCounters{})
+	return v.(*topicCounters)
+}
@@ -0,0 +1,54 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestStats(t *testing.T) {
+	ps := New(-1, WithTopicStats())
+	ready := make(chan Event, 1)
+	full := make(chan Event)
+	ps.Subscribe("a", ready)
+	ps.Subscribe("a", full)
+	ps.Subscribe("idle", ready)
+	ps.Publish("a", 1)
+	ps.Publish("nobody", 2)
+
+	stats := ps.Stats()
+	assert.Equal(t, stats.Published, uint64(2))
+	assert.Equal(t, stats.Delivered, uint64(1))
+	assert.Equal(t, stats.Dropped, uint64(1))
+	assert.Equal(t, stats.Topics, map[string]TopicStats{
+		"a":      {Published: 1, Delivered: 1, Dropped: 1, Subscribers: 2},
+		"idle":   {Subscribers: 1},
+		"nobody": {Published: 1},
+	})
+
+	var decoded Stats
+	assert.Equal(t, json.Unmarshal([]byte(ps.Expvar().String()), &decoded), nil)
+	assert.Equal(t, decoded.Published, uint64(2))
+}
+
+func TestStatsCollector(t *testing.T) {
+	got := make(chan Stats, 1)
+	ps := New(-1, WithStatsCollector(StatsCollectorFunc(func(s Stats) {
+		select {
+		case got <- s:
+		default:
+		}
+	}), time.Millisecond))
+	defer ps.Close()
+	ps.Publish("a", 1)
+
+	select {
+	case s := <-got:
+		assert.Equal(t, s.Published, uint64(1))
+		assert.Equal(t, len(s.Topics), 0)
+	case <-time.After(time.Second):
+		t.Fatal("no stats collected")
+	}
+}
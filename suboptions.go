@@ -0,0 +1,194 @@
+package pubsub
+
+// Overflow is the behavior of a subscription when its channel isn't ready to
+// receive a message.
+type Overflow int
+
+const (
+	// OverflowDrop drops the new message, which is the default.
+	OverflowDrop Overflow = iota
+	// OverflowDropOldest drops the oldest message buffered in the channel to make
+	// room for the new one.
+	OverflowDropOldest
+	// OverflowLatest keeps the latest message of each topic like
+	// SubscribeConflated.
+	OverflowLatest
+)
+
+// SubscribeOption configures a subscription of SubscribeWith or PSubscribeWith.
+type SubscribeOption func(o *subscribeOptions)
+
+type subscribeOptions struct {
+	c         chan Event
+	buffer    int
+	overflow  *Overflow
+	pred      func(Event) bool
+	after     int
+	lanes     int
+	transform func(interface{}) interface{}
+	done      <-chan struct{}
+}
+
+// WithChannel subscribes c instead of a new channel.
+func WithChannel(c chan Event) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.c = c
+	}
+}
+
+// WithBuffer sets the buffer size of the new channel, 64 by default.
+func WithBuffer(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.buffer = n
+	}
+}
+
+// WithOverflow sets the behavior when the channel isn't ready to receive, the
+// Overflow of the Config of the Pubsub by default.
+func WithOverflow(overflow Overflow) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.overflow = &overflow
+	}
+}
+
+// WithFilter only sends the messages for which pred returns true, like
+// SubscribeFiltered.
+func WithFilter(pred func(Event) bool) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.pred = pred
+	}
+}
+
+// WithTransform sends the messages mapped by fn, e.g. a projection of large
+// messages, instead of the published messages. fn is called by Publish for every
+// message sent to the channel, after WithFilter, and must not modify the message,
+// which other subscribers receive.
+func WithTransform(fn func(message interface{}) interface{}) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.transform = fn
+	}
+}
+
+// WithAutoUnsubscribeAfter unsubscribes after n messages were sent to the channel,
+// like SubscribeOnce for n = 1. Dropped messages don't count.
+func WithAutoUnsubscribeAfter(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.after = n
+	}
+}
+
+// WithDone unsubscribes the channel when done is closed, e.g. the done channel of
+// the goroutine receiving from it, so an abandoned subscription doesn't stay
+// subscribed. The Subscription of the channel is evicted if it was its last
+// subscription, like SubscribeCtx.
+func WithDone(done <-chan struct{}) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.done = done
+	}
+}
+
+// SubscribeWith subscribes a channel to name configured by opts, a new channel
+// unless WithChannel, and returns its Subscription. Subscribing the same channel to
+// name again replaces its options.
+func (p *Pubsub) SubscribeWith(name string, opts ...SubscribeOption) (*Subscription, error) {
+	return p.subscribeWith(byName, p.normalize(name), opts)
+}
+
+// PSubscribeWith is like SubscribeWith for the topics matching pattern.
+func (p *Pubsub) PSubscribeWith(pattern string, opts ...SubscribeOption) (*Subscription, error) {
+	pattern = p.normalize(pattern)
+	if err := p.validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	return p.subscribeWith(byPattern, pattern, opts)
+}
+
+func (p *Pubsub) subscribeWith(k kind, name string, opts []SubscribeOption) (*Subscription, error) {
+	o := subscribeOptions{buffer: handlerBuffer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := o.c
+	if c == nil {
+		c = make(chan Event, o.buffer)
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	overflow := p.overflow
+	if o.overflow != nil {
+		overflow = *o.overflow
+	}
+	s, err := p.addWith(k, name, c, func(s *subscriber) {
+		s.pred = o.pred
+		s.transform = o.transform
+		s.overflow = overflow
+		s.conflate = nil
+		s.lanes = nil
+		if o.lanes > 0 {
+			s.lanes = &lanes{s: s, size: o.lanes, dropOldest: overflow == OverflowDropOldest}
+		} else if overflow == OverflowLatest {
+			s.conflate = &conflater{s: s}
+		}
+		s.limit = nil
+		if o.after > 0 {
+			s.limit = &subscriberLimit{n: int64(o.after), k: k, name: name}
+		}
+		// stop watching the done channel and the context of the replaced subscriber
+		if s.unwatch != nil {
+			close(s.unwatch)
+			s.unwatch = nil
+		}
+		s.unwatchCtx()
+		if o.done != nil {
+			s.unwatch = make(chan struct{})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if o.done != nil {
+		go p.removeWhenDone(k, name, c, o.done, s.unwatch)
+	}
+	return s.sub, nil
+}
+
+// removeWhenDone unsubscribes the subscriber of c to name watched by unwatch once
+// done is closed, unless the subscriber was removed or replaced before.
+func (p *Pubsub) removeWhenDone(k kind, name string, c chan Event, done <-chan struct{}, unwatch chan struct{}) {
+	select {
+	case <-done:
+	case <-unwatch:
+		return
+	}
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.evict(c, func() {
+		collection, unlock := p.collection(k, name)
+		defer unlock()
+		for i, s := range collection[name] {
+			if s.unwatch == unwatch {
+				p.removeAt(k, collection, name, i)
+				p.changed(k, name)
+				return
+			}
+		}
+	})
+}
+
+// replaceOldest drops the oldest message buffered in the channel of s to send
+// event instead.
+func (p *Pubsub) replaceOldest(s *subscriber, event Event) outcome {
+	select {
+	case old := <-s.c:
+		p.drop(s.c, old, old.Pattern)
+	default:
+	}
+	select {
+	case s.c <- event:
+		return delivered
+	default:
+		return dropped
+	}
+}
@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSubscribeWith(t *testing.T) {
+	ps := New(-1)
+
+	sub, err := ps.SubscribeWith("a", WithBuffer(2), WithOverflow(OverflowDropOldest),
+		WithFilter(func(e Event) bool { return e.Message != 0 }))
+	assert.Equal(t, err, nil)
+	c := sub.Channel()
+	assert.Equal(t, cap(c), 2)
+	for i := 0; i < 4; i++ {
+		ps.Publish("a", i)
+	}
+	assert.Equal(t, (<-c).Message, 2)
+	assert.Equal(t, (<-c).Message, 3)
+
+	own := make(chan Event, 10)
+	sub, err = ps.PSubscribeWith("b.*", WithChannel(own), WithAutoUnsubscribeAfter(2))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sub.Channel(), own)
+	for i := 0; i < 3; i++ {
+		ps.Publish("b.x", i)
+	}
+	assert.Equal(t, len(own), 2)
+	receiveN(t, own, 2)
+	for ps.NumPSubscribers("b.*") > 0 {
+		// removed by another goroutine
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, sub.State(), Closed)
+
+	sub, _ = ps.SubscribeWith("c", WithBuffer(0), WithOverflow(OverflowLatest))
+	ps.Publish("c", 1)
+	ps.Publish("c", 2)
+	// 1 may be on its way already, 2 is kept rather than dropped
+	for (<-sub.Channel()).Message != 2 {
+	}
+
+	_, err = ps.PSubscribeWith("h[llo")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestSubscribeWithTransform(t *testing.T) {
+	type user struct {
+		Name    string
+		Profile []byte
+	}
+	ps := New(-1)
+	sub, err := ps.SubscribeWith("users", WithTransform(func(message interface{}) interface{} {
+		return message.(user).Name
+	}), WithFilter(func(e Event) bool { return e.Message.(user).Name != "" }))
+	assert.Equal(t, err, nil)
+	full := make(chan Event, 1)
+	ps.Subscribe("users", full)
+
+	ps.Publish("users", user{"ann", make([]byte, 1024)})
+	ps.Publish("users", user{})
+	c := sub.Channel()
+	assert.Equal(t, (<-c).Message, "ann")
+	assert.Equal(t, len(c), 0)
+	assert.Equal(t, len((<-full).Message.(user).Profile), 1024)
+
+	assert.Equal(t, ps.PublishCtx(context.Background(), "users", user{Name: "bob"}), nil)
+	assert.Equal(t, (<-c).Message, "bob")
+}
+
+func TestSubscribeWithDone(t *testing.T) {
+	ps := New(-1)
+	done := make(chan struct{})
+	sub, err := ps.SubscribeWith("a", WithDone(done))
+	assert.Equal(t, err, nil)
+	_, err = ps.PSubscribeWith("b*", WithChannel(sub.Channel()), WithDone(done))
+	assert.Equal(t, err, nil)
+	other, err := ps.SubscribeWith("a", WithDone(make(chan struct{})))
+	assert.Equal(t, err, nil)
+
+	close(done)
+	waitFor(t, func() bool {
+		return sub.State() == Evicted
+	})
+	assert.Equal(t, ps.NumSubscribers("a"), 1)
+	assert.Equal(t, ps.Patterns(), []string{})
+	assert.Equal(t, other.State(), Active)
+}
+
+func TestSubscribeWithDoneReplaced(t *testing.T) {
+	ps := New(-1)
+	done := make(chan struct{})
+	sub, err := ps.SubscribeWith("a", WithDone(done))
+	assert.Equal(t, err, nil)
+	c := sub.Channel()
+
+	// the newer subscriptions of the channel aren't unsubscribed by done
+	ps.Unsubscribe("a", c)
+	_, err = ps.SubscribeWith("a", WithChannel(c))
+	assert.Equal(t, err, nil)
+	_, err = ps.SubscribeWith("b", WithChannel(c), WithDone(done))
+	assert.Equal(t, err, nil)
+	_, err = ps.SubscribeWith("b", WithChannel(c))
+	assert.Equal(t, err, nil)
+
+	close(done)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, ps.NumSubscribers("a"), 1)
+	assert.Equal(t, ps.NumSubscribers("b"), 1)
+}
@@ -0,0 +1,231 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State is the lifecycle state of a Subscription.
+type State int32
+
+const (
+	// Active subscriptions receive published messages.
+	Active State = iota
+	// Paused subscriptions don't receive published messages until resumed.
+	Paused
+	// Draining subscriptions don't receive published messages and wait for their
+	// buffered messages to be consumed before being closed.
+	Draining
+	// Evicted subscriptions were removed by the Pubsub, e.g. because their
+	// context is done.
+	Evicted
+	// Closed subscriptions were unsubscribed.
+	Closed
+)
+
+func (s State) String() string {
+	switch s {
+	case Active:
+		return "active"
+	case Paused:
+		return "paused"
+	case Draining:
+		return "draining"
+	case Evicted:
+		return "evicted"
+	case Closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// Subscription is the handle of all subscriptions of a channel.
+type Subscription struct {
+	p     *Pubsub
+	c     chan Event
+	refs  int   // number of subscriptions of c, guarded by p.registry
+	final State // state after the last subscription is removed, guarded by p.locker
+
+	mu      sync.Mutex
+	state   atomic.Int32
+	changes chan State
+	done    chan struct{} // closed when the subscription is closed or evicted
+	drops   atomic.Int64  // consecutive drops
+	seen    seenIDs       // IDs of the messages sent WithDedup
+	sending sync.RWMutex  // held by Publish while sending to c
+}
+
+// Subscription returns the handle of the subscriptions of channel c, or nil if c
+// isn't subscribed to anything.
+func (p *Pubsub) Subscription(c chan Event) *Subscription {
+	p.registry.Lock()
+	defer p.registry.Unlock()
+	return p.subs[c]
+}
+
+// Channel returns the subscribed channel.
+func (s *Subscription) Channel() chan Event {
+	return s.c
+}
+
+// State returns the current state of the subscription.
+func (s *Subscription) State() State {
+	return State(s.state.Load())
+}
+
+// StateChanges returns a channel receiving the states the subscription moves to
+// after the call. The channel is closed when the subscription is closed or
+// evicted. Changes are dropped if the channel isn't ready to receive.
+func (s *Subscription) StateChanges() <-chan State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changes == nil {
+		s.changes = make(chan State, 8)
+		if st := s.State(); st == Closed || st == Evicted {
+			close(s.changes)
+		}
+	}
+	return s.changes
+}
+
+// Pause stops delivering messages to the channel until Resume is called. It returns
+// false if the subscription isn't active.
+func (s *Subscription) Pause() bool {
+	return s.transit(Active, Paused)
+}
+
+// Resume restarts delivering messages to a paused subscription. It returns false if
+// the subscription isn't paused.
+func (s *Subscription) Resume() bool {
+	return s.transit(Paused, Active)
+}
+
+// Unsubscribe unsubscribes the channel from all subscriptions & pattern subscriptions.
+func (s *Subscription) Unsubscribe() {
+	s.p.UnsubscribeAll(s.c)
+}
+
+// MultiSubscription is the handle of the subscriptions of SubscribeMany. Its other
+// methods are those of the Subscription of the channel.
+type MultiSubscription struct {
+	*Subscription
+	names []string // subscribed by SubscribeMany
+}
+
+// Unsubscribe unsubscribes the channel from the names subscribed by SubscribeMany,
+// its other subscriptions go on.
+func (s *MultiSubscription) Unsubscribe() {
+	for _, name := range s.names {
+		s.p.Unsubscribe(name, s.c)
+	}
+}
+
+// Drain stops delivering new messages to the channel, waits until the messages
+// already buffered in the channel are consumed, and unsubscribes the channel. It
+// returns ctx.Err() if ctx is done before the buffer is empty, the channel is
+// unsubscribed anyway.
+func (s *Subscription) Drain(ctx context.Context) error {
+	if s.transit(Active, Draining) || s.transit(Paused, Draining) {
+		defer s.Unsubscribe()
+	}
+
+	if len(s.c) == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for len(s.c) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+func (s *Subscription) transit(from, to State) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if State(s.state.Load()) != from {
+		return false
+	}
+	s.state.Store(int32(to))
+	if to == Closed || to == Evicted {
+		close(s.done)
+	}
+	if s.changes != nil {
+		select {
+		case s.changes <- to:
+		default:
+		}
+		if to == Closed || to == Evicted {
+			close(s.changes)
+		}
+	}
+	return true
+}
+
+func (p *Pubsub) ref(c chan Event) *Subscription {
+	p.registry.Lock()
+	defer p.registry.Unlock()
+
+	s, ok := p.subs[c]
+	if !ok {
+		s = &Subscription{p: p, c: c, final: Closed, done: make(chan struct{})}
+		p.subs[c] = s
+	}
+	s.refs++
+	return s
+}
+
+func (p *Pubsub) unref(c chan Event) {
+	p.registry.Lock()
+	defer p.registry.Unlock()
+
+	s, ok := p.subs[c]
+	if !ok {
+		return
+	}
+	s.refs--
+	if s.refs <= 0 {
+		delete(p.subs, c)
+		p.forgetIdentity(c)
+		// the locks are held, so concurrent sends are waited for by awaitSends
+		for _, from := range []State{Active, Paused, Draining} {
+			if s.transit(from, s.final) {
+				break
+			}
+		}
+	}
+}
+
+// awaitSends waits until the concurrent Publish finished sending to the channel of
+// s if s was closed. It must be called without the locks held, sends blocked by
+// PublishCtx return once s is closed.
+func (p *Pubsub) awaitSends(s *Subscription) {
+	if s == nil {
+		return
+	}
+	if st := s.State(); st == Closed || st == Evicted {
+		s.sending.Lock()
+		s.sending.Unlock()
+	}
+}
+
+// evict calls remove, marking the subscription of c as evicted if remove removes
+// its last subscription. It must be called with the write lock held.
+func (p *Pubsub) evict(c chan Event, remove func()) {
+	p.registry.Lock()
+	s, ok := p.subs[c]
+	p.registry.Unlock()
+	if !ok {
+		return
+	}
+	s.final = Evicted
+	remove()
+	s.final = Closed
+}
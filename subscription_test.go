@@ -0,0 +1,90 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestSubscription(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	assert.Equal(t, ps.Subscription(c) == nil, true)
+
+	ps.Subscribe("a", c)
+	ps.PSubscribe("b*", c)
+	s := ps.Subscription(c)
+	assert.Equal(t, s.Channel(), c)
+	assert.Equal(t, s.refs, 2)
+
+	ps.Unsubscribe("a", c)
+	assert.Equal(t, s.refs, 1)
+	s.Unsubscribe()
+	assert.Equal(t, ps.Subscription(c) == nil, true)
+	assert.Equal(t, len(ps.subs), 0)
+}
+
+func TestDrain(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 2)
+	ps.Subscribe("a", c)
+	ps.Publish("a", 1)
+	ps.Publish("a", 2)
+
+	s := ps.Subscription(c)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.Equal(t, s.Drain(ctx), context.DeadlineExceeded)
+	assert.Equal(t, ps.Subscription(c) == nil, true)
+
+	go func() {
+		<-c
+		<-c
+	}()
+	assert.Equal(t, s.Drain(context.Background()), nil)
+	ps.Publish("a", 3)
+	assert.Equal(t, len(c), 0)
+}
+
+func TestSubscriptionState(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event, 1)
+	ps.Subscribe("a", c)
+	s := ps.Subscription(c)
+	changes := s.StateChanges()
+	assert.Equal(t, s.State(), Active)
+
+	assert.Equal(t, s.Pause(), true)
+	assert.Equal(t, s.Pause(), false)
+	assert.Equal(t, ps.Publish("a", 1), PublishResult{})
+	assert.Equal(t, s.Resume(), true)
+	assert.Equal(t, ps.Publish("a", 2), PublishResult{Delivered: 1})
+
+	s.Pause()
+	s.Unsubscribe()
+	assert.Equal(t, s.State(), Closed)
+
+	var states []State
+	for st := range changes {
+		states = append(states, st)
+	}
+	assert.Equal(t, states, []State{Paused, Active, Paused, Closed})
+}
+
+func TestSubscriptionEvicted(t *testing.T) {
+	ps := New(-1)
+	c := make(chan Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	ps.SubscribeCtx(ctx, "a", c)
+	ps.PSubscribeCtx(ctx, "b*", c)
+	s := ps.Subscription(c)
+	changes := s.StateChanges()
+
+	cancel()
+	assert.Equal(t, <-changes, Evicted)
+	assert.Equal(t, s.State(), Evicted)
+	_, ok := <-changes
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,42 @@
+package pubsub
+
+import "context"
+
+// Tracer traces published messages, e.g. with OpenTelemetry, see the pubsubotel
+// package.
+type Tracer interface {
+	// StartPublish starts tracing the publishing of event in the trace of ctx. It
+	// may set headers of event to carry the trace context to the subscribers, and
+	// must copy the headers before, they may be shared. It returns the func called
+	// with the result once event is delivered.
+	StartPublish(ctx context.Context, event *Event) func(PublishResult)
+}
+
+// TraceCarrier is implemented by a Tracer which carries the trace of a context in
+// the headers of a message, like the pubsubotel Tracer. A message carrying a trace
+// is traced as part of it, see PublishWithContext.
+type TraceCarrier interface {
+	// Inject sets the headers carrying the trace of ctx.
+	Inject(ctx context.Context, headers map[string]string)
+	// Extract returns ctx with the trace carried by event.
+	Extract(ctx context.Context, event Event) context.Context
+}
+
+// WithTracer traces the published messages sampled by the Sampler of the Pubsub
+// with t.
+func WithTracer(t Tracer) Option {
+	return func(p *Pubsub) {
+		p.tracer = t
+	}
+}
+
+// trace starts tracing the publishing of event, or returns nil if it isn't traced.
+func (p *Pubsub) trace(ctx context.Context, event *Event) func(PublishResult) {
+	if p.tracer == nil || !p.sampled(event.Name) {
+		return nil
+	}
+	if c, ok := p.tracer.(TraceCarrier); ok && len(event.Headers) > 0 {
+		ctx = c.Extract(ctx, *event)
+	}
+	return p.tracer.StartPublish(ctx, event)
+}
@@ -0,0 +1,45 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+type testTracer struct {
+	results []PublishResult
+}
+
+func (t *testTracer) StartPublish(ctx context.Context, event *Event) func(PublishResult) {
+	headers := map[string]string{"trace": event.Name}
+	for k, v := range event.Headers {
+		headers[k] = v
+	}
+	event.Headers = headers
+	return func(result PublishResult) {
+		t.results = append(t.results, result)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	ps := New(-1, WithTracer(tracer), WithSampler(SamplerFunc(func(name string) bool {
+		return name != "untraced"
+	})))
+	c := make(chan Event, 3)
+	ps.Subscribe("sub", c)
+	ps.Subscribe("untraced", c)
+
+	headers := map[string]string{"k": "v"}
+	ps.PublishMsg("sub", 1, headers)
+	assert.Equal(t, (<-c).Headers, map[string]string{"trace": "sub", "k": "v"})
+	assert.Equal(t, headers, map[string]string{"k": "v"})
+
+	ps.Publish("untraced", 2)
+	assert.Equal(t, (<-c).Headers == nil, true)
+
+	ps.PublishCtx(context.Background(), "sub", 3)
+	assert.Equal(t, (<-c).Headers, map[string]string{"trace": "sub"})
+	assert.Equal(t, tracer.results, []PublishResult{{Delivered: 1}, {Delivered: 1}})
+}
@@ -0,0 +1,98 @@
+package pubsub
+
+// Transfer moves all subscriptions of channel from to channel to at once, so every
+// message published during the transfer is sent to either from or to. The
+// subscriptions which to already has are kept, and the Subscription of from is
+// closed. Messages buffered in from stay there.
+func (p *Pubsub) Transfer(from, to chan Event) {
+	if from == nil || to == nil || from == to {
+		return
+	}
+
+	defer p.awaitSends(p.Subscription(from))
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	moved := 0
+	for _, sh := range p.shards {
+		sh.mu.Lock()
+		moved += p.transfer(byName, sh.channels, from, to)
+		sh.mu.Unlock()
+	}
+	moved += p.transfer(byPattern, p.patterns, from, to)
+	moved += p.transfer(byFilter, p.filters, from, to)
+	for name, groups := range p.queues {
+		n := 0
+		for _, g := range groups {
+			var ok bool
+			if g.members, ok = p.replace(g.members, from, to); ok {
+				n++
+			}
+		}
+		if n > 0 {
+			p.changedQueue(name)
+			moved += n
+		}
+	}
+
+	// unref from once the snapshots don't route to it anymore, so it isn't closed
+	// while a message can only be sent to it
+	for i := 0; i < moved; i++ {
+		p.unref(from)
+	}
+}
+
+// transfer replaces from by to in collection and returns the number of replaced
+// subscriptions.
+func (p *Pubsub) transfer(k kind, collection map[string][]*subscriber, from, to chan Event) int {
+	n := 0
+	for name, subs := range collection {
+		subs, ok := p.replace(subs, from, to)
+		if !ok {
+			continue
+		}
+		collection[name] = subs
+		if k == byName {
+			p.changed(k, name)
+		}
+		n++
+	}
+	if k != byName && n > 0 {
+		p.changed(k, "")
+	}
+	return n
+}
+
+// replace returns a copy of subs where from is replaced by to, or removed if to is
+// already in subs.
+func (p *Pubsub) replace(subs []*subscriber, from, to chan Event) ([]*subscriber, bool) {
+	i := p.findChan(subs, from)
+	if i < 0 {
+		return subs, false
+	}
+	// subscriptions WithDone and SubscribeCtx watch the done channel of the receiver
+	// of from
+	if subs[i].unwatch != nil {
+		close(subs[i].unwatch)
+	}
+	subs[i].unwatchCtx()
+	if p.findChan(subs, to) >= 0 {
+		p.numSubs.Add(-1)
+		return append(subs[:i:i], subs[i+1:]...), true
+	}
+	s := *subs[i]
+	s.c = to
+	s.sub = p.ref(to)
+	s.unwatch = nil
+	// the lanes and the conflater send to their subscriber, messages queued for
+	// from stay with it
+	if l := s.lanes; l != nil {
+		s.lanes = &lanes{s: &s, size: l.size, dropOldest: l.dropOldest}
+	}
+	if s.conflate != nil {
+		s.conflate = &conflater{s: &s}
+	}
+	subs = append(subs[:i:i], subs[i:]...)
+	subs[i] = &s
+	return subs, true
+}
@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestTransfer(t *testing.T) {
+	ps := New(-1)
+	from := make(chan Event, 1)
+	to := make(chan Event, 5)
+	ps.Subscribe("a", from)
+	ps.Subscribe("b", from)
+	ps.Subscribe("b", to)
+	ps.PSubscribe("c*", from)
+	ps.HSubscribe("d/#", from)
+	ps.QueueSubscribe("e", "g", from)
+	old := ps.Subscription(from)
+
+	ps.Transfer(from, to)
+	assert.Equal(t, old.State(), Closed)
+	assert.Equal(t, ps.Subscription(from), (*Subscription)(nil))
+	assert.Equal(t, ps.NumSubscribers("b"), 1)
+
+	for _, name := range []string{"a", "b", "c1", "d/1", "e"} {
+		assert.Equal(t, ps.Publish(name, name), PublishResult{Delivered: 1})
+	}
+	assert.Equal(t, len(from), 0)
+	assert.Equal(t, len(to), 5)
+
+	ps.UnsubscribeAll(to)
+	assert.Equal(t, ps.Topics(), []string{})
+	assert.Equal(t, ps.Subscription(to), (*Subscription)(nil))
+}
+
+func TestTransferLanes(t *testing.T) {
+	ps := New(-1)
+	from := make(chan Event)
+	to := make(chan Event, 1)
+	_, err := ps.SubscribeWith("a", WithChannel(from), WithPriorityLanes(4))
+	assert.Equal(t, err, nil)
+
+	ps.Transfer(from, to)
+	assert.Equal(t, ps.Publish("a", 1).Delivered, 1)
+	select {
+	case event := <-to:
+		assert.Equal(t, event.Message, 1)
+	case <-time.After(time.Second):
+		t.Fatal("not sent to the new channel")
+	}
+}
+
+func TestTransferConflated(t *testing.T) {
+	ps := New(-1)
+	from := make(chan Event)
+	to := make(chan Event)
+	assert.Equal(t, ps.SubscribeConflated("a", from), nil)
+
+	ps.Transfer(from, to)
+	assert.Equal(t, ps.Publish("a", 1).Delivered, 1)
+	assert.Equal(t, ps.Publish("a", 2).Delivered, 1)
+	// 1 may be sent before 2 replaces it, the latest message is sent last
+	for {
+		select {
+		case event := <-to:
+			if event.Message == 2 {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("not sent to the new channel")
+		}
+	}
+}
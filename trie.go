@@ -0,0 +1,136 @@
+package pubsub
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error of subscribing a malformed hierarchical topic filter.
+var ErrBadFilter = errors.New("malformed topic filter")
+
+// HSubscribe subscribes channel c to a hierarchical topic filter in MQTT style.
+// Topic levels are separated by /, a + level matches exactly one level and a
+// trailing # level matches any number of levels, including the parent level:
+//
+//   - devices/+/telemetry matches devices/1/telemetry, but not devices/1/2/telemetry
+//   - devices/# matches devices, devices/1 and devices/1/telemetry
+//
+// Wildcards at the first level don't match topics starting with $. Filters are
+// kept in a trie of levels, so Publish only visits the filters which can match.
+func (p *Pubsub) HSubscribe(filter string, c chan Event) error {
+	filter = p.normalize(filter)
+	if !validFilter(filter) {
+		return ErrBadFilter
+	}
+	if c == nil {
+		return nil
+	}
+
+	p.locker.Lock()
+	defer p.locker.Unlock()
+
+	_, err := p.add(byFilter, filter, c)
+	return err
+}
+
+// HUnsubscribe unsubscribes the channel c from the hierarchical topic filter.
+func (p *Pubsub) HUnsubscribe(filter string, c chan Event) {
+	filter = p.normalize(filter)
+	if c == nil {
+		return
+	}
+
+	defer p.awaitSends(p.Subscription(c))
+	p.locker.Lock()
+	defer p.locker.Unlock()
+	p.remove(byFilter, filter, c)
+}
+
+// Filters returns the sorted hierarchical topic filters which have subscribers.
+func (p *Pubsub) Filters() []string {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	return sortedKeys(p.filters)
+}
+
+func validFilter(filter string) bool {
+	levels := strings.Split(filter, "/")
+	for i, level := range levels {
+		if level == "#" && i != len(levels)-1 {
+			return false
+		}
+		if level != "#" && level != "+" && strings.ContainsAny(level, "#+") {
+			return false
+		}
+	}
+	return true
+}
+
+// filterRoutes is the routing snapshot of filter subscriptions.
+type filterRoutes struct {
+	subs map[string][]*subscriber
+	trie *trieNode
+}
+
+func newFilterRoutes(filters map[string][]*subscriber) *filterRoutes {
+	r := &filterRoutes{
+		subs: make(map[string][]*subscriber, len(filters)),
+		trie: &trieNode{},
+	}
+	for filter, subs := range filters {
+		r.subs[filter] = subs
+		r.trie.insert(filter)
+	}
+	return r
+}
+
+type trieNode struct {
+	children map[string]*trieNode
+	filter   string
+	end      bool // whether a subscribed filter ends at the node
+}
+
+func (n *trieNode) insert(filter string) {
+	node := n
+	for _, level := range strings.Split(filter, "/") {
+		child, ok := node.children[level]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[string]*trieNode)
+			}
+			child = &trieNode{}
+			node.children[level] = child
+		}
+		node = child
+	}
+	node.filter = filter
+	node.end = true
+}
+
+// match calls fn with every filter matching the topic.
+func (n *trieNode) match(topic string, fn func(filter string)) {
+	n.matchLevels(strings.Split(topic, "/"), strings.HasPrefix(topic, "$"), fn)
+}
+
+func (n *trieNode) matchLevels(levels []string, system bool, fn func(filter string)) {
+	if len(levels) == 0 {
+		if n.end {
+			fn(n.filter)
+		}
+		if child, ok := n.children["#"]; ok && child.end {
+			fn(child.filter)
+		}
+		return
+	}
+	if !system {
+		if child, ok := n.children["#"]; ok && child.end {
+			fn(child.filter)
+		}
+		if child, ok := n.children["+"]; ok {
+			child.matchLevels(levels[1:], false, fn)
+		}
+	}
+	if child, ok := n.children[levels[0]]; ok {
+		child.matchLevels(levels[1:], false, fn)
+	}
+}
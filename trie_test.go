@@ -0,0 +1,49 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestHSubscribe(t *testing.T) {
+	ps := New(-1)
+	telemetry := make(chan Event, 10)
+	all := make(chan Event, 10)
+	assert.Equal(t, ps.HSubscribe("devices/+/telemetry/#", telemetry), nil)
+	assert.Equal(t, ps.HSubscribe("#", all), nil)
+	assert.Equal(t, ps.HSubscribe("devices/#/x", all), ErrBadFilter)
+	assert.Equal(t, ps.HSubscribe("devices/a+", all), ErrBadFilter)
+	assert.Equal(t, ps.Filters(), []string{"#", "devices/+/telemetry/#"})
+
+	assert.Equal(t, ps.Publish("devices/1/telemetry", 1).Delivered, 2)
+	assert.Equal(t, ps.Publish("devices/1/telemetry/temp", 2).Delivered, 2)
+	assert.Equal(t, ps.Publish("devices/1/status", 3).Delivered, 1)
+	assert.Equal(t, ps.Publish("$SYS/health", 4).Delivered, 0)
+	e := <-telemetry
+	assert.Equal(t, e.Message, 1)
+
+	ps.HUnsubscribe("devices/+/telemetry/#", telemetry)
+	assert.Equal(t, ps.Publish("devices/1/telemetry", 1).Delivered, 1)
+	assert.Equal(t, len(ps.filterRoutes.Load().trie.children), 1)
+
+	ps.UnsubscribeAll(all)
+	assert.Equal(t, len(ps.filterRoutes.Load().trie.children), 0)
+	assert.Equal(t, len(ps.filters), 0)
+}
+
+func TestTrieMatchesMQTTMatcher(t *testing.T) {
+	filters := []string{"a/+/c", "a/#", "#", "+/+", "a/b", "$SYS/#", "+", "a/+/+/#"}
+	topics := []string{"a/b/c", "a", "a/b", "b", "a/b/c/d", "$SYS/health", "a/", "/"}
+	var root trieNode
+	for _, f := range filters {
+		root.insert(f)
+	}
+	for _, topic := range topics {
+		got := map[string]bool{}
+		root.match(topic, func(f string) { got[f] = true })
+		for _, f := range filters {
+			assert.Equal(t, got[f], MQTTMatcher{}.Match(f, topic), f, topic)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package pubsub
+
+import "time"
+
+// HeaderTTL is the header of the time to live of a message published with
+// PublishMsg, parsed by time.ParseDuration.
+const HeaderTTL = "Pubsub-TTL"
+
+// PublishTTL publishes a message like Publish, which expires after ttl. Expired
+// messages kept by the Pubsub, e.g. in the history, the retained messages, the
+// broadcast rings, the durable logs or the queues of SubscribeAck, are discarded
+// rather than delivered.
+func (p *Pubsub) PublishTTL(name string, message interface{}, ttl time.Duration) PublishResult {
+	event := p.newEvent(name, message)
+	event.Expires = p.clock.Now().Add(ttl)
+	return p.publishVia(event)
+}
+
+// Expired returns whether the message has a TTL which is over by the system clock.
+// The Pubsub checks it with its Clock.
+func (e Event) Expired() bool {
+	return e.expiredAt(time.Now())
+}
+
+func (e Event) expiredAt(now time.Time) bool {
+	return !e.Expires.IsZero() && !now.Before(e.Expires)
+}
+
+// expiresFrom sets the expiry of event from its HeaderTTL header, from now.
+func (e *Event) expiresFrom(headers map[string]string, now time.Time) {
+	if ttl, ok := headers[HeaderTTL]; ok {
+		if d, err := time.ParseDuration(ttl); err == nil {
+			e.Expires = now.Add(d)
+		}
+	}
+}
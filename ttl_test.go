@@ -0,0 +1,51 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestPublishTTL(t *testing.T) {
+	ps := New(-1, WithHistory(10))
+	c := make(chan Event, 10)
+	ps.Subscribe("a", c)
+
+	ps.PublishTTL("a", 1, 10*time.Millisecond)
+	ps.PublishMsg("a", 2, map[string]string{HeaderTTL: "1h"})
+	ps.Publish("a", 3)
+
+	// subscribers receive the message before it expires
+	events := receiveN(t, c, 3)
+	assert.Equal(t, events[0].Expired(), false)
+	assert.Equal(t, events[1].Expires.IsZero(), false)
+	assert.Equal(t, events[2].Expires.IsZero(), true)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, events[0].Expired(), true)
+	history := ps.History("a")
+	assert.Equal(t, len(history), 2)
+	assert.Equal(t, history[0].Message, 2)
+}
+
+func TestPublishTTLRetained(t *testing.T) {
+	ps := New(-1)
+	ps.PublishRetain("a", 1)
+	r := ps.Broadcast("a", 4)
+	rr := r.Reader()
+
+	ps.publishRetain(Event{Name: "a", Message: 2, Expires: time.Now().Add(-time.Second)})
+	_, ok := ps.Retained("a")
+	assert.Equal(t, ok, false)
+
+	c := make(chan Event, 1)
+	ps.SubscribeRetained("a", c)
+	assert.Equal(t, len(c), 0)
+
+	// the expired message is skipped by the ring readers
+	ps.Publish("a", 3)
+	event, ok := rr.TryNext()
+	assert.Equal(t, ok, true)
+	assert.Equal(t, event.Message, 3)
+}
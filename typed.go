@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Error of subscribing a value which isn't a channel which can be sent to.
+var ErrNotChannel = errors.New("not a channel which can be sent to")
+
+var eventType = reflect.TypeOf(Event{})
+
+// SubscribeChan subscribes ch, a channel of any type which can be sent to, e.g.
+// chan<- interface{} or chan MyEvent, to name. A chan Event receives the events,
+// other channels receive the messages stored in a value of their element type like
+// Into, so encoded messages are decoded. Messages which can't be stored are skipped
+// and reported as a NoticeUnassignable. The messages are sent from a goroutine of
+// the subscription, and dropped while ch isn't ready and the buffer of the
+// subscription is full. Unsubscribe the returned Subscription to stop.
+func (p *Pubsub) SubscribeChan(name string, ch interface{}) (*Subscription, error) {
+	return p.subscribeChan(ch, func(c chan Event) error {
+		return p.Subscribe(name, c)
+	})
+}
+
+// PSubscribeChan is like SubscribeChan for the topics matching pattern.
+func (p *Pubsub) PSubscribeChan(pattern string, ch interface{}) (*Subscription, error) {
+	return p.subscribeChan(ch, func(c chan Event) error {
+		return p.PSubscribe(pattern, c)
+	})
+}
+
+func (p *Pubsub) subscribeChan(ch interface{}, subscribe func(c chan Event) error) (*Subscription, error) {
+	out := reflect.ValueOf(ch)
+	if out.Kind() != reflect.Chan || out.Type().ChanDir()&reflect.SendDir == 0 || out.IsNil() {
+		return nil, ErrNotChannel
+	}
+	c := make(chan Event, handlerBuffer)
+	if err := subscribe(c); err != nil {
+		return nil, err
+	}
+	s := p.Subscription(c)
+	go p.forwardChan(s, out)
+	return s, nil
+}
+
+// forwardChan sends the messages received by s to out until s is closed.
+func (p *Pubsub) forwardChan(s *Subscription, out reflect.Value) {
+	elem := out.Type().Elem()
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: out},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.done)},
+	}
+	for {
+		var event Event
+		select {
+		case event = <-s.c:
+		case <-s.done:
+			return
+		}
+		var v reflect.Value
+		if elem == eventType {
+			v = reflect.ValueOf(event)
+		} else {
+			ptr := reflect.New(elem)
+			if err := event.Into(ptr.Interface()); err != nil {
+				p.Notify(Notice{Kind: NoticeUnassignable, Name: event.Name, Channel: s.c, Err: err})
+				continue
+			}
+			v = ptr.Elem()
+		}
+		cases[0].Send = v
+		if chosen, _, _ := reflect.Select(cases); chosen == 1 {
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+type typedEvent struct {
+	N int `json:"n"`
+}
+
+func TestSubscribeChan(t *testing.T) {
+	ps := New(-1)
+	events, stop, err := ps.Events()
+	assert.Equal(t, err, nil)
+	defer stop()
+
+	typed := make(chan typedEvent, 10)
+	sub, err := ps.SubscribeChan("a", typed)
+	assert.Equal(t, err, nil)
+	ps.Publish("a", typedEvent{1})
+	ps.Publish("a", &typedEvent{2})
+	ps.Publish("a", []byte(`{"n":3}`))
+	ps.Publish("a", "not json")
+	for _, want := range []int{1, 2, 3} {
+		select {
+		case e := <-typed:
+			assert.Equal(t, e.N, want)
+		case <-time.After(time.Second):
+			t.Fatal("no message")
+		}
+	}
+	n := receiveNotice(t, events)
+	assert.Equal(t, n.Kind, NoticeUnassignable)
+	sub.Unsubscribe()
+
+	var sendOnly chan<- interface{}
+	values := make(chan interface{}, 10)
+	sendOnly = values
+	_, err = ps.PSubscribeChan("b*", sendOnly)
+	assert.Equal(t, err, nil)
+	ps.Publish("b1", 1)
+	select {
+	case v := <-values:
+		assert.Equal(t, v, 1)
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+
+	_, err = ps.SubscribeChan("a", make(<-chan int))
+	assert.Equal(t, err, ErrNotChannel)
+	_, err = ps.SubscribeChan("a", 1)
+	assert.Equal(t, err, ErrNotChannel)
+}
@@ -0,0 +1,377 @@
+package pubsub
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store keeping a write-ahead log file and an offsets file per topic
+// in a directory. Messages are encoded with a Codec, and read back as []byte with
+// the HeaderContentType header of the codec, to be decoded with Into.
+type FileStore struct {
+	dir   string
+	codec Codec
+	keys  KeyProvider // set by OpenEncryptedFileStore
+
+	mu      sync.Mutex
+	logs    map[string]*walLog
+	offsets map[string]map[string]uint64
+}
+
+// walLog is the log file of a topic and the positions of its records.
+type walLog struct {
+	mu        sync.Mutex
+	f         *os.File
+	positions []int64 // position of the record of offset i+1
+	size      int64
+}
+
+// walRecord is a record of a log file, after its length as a big endian uint32.
+type walRecord struct {
+	ID          string            `json:"id,omitempty"`
+	Reply       string            `json:"reply,omitempty"`
+	Time        time.Time         `json:"time"`
+	Expires     time.Time         `json:"expires"`
+	Seq         uint64            `json:"seq,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	ContentType string            `json:"content_type"`
+	Data        []byte            `json:"data"`
+
+	// set instead of the other fields if the record is encrypted
+	Key    string `json:"key,omitempty"`
+	Sealed []byte `json:"sealed,omitempty"`
+}
+
+// walSealed is a record of a log file encrypted by OpenEncryptedFileStore, a
+// walRecord sealed with the key of Key.
+type walSealed struct {
+	Key    string `json:"key"`
+	Sealed []byte `json:"sealed"`
+}
+
+// OpenFileStore returns a FileStore in dir, created if needed, encoding the
+// messages with codec, or JSON if nil. Register codec with RegisterCodec for Into
+// to decode the messages read back.
+func OpenFileStore(dir string, codec Codec) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if codec == nil {
+		codec = JSON
+	}
+	return &FileStore{
+		dir:     dir,
+		codec:   codec,
+		logs:    map[string]*walLog{},
+		offsets: map[string]map[string]uint64{},
+	}, nil
+}
+
+// OpenEncryptedFileStore is like OpenFileStore, encrypting the records of the log
+// files with the keys of keys, bound to their topic. Records written without
+// encryption stay readable. The names of the topics, which name the files, and the
+// offsets of the consumers aren't encrypted.
+func OpenEncryptedFileStore(dir string, codec Codec, keys KeyProvider) (*FileStore, error) {
+	s, err := OpenFileStore(dir, codec)
+	if err != nil {
+		return nil, err
+	}
+	s.keys = keys
+	return s, nil
+}
+
+func (s *FileStore) path(name, ext string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+ext)
+}
+
+// log returns the log of name, opening its file on first use.
+func (s *FileStore) log(name string) (*walLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.logs[name]; ok {
+		return l, nil
+	}
+	f, err := os.OpenFile(s.path(name, ".log"), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	l := &walLog{f: f}
+	if err := l.scan(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.logs[name] = l
+	return l, nil
+}
+
+// scan indexes the records of the file, truncating a partly written last record.
+func (l *walLog) scan() error {
+	r := bufio.NewReader(l.f)
+	var header [4]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			if err == io.ErrUnexpectedEOF {
+				return l.truncate()
+			}
+			return err
+		}
+		n := int64(binary.BigEndian.Uint32(header[:]))
+		if _, err := r.Discard(int(n)); err != nil {
+			if err == io.EOF {
+				return l.truncate()
+			}
+			return err
+		}
+		l.positions = append(l.positions, l.size)
+		l.size += 4 + n
+	}
+	_, err := l.f.Seek(l.size, io.SeekStart)
+	return err
+}
+
+func (l *walLog) truncate() error {
+	if err := l.f.Truncate(l.size); err != nil {
+		return err
+	}
+	_, err := l.f.Seek(l.size, io.SeekStart)
+	return err
+}
+
+func (s *FileStore) Append(event Event) (uint64, error) {
+	rec := walRecord{
+		ID:      event.ID,
+		Reply:   event.Reply,
+		Time:    event.Time,
+		Expires: event.Expires,
+		Seq:     event.Seq,
+		Headers: event.Headers,
+	}
+	var err error
+	rec.ContentType, rec.Data, err = encodeMessage(event, s.codec)
+	if err != nil {
+		return 0, err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	if s.keys != nil {
+		id, sealed, err := seal(s.keys, data, []byte(event.Name))
+		if err != nil {
+			return 0, err
+		}
+		if data, err = json.Marshal(walSealed{id, sealed}); err != nil {
+			return 0, err
+		}
+	}
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+
+	l, err := s.log(event.Name)
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(buf); err != nil {
+		// drop what was written of the record
+		l.truncate()
+		return 0, err
+	}
+	l.positions = append(l.positions, l.size)
+	l.size += int64(len(buf))
+	return uint64(len(l.positions)), nil
+}
+
+// encodeMessage returns the content type and the data of the message of event,
+// encoded with codec unless it's already encoded.
+func encodeMessage(event Event, codec Codec) (string, []byte, error) {
+	if data, ok := event.Message.([]byte); ok && event.Headers[HeaderContentType] != "" {
+		return event.Headers[HeaderContentType], data, nil
+	}
+	data, err := codec.Encode(event.Message)
+	if err != nil {
+		return "", nil, err
+	}
+	return codec.ContentType(), data, nil
+}
+
+func (s *FileStore) Read(name string, offset uint64, fn func(event Event) bool) error {
+	l, err := s.log(name)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	positions, size := l.positions, l.size
+	l.mu.Unlock()
+	if offset == 0 {
+		offset = 1
+	}
+	for i := offset - 1; i < uint64(len(positions)); i++ {
+		end := size
+		if i+1 < uint64(len(positions)) {
+			end = positions[i+1]
+		}
+		buf := make([]byte, end-positions[i])
+		if _, err := l.f.ReadAt(buf, positions[i]); err != nil {
+			return err
+		}
+		rec, err := s.decodeRecord(name, buf[4:])
+		if err != nil {
+			return err
+		}
+		headers := make(map[string]string, len(rec.Headers)+1)
+		for k, v := range rec.Headers {
+			headers[k] = v
+		}
+		headers[HeaderContentType] = rec.ContentType
+		event := Event{
+			ID:      rec.ID,
+			Name:    name,
+			Message: rec.Data,
+			Reply:   rec.Reply,
+			Time:    rec.Time,
+			Headers: headers,
+			Offset:  i + 1,
+			Expires: rec.Expires,
+			Seq:     rec.Seq,
+		}
+		if !fn(event) {
+			break
+		}
+	}
+	return nil
+}
+
+// decodeRecord decodes a record of the log of name, decrypting it if needed.
+func (s *FileStore) decodeRecord(name string, data []byte) (walRecord, error) {
+	var rec walRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return walRecord{}, err
+	}
+	if rec.Key == "" {
+		return rec, nil
+	}
+	data, err := unseal(s.keys, rec.Key, rec.Sealed, []byte(name))
+	if err != nil {
+		return walRecord{}, fmt.Errorf("pubsub: decrypt %q: %w", name, err)
+	}
+	rec = walRecord{}
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return walRecord{}, err
+	}
+	return rec, nil
+}
+
+// consumers returns the offsets of the consumers of name, loading its file on
+// first use. It must be called with s.mu held.
+func (s *FileStore) consumers(name string) (map[string]uint64, error) {
+	if offsets, ok := s.offsets[name]; ok {
+		return offsets, nil
+	}
+	offsets := map[string]uint64{}
+	data, err := os.ReadFile(s.path(name, ".offsets"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &offsets); err != nil {
+			return nil, err
+		}
+	}
+	s.offsets[name] = offsets
+	return offsets, nil
+}
+
+func (s *FileStore) Commit(name, consumer string, offset uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets, err := s.consumers(name)
+	if err != nil {
+		return err
+	}
+	if offset <= offsets[consumer] {
+		return nil
+	}
+	offsets[consumer] = offset
+	data, err := json.Marshal(offsets)
+	if err != nil {
+		return err
+	}
+	// replace the file atomically, a crash keeps the previous offsets
+	path := s.path(name, ".offsets")
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func (s *FileStore) Committed(name, consumer string) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets, err := s.consumers(name)
+	if err != nil {
+		return 0, err
+	}
+	return offsets[consumer], nil
+}
+
+// Offsets returns the committed offsets of all topics in the directory.
+func (s *FileStore) Offsets() (map[string]map[string]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.offsets"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ret := make(map[string]map[string]uint64, len(paths))
+	for _, path := range paths {
+		name, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(path), ".offsets"))
+		if err != nil {
+			continue
+		}
+		offsets, err := s.consumers(name)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = copyOffsets(offsets)
+	}
+	return ret, nil
+}
+
+func (s *FileStore) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for _, l := range s.logs {
+		errs = append(errs, l.f.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+// Close syncs and closes the log files.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for name, l := range s.logs {
+		errs = append(errs, l.f.Sync(), l.f.Close())
+		delete(s.logs, name)
+	}
+	return errors.Join(errs...)
+}
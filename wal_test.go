@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir, nil)
+	assert.Equal(t, err, nil)
+	ps := New(-1, WithDurable("a/*", store))
+	ps.PublishMsg("a/b", map[string]int{"n": 1}, map[string]string{"k": "v"})
+	ps.Publish("a/b", map[string]int{"n": 2})
+
+	c := make(chan Event, 10)
+	d, _ := ps.SubscribeDurable("a/b", "consumer", c)
+	events := receiveN(t, c, 2)
+	assert.Equal(t, events[0].Headers["k"], "v")
+	assert.Equal(t, d.Ack(events[0]), nil)
+	d.Close()
+	assert.Equal(t, ps.Shutdown(context.Background()), nil)
+	assert.Equal(t, store.Close(), nil)
+
+	// a partly written record is dropped on reopen
+	f, _ := os.OpenFile(filepath.Join(dir, "a%2Fb.log"), os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{0, 0, 1})
+	f.Close()
+
+	store, err = OpenFileStore(dir, nil)
+	assert.Equal(t, err, nil)
+	defer store.Close()
+	ps = New(-1, WithDurable("a/*", store))
+	ps.Publish("a/b", map[string]int{"n": 3})
+
+	d, _ = ps.SubscribeDurable("a/b", "consumer", c)
+	defer d.Close()
+	events = receiveN(t, c, 2)
+	var v struct{ N int }
+	assert.Equal(t, events[0].Into(&v), nil)
+	assert.Equal(t, v.N, 2)
+	assert.Equal(t, events[1].Offset, uint64(3))
+}
+
+func TestFileStoreOffsets(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenFileStore(dir, nil)
+	assert.Equal(t, err, nil)
+	store.Commit("a/b", "x", 2)
+	store.Commit("a/c", "y", 1)
+
+	reopened, err := OpenFileStore(dir, nil)
+	assert.Equal(t, err, nil)
+	offsets, err := reopened.Offsets()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, offsets, map[string]map[string]uint64{
+		"a/b": {"x": 2},
+		"a/c": {"y": 1},
+	})
+}
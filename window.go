@@ -0,0 +1,109 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+const windowBuckets = 10
+
+// Rate is the per second rate of a topic over the stats window.
+type Rate struct {
+	Published float64
+	Dropped   float64
+}
+
+// WithStatsWindow keeps rolling per topic rates over the last d, which can be read
+// with Rates.
+func WithStatsWindow(d time.Duration) Option {
+	return func(p *Pubsub) {
+		if d <= 0 {
+			return
+		}
+		p.window = &statsWindow{
+			size:   d,
+			span:   d / windowBuckets,
+			topics: make(map[string]*rateRing),
+		}
+		if p.window.span <= 0 {
+			p.window.span = 1
+		}
+	}
+}
+
+// Rates returns a snapshot of the per topic rates over the stats window. It returns
+// nil if the Pubsub isn't created with WithStatsWindow.
+func (p *Pubsub) Rates() map[string]Rate {
+	if p.window == nil {
+		return nil
+	}
+	return p.window.snapshot(p.clock.Now())
+}
+
+type rateBucket struct {
+	slot      int64
+	published int
+	dropped   int
+}
+
+type rateRing [windowBuckets]rateBucket
+
+type statsWindow struct {
+	mu     sync.Mutex
+	size   time.Duration
+	span   time.Duration
+	topics map[string]*rateRing
+}
+
+func (w *statsWindow) bucket(name string, now time.Time) *rateBucket {
+	ring, ok := w.topics[name]
+	if !ok {
+		ring = new(rateRing)
+		w.topics[name] = ring
+	}
+	slot := now.UnixNano() / int64(w.span)
+	b := &ring[slot%windowBuckets]
+	if b.slot != slot {
+		*b = rateBucket{slot: slot}
+	}
+	return b
+}
+
+func (w *statsWindow) published(name string, now time.Time) {
+	w.mu.Lock()
+	w.bucket(name, now).published++
+	w.mu.Unlock()
+}
+
+func (w *statsWindow) dropped(name string, now time.Time) {
+	w.mu.Lock()
+	w.bucket(name, now).dropped++
+	w.mu.Unlock()
+}
+
+func (w *statsWindow) snapshot(now time.Time) map[string]Rate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	slot := now.UnixNano() / int64(w.span)
+	seconds := w.size.Seconds()
+	ret := make(map[string]Rate, len(w.topics))
+	for name, ring := range w.topics {
+		var published, dropped int
+		for _, b := range ring {
+			if b.slot > slot-windowBuckets && b.slot <= slot {
+				published += b.published
+				dropped += b.dropped
+			}
+		}
+		if published == 0 && dropped == 0 {
+			delete(w.topics, name)
+			continue
+		}
+		ret[name] = Rate{
+			Published: float64(published) / seconds,
+			Dropped:   float64(dropped) / seconds,
+		}
+	}
+	return ret
+}
@@ -0,0 +1,37 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/googollee/go-assert"
+)
+
+func TestRates(t *testing.T) {
+	assert.Equal(t, New(-1).Rates() == nil, true)
+
+	ps := New(-1, WithStatsWindow(time.Second))
+	c := make(chan Event, 1)
+	ps.Subscribe("topic", c)
+	ps.Publish("topic", 1)
+	ps.Publish("topic", 2)
+	ps.Publish("other", 3)
+
+	rates := ps.Rates()
+	assert.Equal(t, len(rates), 2)
+	assert.Equal(t, rates["topic"], Rate{Published: 2, Dropped: 1})
+	assert.Equal(t, rates["other"], Rate{Published: 1})
+}
+
+func TestStatsWindowExpire(t *testing.T) {
+	w := &statsWindow{
+		size:   time.Second,
+		span:   time.Second / windowBuckets,
+		topics: make(map[string]*rateRing),
+	}
+	now := time.Now()
+	w.published("topic", now)
+	assert.Equal(t, w.snapshot(now)["topic"].Published, float64(1))
+	assert.Equal(t, len(w.snapshot(now.Add(2*time.Second))), 0)
+	assert.Equal(t, len(w.topics), 0)
+}